@@ -0,0 +1,385 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the service settings read from the environment
+type Config struct {
+	DatabaseURL string
+
+	// ServiceName and ServiceVersion are reported by GET /
+	ServiceName    string
+	ServiceVersion string
+
+	// StatementTimeout is applied as the Postgres statement_timeout of every connection. Zero disables it
+	StatementTimeout time.Duration
+	// StatementTimeoutSet tells that StatementTimeout was configured explicitly, only then it overrides
+	// the statement_timeout of DatabaseURL
+	StatementTimeoutSet bool
+	// AggregateQueryTimeout bounds the queries summing up the invoices, which grow with the number of items.
+	// Zero leaves them to StatementTimeout
+	AggregateQueryTimeout time.Duration
+
+	// StartupDBTimeout is how long to keep retrying the initial database connection before giving up
+	StartupDBTimeout time.Duration
+
+	// LogLevel is either "info" or "debug". Debug additionally logs request and response bodies
+	LogLevel string
+
+	// CORSAllowedOrigins enables CORS for the listed origins, "*" allows any origin
+	CORSAllowedOrigins   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+
+	// StrictAccept enables 406 Not Acceptable responses for requests that don't accept JSON
+	StrictAccept bool
+
+	// ListenAddress is either host:port or unix:/path/to/socket
+	ListenAddress string
+
+	// StripPathPrefix is removed from the request paths before routing, for the gateways forwarding the full
+	// external path
+	StripPathPrefix string
+
+	// H2C enables cleartext HTTP/2 for running behind a proxy that speaks HTTP/2 to the service
+	H2C bool
+	// HTTP2MaxConcurrentStreams limits the concurrent streams per HTTP/2 connection, zero means the Go default
+	HTTP2MaxConcurrentStreams int
+	HTTPIdleTimeout           time.Duration
+	HTTPReadHeaderTimeout     time.Duration
+	HTTPKeepAlives            bool
+	// HTTPMaxHeaderBytes limits the size of the request headers, zero means the Go default of 1 MB
+	HTTPMaxHeaderBytes int
+	// ShutdownTimeout is how long the in-flight requests may take to finish once the service is asked to stop
+	ShutdownTimeout time.Duration
+
+	// DisabledEndpoints lists the "METHOD /path" endpoints answering with 503, e.g. "POST /invoices"
+	DisabledEndpoints []string
+	// UnavailableRetryAfter is sent as Retry-After with the 503 of a disabled endpoint, an unavailable database or
+	// a timed out aggregate query
+	UnavailableRetryAfter time.Duration
+
+	// IDsAsStrings serializes the ids in the responses as JSON strings
+	IDsAsStrings bool
+	// DeleteConfirmations makes the deletes respond with 200 and a JSON body instead of 204
+	DeleteConfirmations bool
+	// OmitNulls leaves the null fields out of the responses instead of sending them as null
+	OmitNulls bool
+	// InvoiceDateFormat is one of the DateFormat* values
+	InvoiceDateFormat string
+	// InvoiceNumberPattern has to match the whole invoice number when set
+	InvoiceNumberPattern   *regexp.Regexp
+	InvoiceNumberUppercase bool
+	// InvoiceDiffAcrossCustomers allows comparing the invoices of different customers
+	InvoiceDiffAcrossCustomers bool
+
+	// NonCriticalDependencies lists the dependencies that don't make the service unready when they fail
+	NonCriticalDependencies []string
+
+	// AdminToken is the bearer token of the admin endpoints, they are disabled when it's empty
+	AdminToken string
+
+	// ServerTiming enables the Server-Timing response header with the database and total handler time
+	ServerTiming bool
+
+	// MaxURLLength and MaxQueryItems limit the request URL length and the number of query parameters and of
+	// comma-separated items in a parameter. Zero disables the limit
+	MaxURLLength  int
+	MaxQueryItems int
+	// MaxHeaderCount limits the number of the request header fields, zero disables the limit
+	MaxHeaderCount int
+
+	// MaxPriceIntegerDigits limits the digits before the decimal point of a price, up to what the column fits
+	MaxPriceIntegerDigits int
+
+	// MaxDescriptionLength limits the product description length in characters, zero disables the limit
+	MaxDescriptionLength int
+
+	// MaxAvailableItems limits the available_items of a product to catch typos, zero leaves only the int32 range
+	MaxAvailableItems int
+
+	// LowStockThreshold is the number of available items at or below which a product is reported as low on stock
+	LowStockThreshold int
+
+	// ProductsCacheTTL is how long the product list is served from memory, zero disables the cache
+	ProductsCacheTTL time.Duration
+
+	// BackpressureMaxInUse is the number of database connections in use from which the requests are slowed
+	// down or shed, zero disables the backpressure
+	BackpressureMaxInUse    int
+	BackpressureShedPercent int
+	BackpressureDelay       time.Duration
+	BackpressureRetryAfter  time.Duration
+}
+
+// Load reads the service configuration from the environment variables
+func Load() (Config, error) {
+	var cfg Config
+	var err error
+
+	if cfg.DatabaseURL, err = databaseURL(); err != nil {
+		return cfg, err
+	}
+
+	if cfg.StatementTimeout, err = getEnvDuration("DB_STATEMENT_TIMEOUT", DefaultStatementTimeout); err != nil {
+		return cfg, err
+	}
+	cfg.StatementTimeoutSet = os.Getenv("DB_STATEMENT_TIMEOUT") != ""
+	if cfg.AggregateQueryTimeout, err = getEnvDuration("AGGREGATE_QUERY_TIMEOUT", DefaultAggregateQueryTimeout); err != nil {
+		return cfg, err
+	}
+
+	if cfg.StartupDBTimeout, err = getEnvDuration("STARTUP_DB_TIMEOUT", DefaultStartupDBTimeout); err != nil {
+		return cfg, err
+	}
+
+	cfg.ServiceName = getEnvString("SERVICE_NAME", DefaultServiceName)
+	cfg.ServiceVersion = getEnvString("SERVICE_VERSION", DefaultServiceVersion)
+
+	cfg.LogLevel = getEnvString("LOG_LEVEL", LogLevelInfo)
+	if cfg.LogLevel != LogLevelInfo && cfg.LogLevel != LogLevelDebug {
+		return cfg, fmt.Errorf("LOG_LEVEL must be either %q or %q, got %q", LogLevelInfo, LogLevelDebug, cfg.LogLevel)
+	}
+
+	cfg.CORSAllowedOrigins = getEnvList("CORS_ALLOWED_ORIGINS")
+	if cfg.CORSAllowCredentials, err = getEnvBool("CORS_ALLOW_CREDENTIALS", false); err != nil {
+		return cfg, err
+	}
+	if cfg.CORSMaxAge, err = getEnvDuration("CORS_MAX_AGE", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.CORSAllowCredentials && slices.Contains(cfg.CORSAllowedOrigins, "*") {
+		return cfg, errors.New("CORS_ALLOW_CREDENTIALS can't be combined with the \"*\" CORS_ALLOWED_ORIGINS, list the origins explicitly")
+	}
+
+	if cfg.StrictAccept, err = getEnvBool("STRICT_ACCEPT", false); err != nil {
+		return cfg, err
+	}
+	cfg.ListenAddress = getEnvString("LISTEN_ADDRESS", DefaultServiceBindingAddress)
+	cfg.StripPathPrefix = getEnvString("STRIP_PATH_PREFIX", "")
+	if cfg.StripPathPrefix != "" && !strings.HasPrefix(cfg.StripPathPrefix, "/") {
+		return cfg, fmt.Errorf("STRIP_PATH_PREFIX must start with /, got %q", cfg.StripPathPrefix)
+	}
+	if cfg.H2C, err = getEnvBool("H2C", false); err != nil {
+		return cfg, err
+	}
+	if cfg.HTTP2MaxConcurrentStreams, err = getEnvInt("HTTP2_MAX_CONCURRENT_STREAMS", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.HTTPIdleTimeout, err = getEnvDuration("HTTP_IDLE_TIMEOUT", DefaultHTTPIdleTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.HTTPReadHeaderTimeout, err = getEnvDuration("HTTP_READ_HEADER_TIMEOUT", DefaultHTTPReadHeaderTimeout); err != nil {
+		return cfg, err
+	}
+	if cfg.HTTPKeepAlives, err = getEnvBool("HTTP_KEEP_ALIVES", true); err != nil {
+		return cfg, err
+	}
+	if cfg.HTTPMaxHeaderBytes, err = getEnvInt("HTTP_MAX_HEADER_BYTES", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.ShutdownTimeout, err = getEnvDuration("SHUTDOWN_TIMEOUT", DefaultShutdownTimeout); err != nil {
+		return cfg, err
+	}
+
+	cfg.DisabledEndpoints = getEnvList("DISABLED_ENDPOINTS")
+	for _, endpoint := range cfg.DisabledEndpoints {
+		if method, path, ok := strings.Cut(endpoint, " "); !ok || method == "" || !strings.HasPrefix(path, "/") {
+			return cfg, fmt.Errorf("DISABLED_ENDPOINTS items must look like \"POST /invoices\", got %q", endpoint)
+		}
+	}
+	if cfg.UnavailableRetryAfter, err = getEnvDuration("UNAVAILABLE_RETRY_AFTER", DefaultUnavailableRetry); err != nil {
+		return cfg, err
+	}
+
+	if cfg.IDsAsStrings, err = getEnvBool("API_IDS_AS_STRINGS", false); err != nil {
+		return cfg, err
+	}
+	if cfg.DeleteConfirmations, err = getEnvBool("DELETE_CONFIRMATIONS", false); err != nil {
+		return cfg, err
+	}
+	if cfg.OmitNulls, err = getEnvBool("API_OMIT_NULLS", false); err != nil {
+		return cfg, err
+	}
+	cfg.InvoiceDateFormat = getEnvString("INVOICE_DATE_FORMAT", DateFormatRFC3339)
+	if !slices.Contains([]string{DateFormatRFC3339, DateFormatDate, DateFormatUnix}, cfg.InvoiceDateFormat) {
+		return cfg, fmt.Errorf("INVOICE_DATE_FORMAT must be one of %q, %q or %q, got %q", DateFormatRFC3339, DateFormatDate, DateFormatUnix, cfg.InvoiceDateFormat)
+	}
+	if pattern := os.Getenv("INVOICE_NUMBER_PATTERN"); pattern != "" {
+		if cfg.InvoiceNumberPattern, err = regexp.Compile("^(?:" + pattern + ")$"); err != nil {
+			return cfg, fmt.Errorf("INVOICE_NUMBER_PATTERN is not a valid regular expression: %w", err)
+		}
+	}
+	if cfg.InvoiceNumberUppercase, err = getEnvBool("INVOICE_NUMBER_UPPERCASE", false); err != nil {
+		return cfg, err
+	}
+	if cfg.InvoiceDiffAcrossCustomers, err = getEnvBool("INVOICE_DIFF_ACROSS_CUSTOMERS", false); err != nil {
+		return cfg, err
+	}
+
+	cfg.NonCriticalDependencies = getEnvList("NONCRITICAL_DEPENDENCIES")
+	cfg.AdminToken = os.Getenv("ADMIN_TOKEN")
+
+	if cfg.ServerTiming, err = getEnvBool("SERVER_TIMING", false); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxURLLength, err = getEnvInt("MAX_URL_LENGTH", DefaultMaxURLLength); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxQueryItems, err = getEnvInt("MAX_QUERY_ITEMS", DefaultMaxQueryItems); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxHeaderCount, err = getEnvInt("MAX_HEADER_COUNT", DefaultMaxHeaderCount); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxPriceIntegerDigits, err = getEnvInt("MAX_PRICE_INTEGER_DIGITS", MaxPriceIntegerDigits); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxPriceIntegerDigits == 0 || cfg.MaxPriceIntegerDigits > MaxPriceIntegerDigits {
+		return cfg, fmt.Errorf("MAX_PRICE_INTEGER_DIGITS must be between 1 and %d, got %d", MaxPriceIntegerDigits, cfg.MaxPriceIntegerDigits)
+	}
+	if cfg.MaxDescriptionLength, err = getEnvInt("MAX_DESCRIPTION_LENGTH", DefaultMaxDescriptionLength); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxAvailableItems, err = getEnvInt("MAX_AVAILABLE_ITEMS", DefaultMaxAvailableItems); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxAvailableItems < 0 || cfg.MaxAvailableItems > math.MaxInt32 {
+		return cfg, fmt.Errorf("MAX_AVAILABLE_ITEMS must be between 0 and %d, got %d", math.MaxInt32, cfg.MaxAvailableItems)
+	}
+	if cfg.LowStockThreshold, err = getEnvInt("LOW_STOCK_THRESHOLD", DefaultLowStockThreshold); err != nil {
+		return cfg, err
+	}
+	if cfg.ProductsCacheTTL, err = getEnvDuration("CACHE_PRODUCTS_TTL", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.BackpressureMaxInUse, err = getEnvInt("BACKPRESSURE_MAX_IN_USE", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.BackpressureShedPercent, err = getEnvInt("BACKPRESSURE_SHED_PERCENT", 0); err != nil {
+		return cfg, err
+	}
+	if cfg.BackpressureShedPercent > 100 {
+		return cfg, fmt.Errorf("BACKPRESSURE_SHED_PERCENT must be between 0 and 100, got %d", cfg.BackpressureShedPercent)
+	}
+	if cfg.BackpressureDelay, err = getEnvDuration("BACKPRESSURE_DELAY", DefaultBackpressureDelay); err != nil {
+		return cfg, err
+	}
+	if cfg.BackpressureRetryAfter, err = getEnvDuration("BACKPRESSURE_RETRY_AFTER", DefaultBackpressureRetry); err != nil {
+		return cfg, err
+	}
+
+	return cfg, nil
+}
+
+// databaseURL returns DATABASE_URL, or when it's not set, the connection URL assembled from the DB_HOST, DB_PORT,
+// DB_USER, DB_PASSWORD, DB_NAME and DB_SSLMODE variables injected separately by some environments
+func databaseURL() (string, error) {
+	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
+		return databaseURL, nil
+	}
+
+	var missing []string
+	for _, key := range []string{"DB_HOST", "DB_USER", "DB_NAME"} {
+		if os.Getenv(key) == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("DATABASE_URL environment variable is not set, nor are %s to build it from", strings.Join(missing, ", "))
+	}
+	if port := os.Getenv("DB_PORT"); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			return "", fmt.Errorf("DB_PORT must be a port number, got %q", port)
+		}
+	}
+	sslmode := os.Getenv("DB_SSLMODE")
+	// lib/pq doesn't support the allow and prefer modes of libpq
+	if sslmode != "" && !slices.Contains([]string{"disable", "require", "verify-ca", "verify-full"}, sslmode) {
+		return "", fmt.Errorf("DB_SSLMODE must be one of disable, require, verify-ca or verify-full, got %q", sslmode)
+	}
+	return buildDSN(os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME"), sslmode), nil
+}
+
+// buildDSN assembles a Postgres connection URL from its parts, escaping the user and the password. An empty port
+// or sslmode leaves them to the lib/pq defaults, 5432 and "require"
+func buildDSN(host, port, user, password, dbname, sslmode string) string {
+	u := url.URL{Scheme: "postgres", Host: host, Path: "/" + dbname}
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	}
+	if password != "" {
+		u.User = url.UserPassword(user, password)
+	} else {
+		u.User = url.User(user)
+	}
+	if sslmode != "" {
+		u.RawQuery = url.Values{"sslmode": {sslmode}}.Encode()
+	}
+	return u.String()
+}
+
+func getEnvString(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvList splits a comma-separated value, dropping the empty items
+func getEnvList(key string) []string {
+	var items []string
+	for item := range strings.SplitSeq(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnvInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer, got %q", key, value)
+	}
+	return i, nil
+}
+
+func getEnvBool(key string, defaultValue bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be either true or false, got %q", key, value)
+	}
+	return b, nil
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative duration like 30s, got %q", key, value)
+	}
+	return duration, nil
+}
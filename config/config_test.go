@@ -0,0 +1,140 @@
+package config
+
+import "testing"
+
+func TestLoad(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://user:password@db:5432/mydb?sslmode=disable")
+
+	t.Run("Defaults", func(t *testing.T) {
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.StatementTimeout != DefaultStatementTimeout || cfg.StatementTimeoutSet || cfg.LogLevel != LogLevelInfo || len(cfg.CORSAllowedOrigins) != 0 {
+			t.Errorf("unexpected defaults: %+v", cfg)
+		}
+	})
+
+	t.Run("Explicit statement timeout", func(t *testing.T) {
+		t.Setenv("DB_STATEMENT_TIMEOUT", "0")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.StatementTimeout != 0 || !cfg.StatementTimeoutSet {
+			t.Errorf("expected an explicitly disabled statement timeout, got %v", cfg.StatementTimeout)
+		}
+	})
+
+	t.Run("CORS credentials with explicit origins", func(t *testing.T) {
+		t.Setenv("CORS_ALLOWED_ORIGINS", "https://shop.example.com, https://admin.example.com")
+		t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(cfg.CORSAllowedOrigins) != 2 || cfg.CORSAllowedOrigins[1] != "https://admin.example.com" || !cfg.CORSAllowCredentials {
+			t.Errorf("unexpected CORS configuration: %+v", cfg)
+		}
+	})
+
+	t.Run("CORS credentials with wildcard origin", func(t *testing.T) {
+		t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+		t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+
+		if _, err := Load(); err == nil {
+			t.Error("expected an error for credentials combined with the wildcard origin")
+		}
+	})
+
+	t.Run("Malformed disabled endpoint", func(t *testing.T) {
+		t.Setenv("DISABLED_ENDPOINTS", "POST /invoices,/products")
+
+		if _, err := Load(); err == nil {
+			t.Error("expected an error for an endpoint without a method")
+		}
+	})
+
+	t.Run("Database URL from discrete variables", func(t *testing.T) {
+		t.Setenv("DATABASE_URL", "")
+		t.Setenv("DB_HOST", "db")
+		t.Setenv("DB_PORT", "5433")
+		t.Setenv("DB_USER", "app")
+		t.Setenv("DB_PASSWORD", "p@ss/w:rd")
+		t.Setenv("DB_NAME", "shop")
+		t.Setenv("DB_SSLMODE", "disable")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expected := "postgres://app:p%40ss%2Fw%3Ard@db:5433/shop?sslmode=disable"; cfg.DatabaseURL != expected {
+			t.Errorf("expected %q, got %q", expected, cfg.DatabaseURL)
+		}
+	})
+
+	t.Run("Database URL with missing variables", func(t *testing.T) {
+		t.Setenv("DATABASE_URL", "")
+		t.Setenv("DB_HOST", "db")
+
+		_, err := Load()
+		if err == nil || err.Error() != "DATABASE_URL environment variable is not set, nor are DB_USER, DB_NAME to build it from" {
+			t.Errorf("expected an error naming the missing variables, got %v", err)
+		}
+	})
+
+	t.Run("Available items cap out of the int32 range", func(t *testing.T) {
+		t.Setenv("MAX_AVAILABLE_ITEMS", "3000000000")
+
+		if _, err := Load(); err == nil {
+			t.Error("expected an error for a cap the column can't hold")
+		}
+	})
+
+	t.Run("Database URL with an sslmode the driver lacks", func(t *testing.T) {
+		t.Setenv("DATABASE_URL", "")
+		t.Setenv("DB_HOST", "db")
+		t.Setenv("DB_USER", "app")
+		t.Setenv("DB_NAME", "shop")
+
+		for _, sslmode := range []string{"allow", "prefer"} {
+			t.Setenv("DB_SSLMODE", sslmode)
+			if _, err := Load(); err == nil {
+				t.Errorf("expected an error for DB_SSLMODE=%s", sslmode)
+			}
+		}
+	})
+
+	t.Run("Invalid invoice number pattern", func(t *testing.T) {
+		t.Setenv("INVOICE_NUMBER_PATTERN", "INV-(")
+
+		if _, err := Load(); err == nil {
+			t.Error("expected an error for an invalid regular expression")
+		}
+	})
+}
+
+func TestBuildDSN(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		port     string
+		password string
+		sslmode  string
+		expected string
+	}{
+		{name: "All the parts", host: "db", port: "5433", password: "secret", sslmode: "require", expected: "postgres://app:secret@db:5433/shop?sslmode=require"},
+		{name: "Defaults", host: "db", expected: "postgres://app@db/shop"},
+		{name: "IPv6 host", host: "::1", port: "5432", expected: "postgres://app@[::1]:5432/shop"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildDSN(tt.host, tt.port, "app", tt.password, "shop", tt.sslmode); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
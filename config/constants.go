@@ -1,14 +1,91 @@
 package config
 
+import "time"
+
 const (
-	ApiPrefix          = "/api/v1"
+	ApiRoot            = "/api/"
+	ApiVersion         = "v1"
+	ApiPrefix          = ApiRoot + ApiVersion
 	ProductsApiPrefix  = ApiPrefix + "/products"
 	CustomersApiPrefix = ApiPrefix + "/customers"
 	InvoicesApiPrefix  = ApiPrefix + "/invoices"
+	AdminApiPrefix     = ApiPrefix + "/admin"
+	ReportsApiPrefix   = ApiPrefix + "/reports"
+	HealthApiPath      = ApiPrefix + "/health"
+	RoutesApiPath      = ApiPrefix + "/routes"
+	ReadinessPath      = "/readyz"
 
 	ContentTypeJSON        = "application/json"
+	ContentTypeMergePatch  = "application/merge-patch+json"
+	ContentTypeJSONPatch   = "application/json-patch+json"
+	ContentTypeCSV         = "text/csv"
+	ContentTypeNDJSON      = "application/x-ndjson"
 	InternalServerErrorMsg = "Internal server error"
 	MethodNotAllowedMsg    = "Method not allowed"
 
+	DefaultServiceName           = "wallcraft-go-test-task"
+	DefaultServiceVersion        = "dev"
 	DefaultServiceBindingAddress = "0.0.0.0:8080"
+	UnixSocketPrefix             = "unix:"
+	DefaultStatementTimeout      = 30 * time.Second
+	DefaultAggregateQueryTimeout = 10 * time.Second
+	DefaultStartupDBTimeout      = 30 * time.Second
+	StartupDBRetryInitialDelay   = 250 * time.Millisecond
+	StartupDBRetryMaxDelay       = 5 * time.Second
+	DefaultHTTPIdleTimeout       = 120 * time.Second
+	DefaultHTTPReadHeaderTimeout = 10 * time.Second
+	DefaultShutdownTimeout       = 15 * time.Second
+	ReadinessCheckTimeout        = 2 * time.Second
+	DefaultMaxURLLength          = 2048
+	DefaultMaxQueryItems         = 100
+	DefaultMaxHeaderCount        = 100
+	DefaultLowStockThreshold     = 5
+	DefaultMaxDescriptionLength  = 10000
+	DefaultMaxAvailableItems     = 10_000_000
+	DefaultBackpressureDelay     = 100 * time.Millisecond
+	DefaultBackpressureRetry     = time.Second
+	DefaultUnavailableRetry      = 5 * time.Second
+
+	// AdminActor is recorded in the audit log for the changes made with the admin token
+	AdminActor = "admin"
+
+	LogLevelInfo      = "info"
+	LogLevelDebug     = "debug"
+	DebugBodyLogLimit = 4096
+
+	MaxCustomerNameLength   = 50
+	MaxCustomerImportSize   = 10 << 20
+	CustomerImportBatchSize = 500
+
+	MaxItemCountIntegerDigits  = 7
+	MaxItemCountFractionDigits = 3
+
+	DefaultAuditPageSize = 50
+	MaxAuditPageSize     = 100
+
+	MaxAvailabilityCartLines = 500
+
+	MaxInvoiceTotalsIDs = 100
+
+	// The exports are flushed to the client every ExportFlushRows rows
+	ExportFlushRows = 100
+
+	MinProductSearchLength = 2
+
+	DefaultTopProductsLimit = 10
+	MaxTopProductsLimit     = 100
+
+	// How long after its TTL the cached product list may still be served when the database fails
+	MaxProductsCacheStaleness = 5 * time.Minute
+
+	// The price column is NUMERIC(10, 2)
+	MaxPriceIntegerDigits  = 8
+	MaxPriceFractionDigits = 2
+)
+
+// The serializations of the invoice dates in the responses, see INVOICE_DATE_FORMAT
+const (
+	DateFormatRFC3339 = "rfc3339"
+	DateFormatDate    = "date"
+	DateFormatUnix    = "unix"
 )
@@ -0,0 +1,37 @@
+package config
+
+// Error codes sent in the "code" field of every error response. Unlike the messages they are meant to be matched
+// by clients, so once released a code must keep its meaning
+const (
+	ErrorCodeInternal             = "internal"
+	ErrorCodeNotFound             = "request.not_found"
+	ErrorCodeUnsupportedVersion   = "request.unsupported_api_version"
+	ErrorCodeMethodNotAllowed     = "request.method_not_allowed"
+	ErrorCodeMalformedJSON        = "request.malformed_json"
+	ErrorCodeMalformedCSV         = "request.malformed_csv"
+	ErrorCodeInvalidID            = "request.invalid_id"
+	ErrorCodeInvalidParameter     = "request.invalid_parameter"
+	ErrorCodeUnsupportedMediaType = "request.unsupported_media_type"
+	ErrorCodeNotAcceptable        = "request.not_acceptable"
+	ErrorCodeBodyTooLarge         = "request.body_too_large"
+	ErrorCodeURLTooLong           = "request.url_too_long"
+	ErrorCodeHeadersTooLarge      = "request.headers_too_large"
+	ErrorCodeTooManyQueryItems    = "request.too_many_query_items"
+	ErrorCodeUnauthorized         = "auth.unauthorized"
+	ErrorCodeEndpointDisabled     = "endpoint.disabled"
+	ErrorCodeOverloaded           = "service.overloaded"
+	ErrorCodeQueryTimeout         = "service.query_timeout"
+
+	ErrorCodeValidationRequired   = "validation.required"
+	ErrorCodeValidationInvalid    = "validation.invalid"
+	ErrorCodeValidationOutOfRange = "validation.out_of_range"
+
+	ErrorCodeProductNotFound        = "product.not_found"
+	ErrorCodeProductInUse           = "product.in_use"
+	ErrorCodeCustomerNotFound       = "customer.not_found"
+	ErrorCodeCustomerInUse          = "customer.in_use"
+	ErrorCodeInvoiceNotFound        = "invoice.not_found"
+	ErrorCodeInvoiceInUse           = "invoice.in_use"
+	ErrorCodeInvoiceNumberDuplicate = "invoice.number.duplicate"
+	ErrorCodeInvoiceItemNotFound    = "invoice_item.not_found"
+)
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+const (
+	AuditActionCreate        = "create"
+	AuditActionUpdate        = "update"
+	AuditActionDelete        = "delete"
+	AuditActionImport        = "import"
+	AuditActionAddProduct    = "add_product"
+	AuditActionRemoveProduct = "remove_product"
+	// The invoices of the customer were moved to another one
+	AuditActionReassignInvoices = "reassign_invoices"
+)
+
+// NewAuditEntry builds the audit log entry for a change made while handling the request in ctx.
+// A zero entityID is stored as NULL, e.g. for bulk changes
+func NewAuditEntry(ctx context.Context, entity string, entityID int32, action string) CreateAuditLogEntryParams {
+	actor := utils.Actor(ctx)
+	requestID := utils.RequestID(ctx)
+	return CreateAuditLogEntryParams{
+		Entity:    entity,
+		EntityID:  sql.NullInt32{Int32: entityID, Valid: entityID != 0},
+		Action:    action,
+		Actor:     sql.NullString{String: actor, Valid: actor != ""},
+		RequestID: sql.NullString{String: requestID, Valid: requestID != ""},
+	}
+}
+
+// audited runs the change in a transaction together with writing its audit log entry, so the entry can't be
+// lost once the change is committed. entry returns false for the results that didn't change anything
+func audited[T any](ctx context.Context, s *Store, change func(q *Queries) (T, error), entry func(result T) (CreateAuditLogEntryParams, bool)) (T, error) {
+	var result T
+	err := s.ExecTx(ctx, func(q *Queries) error {
+		var err error
+		if result, err = change(q); err != nil {
+			return err
+		}
+		if params, ok := entry(result); ok {
+			return q.CreateAuditLogEntry(ctx, params)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// The write queries below shadow the ones of the embedded Queries to record every successful change.
+// The changes made with ExecTx are audited by its callers
+
+func (s *Store) CreateProduct(ctx context.Context, arg CreateProductParams) (Product, error) {
+	return audited(ctx, s, func(q *Queries) (Product, error) {
+		return q.CreateProduct(ctx, arg)
+	}, func(product Product) (CreateAuditLogEntryParams, bool) {
+		return NewAuditEntry(ctx, "product", product.ID, AuditActionCreate), true
+	})
+}
+
+func (s *Store) UpdateProduct(ctx context.Context, arg UpdateProductParams) (Product, error) {
+	return audited(ctx, s, func(q *Queries) (Product, error) {
+		return q.UpdateProduct(ctx, arg)
+	}, func(product Product) (CreateAuditLogEntryParams, bool) {
+		return NewAuditEntry(ctx, "product", product.ID, AuditActionUpdate), true
+	})
+}
+
+func (s *Store) DeleteProduct(ctx context.Context, productID int32) (string, error) {
+	return audited(ctx, s, func(q *Queries) (string, error) {
+		return q.DeleteProduct(ctx, productID)
+	}, func(result string) (CreateAuditLogEntryParams, bool) {
+		return NewAuditEntry(ctx, "product", productID, AuditActionDelete), result == "success"
+	})
+}
+
+func (s *Store) CreateCustomer(ctx context.Context, arg CreateCustomerParams) (Customer, error) {
+	return audited(ctx, s, func(q *Queries) (Customer, error) {
+		return q.CreateCustomer(ctx, arg)
+	}, func(customer Customer) (CreateAuditLogEntryParams, bool) {
+		return NewAuditEntry(ctx, "customer", customer.ID, AuditActionCreate), true
+	})
+}
+
+func (s *Store) UpdateCustomer(ctx context.Context, arg UpdateCustomerParams) (Customer, error) {
+	return audited(ctx, s, func(q *Queries) (Customer, error) {
+		return q.UpdateCustomer(ctx, arg)
+	}, func(customer Customer) (CreateAuditLogEntryParams, bool) {
+		return NewAuditEntry(ctx, "customer", customer.ID, AuditActionUpdate), true
+	})
+}
+
+func (s *Store) DeleteCustomer(ctx context.Context, customerID int32) (string, error) {
+	return audited(ctx, s, func(q *Queries) (string, error) {
+		return q.DeleteCustomer(ctx, customerID)
+	}, func(result string) (CreateAuditLogEntryParams, bool) {
+		return NewAuditEntry(ctx, "customer", customerID, AuditActionDelete), result == "success"
+	})
+}
+
+func (s *Store) CreateInvoice(ctx context.Context, arg CreateInvoiceParams) (Invoice, error) {
+	return audited(ctx, s, func(q *Queries) (Invoice, error) {
+		return q.CreateInvoice(ctx, arg)
+	}, func(invoice Invoice) (CreateAuditLogEntryParams, bool) {
+		return NewAuditEntry(ctx, "invoice", invoice.ID, AuditActionCreate), true
+	})
+}
+
+func (s *Store) UpdateInvoice(ctx context.Context, arg UpdateInvoiceParams) (UpdateInvoiceRow, error) {
+	return audited(ctx, s, func(q *Queries) (UpdateInvoiceRow, error) {
+		return q.UpdateInvoice(ctx, arg)
+	}, func(row UpdateInvoiceRow) (CreateAuditLogEntryParams, bool) {
+		return NewAuditEntry(ctx, "invoice", arg.ID, AuditActionUpdate), row.Result == "success"
+	})
+}
+
+func (s *Store) DeleteInvoice(ctx context.Context, invoiceID int32) (string, error) {
+	return audited(ctx, s, func(q *Queries) (string, error) {
+		return q.DeleteInvoice(ctx, invoiceID)
+	}, func(result string) (CreateAuditLogEntryParams, bool) {
+		return NewAuditEntry(ctx, "invoice", invoiceID, AuditActionDelete), result == "success"
+	})
+}
+
+func (s *Store) AddProductToInvoice(ctx context.Context, arg AddProductToInvoiceParams) (InvoiceItem, error) {
+	return audited(ctx, s, func(q *Queries) (InvoiceItem, error) {
+		return q.AddProductToInvoice(ctx, arg)
+	}, func(item InvoiceItem) (CreateAuditLogEntryParams, bool) {
+		return NewAuditEntry(ctx, "invoice", arg.InvoiceID, AuditActionAddProduct), true
+	})
+}
+
+func (s *Store) DeleteProductFromInvoice(ctx context.Context, arg DeleteProductFromInvoiceParams) (string, error) {
+	return audited(ctx, s, func(q *Queries) (string, error) {
+		return q.DeleteProductFromInvoice(ctx, arg)
+	}, func(result string) (CreateAuditLogEntryParams, bool) {
+		return NewAuditEntry(ctx, "invoice", arg.InvoiceID, AuditActionRemoveProduct), result == "success"
+	})
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"iter"
+)
+
+// The exports stream whole tables, so unlike the generated :many queries they hand the rows over one at a time
+// instead of collecting them into a slice
+
+const exportProducts = `SELECT id, name, description, price, available_items, created_at, updated_at FROM product ORDER BY id`
+
+// ExportProducts iterates over every product ordered by id
+func (q *Queries) ExportProducts(ctx context.Context) iter.Seq2[Product, error] {
+	return exportRows(ctx, q.db, exportProducts, func(rows *sql.Rows, i *Product) error {
+		return rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.AvailableItems,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		)
+	})
+}
+
+const exportCustomers = `SELECT id, first_name, last_name, created_at, updated_at FROM customer ORDER BY id`
+
+// ExportCustomers iterates over every customer ordered by id
+func (q *Queries) ExportCustomers(ctx context.Context) iter.Seq2[Customer, error] {
+	return exportRows(ctx, q.db, exportCustomers, func(rows *sql.Rows, i *Customer) error {
+		return rows.Scan(
+			&i.ID,
+			&i.FirstName,
+			&i.LastName,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		)
+	})
+}
+
+const exportInvoices = `SELECT id, invoice_number, invoice_date, customer_id, created_at, updated_at FROM invoice ORDER BY id`
+
+// ExportInvoices iterates over every invoice ordered by id
+func (q *Queries) ExportInvoices(ctx context.Context) iter.Seq2[Invoice, error] {
+	return exportRows(ctx, q.db, exportInvoices, func(rows *sql.Rows, i *Invoice) error {
+		return rows.Scan(
+			&i.ID,
+			&i.InvoiceNumber,
+			&i.InvoiceDate,
+			&i.CustomerID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		)
+	})
+}
+
+// disableStatementTimeout lifts the statement_timeout of the pool for the rest of the transaction. Postgres counts
+// the time spent sending the rows to the client against it, so a large table streamed to a slow reader would be
+// cancelled partway through
+const disableStatementTimeout = `SET LOCAL statement_timeout = 0`
+
+// The Store exports below shadow the ones of the embedded Queries to run them without the statement timeout
+
+func (s *Store) ExportProducts(ctx context.Context) iter.Seq2[Product, error] {
+	return exportInTx(ctx, s, (*Queries).ExportProducts)
+}
+
+func (s *Store) ExportCustomers(ctx context.Context) iter.Seq2[Customer, error] {
+	return exportInTx(ctx, s, (*Queries).ExportCustomers)
+}
+
+func (s *Store) ExportInvoices(ctx context.Context) iter.Seq2[Invoice, error] {
+	return exportInTx(ctx, s, (*Queries).ExportInvoices)
+}
+
+// exportInTx runs the export in a read-only transaction with the statement timeout disabled. The transaction
+// lasts as long as the iteration
+func exportInTx[T any](ctx context.Context, s *Store, export func(q *Queries, ctx context.Context) iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		// Nothing is written, so ending the transaction early with a rollback is fine
+		defer tx.Rollback()
+		q := s.WithTx(tx)
+		if s.timed {
+			q = New(timedDBTX{tx})
+		}
+		if _, err := q.db.ExecContext(ctx, disableStatementTimeout); err != nil {
+			yield(zero, err)
+			return
+		}
+		for row, err := range export(q, ctx) {
+			if !yield(row, err) || err != nil {
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// exportRows runs the query when the iteration starts and yields the scanned rows. An error ends the iteration,
+// it's yielded along with the zero value. The rows are closed when the caller stops early as well
+func exportRows[T any](ctx context.Context, db DBTX, query string, scan func(rows *sql.Rows, i *T) error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var i T
+			if err := scan(rows, &i); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(i, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestExportWithoutStatementTimeout(t *testing.T) {
+	updatedAt := time.Date(2025, 6, 22, 14, 33, 12, 0, time.UTC)
+	fake := &FakeDB{
+		Columns: []string{"id", "first_name", "last_name", "created_at", "updated_at"},
+		Rows: [][]driver.Value{
+			{int64(1), "John", "Doe", updatedAt, updatedAt},
+			{int64(2), "Jane", "Smith", updatedAt, updatedAt},
+		},
+	}
+	db := fake.Open()
+	defer db.Close()
+	store := NewStore(db)
+
+	var ids []int32
+	for customer, err := range store.ExportCustomers(context.Background()) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, customer.ID)
+	}
+
+	if !slices.Equal(ids, []int32{1, 2}) {
+		t.Errorf("expected the customers 1 and 2, got %v", ids)
+	}
+	// The export runs in its own transaction, where the statement timeout of the pool is lifted first
+	expected := []string{"BEGIN READ ONLY", disableStatementTimeout, exportCustomers, "COMMIT"}
+	if statements := fake.Statements(); !slices.Equal(statements, expected) {
+		t.Errorf("expected the statements %q, got %q", expected, statements)
+	}
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeDB is a database/sql driver for the tests. It records the statements it's sent, and answers every query
+// with Rows after Delay. It's exported for the tests of the database_test package as well
+type FakeDB struct {
+	// Delay is how long every query and exec takes
+	Delay   time.Duration
+	Columns []string
+	Rows    [][]driver.Value
+
+	mu         sync.Mutex
+	statements []string
+}
+
+// Open returns a connection pool using the fake driver
+func (f *FakeDB) Open() *sql.DB {
+	return sql.OpenDB(fakeConnector{f})
+}
+
+// Statements returns the statements sent so far, the transactions appear as BEGIN (with the isolation level and
+// READ ONLY when they're set), COMMIT and ROLLBACK
+func (f *FakeDB) Statements() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.statements...)
+}
+
+// record appends a query or an exec to the statements and waits for Delay
+func (f *FakeDB) record(ctx context.Context, statement string) error {
+	f.append(statement)
+	select {
+	case <-time.After(f.Delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (f *FakeDB) append(statement string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statements = append(f.statements, statement)
+}
+
+type fakeConnector struct {
+	db *FakeDB
+}
+
+func (c fakeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeConn{db: c.db}, nil
+}
+
+func (c fakeConnector) Driver() driver.Driver {
+	return fakeDriver{c}
+}
+
+type fakeDriver struct {
+	connector fakeConnector
+}
+
+func (d fakeDriver) Open(name string) (driver.Conn, error) {
+	return d.connector.Connect(context.Background())
+}
+
+type fakeConn struct {
+	db *FakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements aren't supported by the fake driver")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	statement := "BEGIN"
+	if level := sql.IsolationLevel(opts.Isolation); level != sql.LevelDefault {
+		statement += " ISOLATION LEVEL " + strings.ToUpper(level.String())
+	}
+	if opts.ReadOnly {
+		statement += " READ ONLY"
+	}
+	c.db.append(statement)
+	return fakeTx{c.db}, nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.db.record(ctx, query); err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: c.db.Columns, rows: c.db.Rows}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.db.record(ctx, query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+type fakeTx struct {
+	db *FakeDB
+}
+
+func (tx fakeTx) Commit() error {
+	tx.db.append("COMMIT")
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.db.append("ROLLBACK")
+	return nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -9,6 +9,16 @@ import (
 	"time"
 )
 
+type AuditLog struct {
+	ID        int64
+	Entity    string
+	EntityID  sql.NullInt32
+	Action    string
+	Actor     sql.NullString
+	RequestID sql.NullString
+	CreatedAt time.Time
+}
+
 type Customer struct {
 	ID        int32
 	FirstName string
@@ -30,7 +40,8 @@ type InvoiceItem struct {
 	ID        int32
 	InvoiceID int32
 	ProductID int32
-	Count     int32
+	Count     string
+	UnitPrice string
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
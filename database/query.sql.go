@@ -9,37 +9,80 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 const addProductToInvoice = `-- name: AddProductToInvoice :one
-INSERT INTO invoice_item (invoice_id, product_id, count)
-VALUES ($1::int, $2::int, $3::int)
+INSERT INTO invoice_item (invoice_id, product_id, count, unit_price)
+SELECT $1::int, p.id, $2::numeric, p.price FROM product p WHERE p.id = $3::int
 ON CONFLICT (invoice_id, product_id)
 DO UPDATE SET
-    count = EXCLUDED.count
-RETURNING id, invoice_id, product_id, count, created_at, updated_at
+    count = EXCLUDED.count,
+    updated_at = NOW()
+RETURNING id, invoice_id, product_id, count, unit_price, created_at, updated_at
 `
 
 type AddProductToInvoiceParams struct {
 	InvoiceID int32
+	Count     string
 	ProductID int32
-	Count     int32
 }
 
+// The price of the product is recorded when it's first added, changing the count later keeps it
 func (q *Queries) AddProductToInvoice(ctx context.Context, arg AddProductToInvoiceParams) (InvoiceItem, error) {
-	row := q.db.QueryRowContext(ctx, addProductToInvoice, arg.InvoiceID, arg.ProductID, arg.Count)
+	row := q.db.QueryRowContext(ctx, addProductToInvoice, arg.InvoiceID, arg.Count, arg.ProductID)
 	var i InvoiceItem
 	err := row.Scan(
 		&i.ID,
 		&i.InvoiceID,
 		&i.ProductID,
 		&i.Count,
+		&i.UnitPrice,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
+const countOrphanedInvoiceItems = `-- name: CountOrphanedInvoiceItems :one
+SELECT COUNT(*) FROM invoice_item ii
+WHERE NOT EXISTS (SELECT 1 FROM invoice i WHERE i.id = ii.invoice_id)
+    OR NOT EXISTS (SELECT 1 FROM product p WHERE p.id = ii.product_id)
+`
+
+// The foreign keys prevent orphans, unless they were bypassed, e.g. by a direct database operation
+func (q *Queries) CountOrphanedInvoiceItems(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOrphanedInvoiceItems)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createAuditLogEntry = `-- name: CreateAuditLogEntry :exec
+INSERT INTO audit_log (entity, entity_id, action, actor, request_id)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreateAuditLogEntryParams struct {
+	Entity    string
+	EntityID  sql.NullInt32
+	Action    string
+	Actor     sql.NullString
+	RequestID sql.NullString
+}
+
+func (q *Queries) CreateAuditLogEntry(ctx context.Context, arg CreateAuditLogEntryParams) error {
+	_, err := q.db.ExecContext(ctx, createAuditLogEntry,
+		arg.Entity,
+		arg.EntityID,
+		arg.Action,
+		arg.Actor,
+		arg.RequestID,
+	)
+	return err
+}
+
 const createCustomer = `-- name: CreateCustomer :one
 INSERT INTO customer (first_name, last_name)
 VALUES ($1, $2)
@@ -64,6 +107,24 @@ func (q *Queries) CreateCustomer(ctx context.Context, arg CreateCustomerParams)
 	return i, err
 }
 
+const createCustomers = `-- name: CreateCustomers :execrows
+INSERT INTO customer (first_name, last_name)
+SELECT unnest($1::text[]), unnest($2::text[])
+`
+
+type CreateCustomersParams struct {
+	FirstNames []string
+	LastNames  []string
+}
+
+func (q *Queries) CreateCustomers(ctx context.Context, arg CreateCustomersParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createCustomers, pq.Array(arg.FirstNames), pq.Array(arg.LastNames))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createInvoice = `-- name: CreateInvoice :one
 INSERT INTO invoice (invoice_number, invoice_date, customer_id)
 VALUES ($1::text, $2::timestamp, $3::int)
@@ -175,6 +236,20 @@ func (q *Queries) DeleteInvoice(ctx context.Context, invoiceID int32) (string, e
 	return result, err
 }
 
+const deleteOrphanedInvoiceItems = `-- name: DeleteOrphanedInvoiceItems :execrows
+DELETE FROM invoice_item ii
+WHERE NOT EXISTS (SELECT 1 FROM invoice i WHERE i.id = ii.invoice_id)
+    OR NOT EXISTS (SELECT 1 FROM product p WHERE p.id = ii.product_id)
+`
+
+func (q *Queries) DeleteOrphanedInvoiceItems(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteOrphanedInvoiceItems)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteProduct = `-- name: DeleteProduct :one
 WITH check_product AS (
     SELECT EXISTS(SELECT 1 FROM product WHERE id = $1::int) AS product_exists
@@ -236,6 +311,69 @@ func (q *Queries) DeleteProductFromInvoice(ctx context.Context, arg DeleteProduc
 	return result, err
 }
 
+const diffInvoiceItems = `-- name: DiffInvoiceItems :many
+SELECT p.id AS product_id, p.name, COALESCE(b.unit_price, a.unit_price)::numeric AS price, a.count AS from_count, b.count AS to_count,
+       (COALESCE(b.sum, 0) - COALESCE(a.sum, 0))::numeric AS sum_delta,
+       CAST(
+           (SELECT COALESCE(SUM(CAST(unit_price * count AS numeric(18,2))), 0) FROM invoice_item WHERE invoice_id = $1::int)
+           - (SELECT COALESCE(SUM(CAST(unit_price * count AS numeric(18,2))), 0) FROM invoice_item WHERE invoice_id = $2::int)
+       AS numeric(20,2)) AS total_delta
+FROM (SELECT product_id, count, unit_price, CAST(unit_price * count AS numeric(18,2)) AS sum FROM invoice_item WHERE invoice_id = $2::int) a
+FULL JOIN (SELECT product_id, count, unit_price, CAST(unit_price * count AS numeric(18,2)) AS sum FROM invoice_item WHERE invoice_id = $1::int) b ON b.product_id = a.product_id
+JOIN product p ON p.id = COALESCE(a.product_id, b.product_id)
+WHERE a.count IS DISTINCT FROM b.count OR a.unit_price IS DISTINCT FROM b.unit_price
+ORDER BY p.id
+`
+
+type DiffInvoiceItemsParams struct {
+	ToInvoiceID   int32
+	FromInvoiceID int32
+}
+
+type DiffInvoiceItemsRow struct {
+	ProductID  int32
+	Name       string
+	Price      string
+	FromCount  sql.NullString
+	ToCount    sql.NullString
+	SumDelta   string
+	TotalDelta string
+}
+
+// Lists the lines whose count or unit price differs between the two invoices. The difference of the invoice
+// totals is repeated in every row, it's computed from the whole invoices rather than from the listed lines.
+// The prices are the ones on the to invoice, or on the from invoice for the removed lines
+func (q *Queries) DiffInvoiceItems(ctx context.Context, arg DiffInvoiceItemsParams) ([]DiffInvoiceItemsRow, error) {
+	rows, err := q.db.QueryContext(ctx, diffInvoiceItems, arg.ToInvoiceID, arg.FromInvoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DiffInvoiceItemsRow
+	for rows.Next() {
+		var i DiffInvoiceItemsRow
+		if err := rows.Scan(
+			&i.ProductID,
+			&i.Name,
+			&i.Price,
+			&i.FromCount,
+			&i.ToCount,
+			&i.SumDelta,
+			&i.TotalDelta,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getCustomer = `-- name: GetCustomer :one
 SELECT id, first_name, last_name, created_at, updated_at FROM customer WHERE id = $1
 `
@@ -253,6 +391,37 @@ func (q *Queries) GetCustomer(ctx context.Context, id int32) (Customer, error) {
 	return i, err
 }
 
+const getCustomerWithInvoiceCount = `-- name: GetCustomerWithInvoiceCount :one
+SELECT c.id, c.first_name, c.last_name, c.created_at, c.updated_at, COUNT(i.id) AS invoice_count
+FROM customer c
+LEFT JOIN invoice i ON i.customer_id = c.id
+WHERE c.id = $1
+GROUP BY c.id
+`
+
+type GetCustomerWithInvoiceCountRow struct {
+	ID           int32
+	FirstName    string
+	LastName     string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	InvoiceCount int64
+}
+
+func (q *Queries) GetCustomerWithInvoiceCount(ctx context.Context, id int32) (GetCustomerWithInvoiceCountRow, error) {
+	row := q.db.QueryRowContext(ctx, getCustomerWithInvoiceCount, id)
+	var i GetCustomerWithInvoiceCountRow
+	err := row.Scan(
+		&i.ID,
+		&i.FirstName,
+		&i.LastName,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.InvoiceCount,
+	)
+	return i, err
+}
+
 const getInvoice = `-- name: GetInvoice :one
 SELECT id, invoice_number, invoice_date, customer_id, created_at, updated_at FROM invoice WHERE id = $1
 `
@@ -271,6 +440,49 @@ func (q *Queries) GetInvoice(ctx context.Context, id int32) (Invoice, error) {
 	return i, err
 }
 
+const getInvoiceDocument = `-- name: GetInvoiceDocument :one
+SELECT
+    i.id,
+    i.invoice_number,
+    i.invoice_date,
+    i.customer_id,
+    c.first_name,
+    c.last_name,
+    CAST(COALESCE((SELECT SUM(CAST(ii.unit_price * ii.count AS numeric(18,2))) FROM invoice_item ii WHERE ii.invoice_id = i.id), 0) AS numeric(20,2)) AS total
+FROM
+    invoice i
+    JOIN customer c ON c.id = i.customer_id
+WHERE
+    i.id = $1::int
+`
+
+type GetInvoiceDocumentRow struct {
+	ID            int32
+	InvoiceNumber string
+	InvoiceDate   time.Time
+	CustomerID    int32
+	FirstName     string
+	LastName      string
+	Total         string
+}
+
+// The invoice together with its customer and the total of all its items, which is the sum of their rounded sums. The lines
+// fit numeric(18,2) with the largest allowed count and price
+func (q *Queries) GetInvoiceDocument(ctx context.Context, invoiceID int32) (GetInvoiceDocumentRow, error) {
+	row := q.db.QueryRowContext(ctx, getInvoiceDocument, invoiceID)
+	var i GetInvoiceDocumentRow
+	err := row.Scan(
+		&i.ID,
+		&i.InvoiceNumber,
+		&i.InvoiceDate,
+		&i.CustomerID,
+		&i.FirstName,
+		&i.LastName,
+		&i.Total,
+	)
+	return i, err
+}
+
 const getProduct = `-- name: GetProduct :one
 SELECT id, name, description, price, available_items, created_at, updated_at FROM product WHERE id = $1
 `
@@ -290,6 +502,69 @@ func (q *Queries) GetProduct(ctx context.Context, id int32) (Product, error) {
 	return i, err
 }
 
+const getProductForUpdate = `-- name: GetProductForUpdate :one
+SELECT id, name, description, price, available_items, created_at, updated_at FROM product WHERE id = $1 FOR UPDATE
+`
+
+// Locks the product until the end of the transaction, for the read-modify-write updates
+func (q *Queries) GetProductForUpdate(ctx context.Context, id int32) (Product, error) {
+	row := q.db.QueryRowContext(ctx, getProductForUpdate, id)
+	var i Product
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Price,
+		&i.AvailableItems,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listAuditLog = `-- name: ListAuditLog :many
+SELECT id, entity, entity_id, action, actor, request_id, created_at FROM audit_log
+WHERE id > $1::bigint
+ORDER BY id
+LIMIT $2::int
+`
+
+type ListAuditLogParams struct {
+	AfterID    int64
+	MaxEntries int32
+}
+
+func (q *Queries) ListAuditLog(ctx context.Context, arg ListAuditLogParams) ([]AuditLog, error) {
+	rows, err := q.db.QueryContext(ctx, listAuditLog, arg.AfterID, arg.MaxEntries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditLog
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Entity,
+			&i.EntityID,
+			&i.Action,
+			&i.Actor,
+			&i.RequestID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listCustomers = `-- name: ListCustomers :many
 
 SELECT id, first_name, last_name, created_at, updated_at FROM customer ORDER BY id LIMIT 100
@@ -327,28 +602,23 @@ func (q *Queries) ListCustomers(ctx context.Context) ([]Customer, error) {
 	return items, nil
 }
 
-const listInvoices = `-- name: ListInvoices :many
-
-SELECT id, invoice_number, invoice_date, customer_id, created_at, updated_at FROM invoice ORDER BY id LIMIT 100
+const listCustomersByIDs = `-- name: ListCustomersByIDs :many
+SELECT id, first_name, last_name, created_at, updated_at FROM customer WHERE id = ANY($1::int[]) ORDER BY id
 `
 
-// ----------------------------------------------------------------------------------------------------------------------
-// invoice
-// ----------------------------------------------------------------------------------------------------------------------
-func (q *Queries) ListInvoices(ctx context.Context) ([]Invoice, error) {
-	rows, err := q.db.QueryContext(ctx, listInvoices)
+func (q *Queries) ListCustomersByIDs(ctx context.Context, ids []int32) ([]Customer, error) {
+	rows, err := q.db.QueryContext(ctx, listCustomersByIDs, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Invoice
+	var items []Customer
 	for rows.Next() {
-		var i Invoice
+		var i Customer
 		if err := rows.Scan(
 			&i.ID,
-			&i.InvoiceNumber,
-			&i.InvoiceDate,
-			&i.CustomerID,
+			&i.FirstName,
+			&i.LastName,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -365,29 +635,85 @@ func (q *Queries) ListInvoices(ctx context.Context) ([]Invoice, error) {
 	return items, nil
 }
 
-const listProducts = `-- name: ListProducts :many
+const listCustomersByActivity = `-- name: ListCustomersByActivity :many
+SELECT
+    c.id,
+    c.first_name,
+    c.last_name,
+    MAX(i.invoice_date) AS last_invoice_date,
+    CAST(COALESCE(SUM(CAST(ii.unit_price * ii.count AS numeric(18,2))), 0) AS numeric(20,2)) AS total_spend
+FROM
+    customer c
+    LEFT JOIN invoice i ON i.customer_id = c.id
+    LEFT JOIN invoice_item ii ON ii.invoice_id = i.id
+GROUP BY
+    c.id
+ORDER BY
+    COUNT(i.id) = 0,
+    CASE WHEN $1::text = 'last_invoice' THEN MAX(i.invoice_date) END,
+    CASE WHEN $1::text = '-last_invoice' THEN MAX(i.invoice_date) END DESC,
+    CASE WHEN $1::text = 'total_spend' THEN COALESCE(SUM(CAST(ii.unit_price * ii.count AS numeric(18,2))), 0) END,
+    CASE WHEN $1::text = '-total_spend' THEN COALESCE(SUM(CAST(ii.unit_price * ii.count AS numeric(18,2))), 0) END DESC,
+    c.id
+LIMIT
+    100
+`
 
-SELECT id, name, description, price, available_items, created_at, updated_at FROM product ORDER BY id LIMIT 100
+type ListCustomersByActivityRow struct {
+	ID              int32
+	FirstName       string
+	LastName        string
+	LastInvoiceDate sql.NullTime
+	TotalSpend      string
+}
+
+// The customers without invoices come last in either direction
+func (q *Queries) ListCustomersByActivity(ctx context.Context, sort string) ([]ListCustomersByActivityRow, error) {
+	rows, err := q.db.QueryContext(ctx, listCustomersByActivity, sort)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCustomersByActivityRow
+	for rows.Next() {
+		var i ListCustomersByActivityRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FirstName,
+			&i.LastName,
+			&i.LastInvoiceDate,
+			&i.TotalSpend,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCustomersModifiedSince = `-- name: ListCustomersModifiedSince :many
+SELECT id, first_name, last_name, created_at, updated_at FROM customer WHERE updated_at > $1::timestamptz ORDER BY updated_at, id LIMIT 100
 `
 
-// ----------------------------------------------------------------------------------------------------------------------
-// product
-// ----------------------------------------------------------------------------------------------------------------------
-func (q *Queries) ListProducts(ctx context.Context) ([]Product, error) {
-	rows, err := q.db.QueryContext(ctx, listProducts)
+func (q *Queries) ListCustomersModifiedSince(ctx context.Context, modifiedSince time.Time) ([]Customer, error) {
+	rows, err := q.db.QueryContext(ctx, listCustomersModifiedSince, modifiedSince)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Product
+	var items []Customer
 	for rows.Next() {
-		var i Product
+		var i Customer
 		if err := rows.Scan(
 			&i.ID,
-			&i.Name,
-			&i.Description,
-			&i.Price,
-			&i.AvailableItems,
+			&i.FirstName,
+			&i.LastName,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -404,54 +730,75 @@ func (q *Queries) ListProducts(ctx context.Context) ([]Product, error) {
 	return items, nil
 }
 
-const listProductsFromInvoice = `-- name: ListProductsFromInvoice :many
-
+const listInvoiceTotals = `-- name: ListInvoiceTotals :many
 SELECT
-    p.id,
-    p.name,
-    p.description,
-    p.price,
-    ii.count,
-    CAST((p.price * ii.count) AS numeric(10,2)) AS sum
+    i.id AS invoice_id,
+    CAST(COALESCE(SUM(CAST(ii.unit_price * ii.count AS numeric(18,2))), 0) AS numeric(20,2)) AS total
 FROM
-    invoice_item ii
-    JOIN Product p ON ii.product_id = p.id
+    invoice i
+    LEFT JOIN invoice_item ii ON ii.invoice_id = i.id
 WHERE
-    ii.invoice_id = $1
+    i.id = ANY($1::int[])
+GROUP BY
+    i.id
 ORDER BY
-    p.id
- LIMIT
-    100
+    i.id
 `
 
-type ListProductsFromInvoiceRow struct {
-	ID          int32
-	Name        string
-	Description sql.NullString
-	Price       string
-	Count       int32
-	Sum         string
+type ListInvoiceTotalsRow struct {
+	InvoiceID int32
+	Total     string
+}
+
+// The invoices without items total 0.00, the missing ones are left out. The lines are rounded to the cent before
+// being added up, like the sums of the listed items, so that the total is the sum of those
+func (q *Queries) ListInvoiceTotals(ctx context.Context, ids []int32) ([]ListInvoiceTotalsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listInvoiceTotals, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListInvoiceTotalsRow
+	for rows.Next() {
+		var i ListInvoiceTotalsRow
+		if err := rows.Scan(&i.InvoiceID, &i.Total); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
+const listInvoices = `-- name: ListInvoices :many
+
+SELECT id, invoice_number, invoice_date, customer_id, created_at, updated_at FROM invoice ORDER BY id LIMIT 100
+`
+
 // ----------------------------------------------------------------------------------------------------------------------
-// invoice_item
+// invoice
 // ----------------------------------------------------------------------------------------------------------------------
-func (q *Queries) ListProductsFromInvoice(ctx context.Context, invoiceID int32) ([]ListProductsFromInvoiceRow, error) {
-	rows, err := q.db.QueryContext(ctx, listProductsFromInvoice, invoiceID)
+func (q *Queries) ListInvoices(ctx context.Context) ([]Invoice, error) {
+	rows, err := q.db.QueryContext(ctx, listInvoices)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []ListProductsFromInvoiceRow
+	var items []Invoice
 	for rows.Next() {
-		var i ListProductsFromInvoiceRow
+		var i Invoice
 		if err := rows.Scan(
 			&i.ID,
-			&i.Name,
-			&i.Description,
-			&i.Price,
-			&i.Count,
-			&i.Sum,
+			&i.InvoiceNumber,
+			&i.InvoiceDate,
+			&i.CustomerID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -466,11 +813,649 @@ func (q *Queries) ListProductsFromInvoice(ctx context.Context, invoiceID int32)
 	return items, nil
 }
 
-const updateCustomer = `-- name: UpdateCustomer :one
+const listInvoicesModifiedSince = `-- name: ListInvoicesModifiedSince :many
+SELECT id, invoice_number, invoice_date, customer_id, created_at, updated_at FROM invoice WHERE updated_at > $1::timestamptz ORDER BY updated_at, id LIMIT 100
+`
+
+func (q *Queries) ListInvoicesModifiedSince(ctx context.Context, modifiedSince time.Time) ([]Invoice, error) {
+	rows, err := q.db.QueryContext(ctx, listInvoicesModifiedSince, modifiedSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Invoice
+	for rows.Next() {
+		var i Invoice
+		if err := rows.Scan(
+			&i.ID,
+			&i.InvoiceNumber,
+			&i.InvoiceDate,
+			&i.CustomerID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProductStock = `-- name: ListProductStock :many
+SELECT id, available_items FROM product WHERE id = ANY($1::int[])
+`
+
+type ListProductStockRow struct {
+	ID             int32
+	AvailableItems int32
+}
+
+func (q *Queries) ListProductStock(ctx context.Context, ids []int32) ([]ListProductStockRow, error) {
+	rows, err := q.db.QueryContext(ctx, listProductStock, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProductStockRow
+	for rows.Next() {
+		var i ListProductStockRow
+		if err := rows.Scan(&i.ID, &i.AvailableItems); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProducts = `-- name: ListProducts :many
+
+SELECT id, name, description, price, available_items, created_at, updated_at FROM product ORDER BY id LIMIT 100
+`
+
+// ----------------------------------------------------------------------------------------------------------------------
+// product
+// ----------------------------------------------------------------------------------------------------------------------
+func (q *Queries) ListProducts(ctx context.Context) ([]Product, error) {
+	rows, err := q.db.QueryContext(ctx, listProducts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Product
+	for rows.Next() {
+		var i Product
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.AvailableItems,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProductsFromInvoice = `-- name: ListProductsFromInvoice :many
+
+SELECT
+    p.id,
+    p.name,
+    p.description,
+    ii.unit_price AS price,
+    ii.count,
+    CAST((ii.unit_price * ii.count) AS numeric(18,2)) AS sum
+FROM
+    invoice_item ii
+    JOIN Product p ON ii.product_id = p.id
+WHERE
+    ii.invoice_id = $1::int
+ORDER BY
+    CASE WHEN $2::text = 'name' THEN p.name END,
+    CASE WHEN $2::text = '-name' THEN p.name END DESC,
+    CASE WHEN $2::text = 'count' THEN ii.count END,
+    CASE WHEN $2::text = '-count' THEN ii.count END DESC,
+    CASE WHEN $2::text = 'price' THEN ii.unit_price END,
+    CASE WHEN $2::text = '-price' THEN ii.unit_price END DESC,
+    CASE WHEN $2::text = 'sum' THEN ii.unit_price * ii.count END,
+    CASE WHEN $2::text = '-sum' THEN ii.unit_price * ii.count END DESC,
+    p.id
+ LIMIT
+    100
+`
+
+type ListProductsFromInvoiceParams struct {
+	InvoiceID int32
+	Sort      string
+}
+
+type ListProductsFromInvoiceRow struct {
+	ID          int32
+	Name        string
+	Description sql.NullString
+	Price       string
+	Count       string
+	Sum         string
+}
+
+// ----------------------------------------------------------------------------------------------------------------------
+// invoice_item
+// ----------------------------------------------------------------------------------------------------------------------
+func (q *Queries) ListProductsFromInvoice(ctx context.Context, arg ListProductsFromInvoiceParams) ([]ListProductsFromInvoiceRow, error) {
+	rows, err := q.db.QueryContext(ctx, listProductsFromInvoice, arg.InvoiceID, arg.Sort)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProductsFromInvoiceRow
+	for rows.Next() {
+		var i ListProductsFromInvoiceRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.Count,
+			&i.Sum,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProductsFromInvoiceWithRunningTotal = `-- name: ListProductsFromInvoiceWithRunningTotal :many
+SELECT
+    p.id,
+    p.name,
+    p.description,
+    ii.unit_price AS price,
+    ii.count,
+    CAST((ii.unit_price * ii.count) AS numeric(18,2)) AS sum,
+    (SUM(CAST((ii.unit_price * ii.count) AS numeric(18,2))) OVER line_order)::numeric AS running_total
+FROM
+    invoice_item ii
+    JOIN Product p ON ii.product_id = p.id
+WHERE
+    ii.invoice_id = $1::int
+WINDOW
+    line_order AS (
+        ORDER BY
+            CASE WHEN $2::text = 'name' THEN p.name END,
+            CASE WHEN $2::text = '-name' THEN p.name END DESC,
+            CASE WHEN $2::text = 'count' THEN ii.count END,
+            CASE WHEN $2::text = '-count' THEN ii.count END DESC,
+            CASE WHEN $2::text = 'price' THEN ii.unit_price END,
+            CASE WHEN $2::text = '-price' THEN ii.unit_price END DESC,
+            CASE WHEN $2::text = 'sum' THEN ii.unit_price * ii.count END,
+            CASE WHEN $2::text = '-sum' THEN ii.unit_price * ii.count END DESC,
+            p.id
+    )
+ORDER BY
+    CASE WHEN $2::text = 'name' THEN p.name END,
+    CASE WHEN $2::text = '-name' THEN p.name END DESC,
+    CASE WHEN $2::text = 'count' THEN ii.count END,
+    CASE WHEN $2::text = '-count' THEN ii.count END DESC,
+    CASE WHEN $2::text = 'price' THEN ii.unit_price END,
+    CASE WHEN $2::text = '-price' THEN ii.unit_price END DESC,
+    CASE WHEN $2::text = 'sum' THEN ii.unit_price * ii.count END,
+    CASE WHEN $2::text = '-sum' THEN ii.unit_price * ii.count END DESC,
+    p.id
+ LIMIT
+    100
+`
+
+type ListProductsFromInvoiceWithRunningTotalParams struct {
+	InvoiceID int32
+	Sort      string
+}
+
+type ListProductsFromInvoiceWithRunningTotalRow struct {
+	ID           int32
+	Name         string
+	Description  sql.NullString
+	Price        string
+	Count        string
+	Sum          string
+	RunningTotal string
+}
+
+func (q *Queries) ListProductsFromInvoiceWithRunningTotal(ctx context.Context, arg ListProductsFromInvoiceWithRunningTotalParams) ([]ListProductsFromInvoiceWithRunningTotalRow, error) {
+	rows, err := q.db.QueryContext(ctx, listProductsFromInvoiceWithRunningTotal, arg.InvoiceID, arg.Sort)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProductsFromInvoiceWithRunningTotalRow
+	for rows.Next() {
+		var i ListProductsFromInvoiceWithRunningTotalRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.Count,
+			&i.Sum,
+			&i.RunningTotal,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProductsFromInvoiceWithoutSum = `-- name: ListProductsFromInvoiceWithoutSum :many
+SELECT
+    p.id,
+    p.name,
+    p.description,
+    ii.unit_price AS price,
+    ii.count
+FROM
+    invoice_item ii
+    JOIN Product p ON ii.product_id = p.id
+WHERE
+    ii.invoice_id = $1::int
+ORDER BY
+    CASE WHEN $2::text = 'name' THEN p.name END,
+    CASE WHEN $2::text = '-name' THEN p.name END DESC,
+    CASE WHEN $2::text = 'count' THEN ii.count END,
+    CASE WHEN $2::text = '-count' THEN ii.count END DESC,
+    CASE WHEN $2::text = 'price' THEN ii.unit_price END,
+    CASE WHEN $2::text = '-price' THEN ii.unit_price END DESC,
+    CASE WHEN $2::text = 'sum' THEN ii.unit_price * ii.count END,
+    CASE WHEN $2::text = '-sum' THEN ii.unit_price * ii.count END DESC,
+    p.id
+ LIMIT
+    100
+`
+
+type ListProductsFromInvoiceWithoutSumParams struct {
+	InvoiceID int32
+	Sort      string
+}
+
+type ListProductsFromInvoiceWithoutSumRow struct {
+	ID          int32
+	Name        string
+	Description sql.NullString
+	Price       string
+	Count       string
+}
+
+func (q *Queries) ListProductsFromInvoiceWithoutSum(ctx context.Context, arg ListProductsFromInvoiceWithoutSumParams) ([]ListProductsFromInvoiceWithoutSumRow, error) {
+	rows, err := q.db.QueryContext(ctx, listProductsFromInvoiceWithoutSum, arg.InvoiceID, arg.Sort)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProductsFromInvoiceWithoutSumRow
+	for rows.Next() {
+		var i ListProductsFromInvoiceWithoutSumRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.Count,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProductsModifiedSince = `-- name: ListProductsModifiedSince :many
+SELECT id, name, description, price, available_items, created_at, updated_at FROM product WHERE updated_at > $1::timestamptz ORDER BY updated_at, id LIMIT 100
+`
+
+func (q *Queries) ListProductsModifiedSince(ctx context.Context, modifiedSince time.Time) ([]Product, error) {
+	rows, err := q.db.QueryContext(ctx, listProductsModifiedSince, modifiedSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Product
+	for rows.Next() {
+		var i Product
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.AvailableItems,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProductsWithStockValue = `-- name: ListProductsWithStockValue :many
+SELECT p.id, p.name, p.description, p.price, p.available_items, p.created_at, p.updated_at, (p.price * p.available_items)::numeric AS stock_value,
+       (SUM(p.price * p.available_items) OVER ())::numeric AS total_stock_value
+FROM product p
+ORDER BY p.id
+LIMIT 100
+`
+
+type ListProductsWithStockValueRow struct {
+	ID              int32
+	Name            string
+	Description     sql.NullString
+	Price           string
+	AvailableItems  int32
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	StockValue      string
+	TotalStockValue string
+}
+
+// The total is taken over all the products, not only the listed ones
+func (q *Queries) ListProductsWithStockValue(ctx context.Context) ([]ListProductsWithStockValueRow, error) {
+	rows, err := q.db.QueryContext(ctx, listProductsWithStockValue)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProductsWithStockValueRow
+	for rows.Next() {
+		var i ListProductsWithStockValueRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.AvailableItems,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.StockValue,
+			&i.TotalStockValue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSalesByPeriod = `-- name: ListSalesByPeriod :many
+SELECT
+    date_trunc($1::text, i.invoice_date, 'UTC')::timestamptz AS period,
+    COUNT(DISTINCT i.id) AS invoice_count,
+    CAST(COALESCE(SUM(CAST(ii.unit_price * ii.count AS numeric(18,2))), 0) AS numeric(20,2)) AS total
+FROM
+    invoice i
+    LEFT JOIN invoice_item ii ON ii.invoice_id = i.id
+WHERE
+    i.invoice_date >= $2::timestamptz
+    AND i.invoice_date < $3::timestamptz
+GROUP BY
+    period
+ORDER BY
+    period
+`
+
+type ListSalesByPeriodParams struct {
+	Granularity string
+	FromDate    time.Time
+	ToDate      time.Time
+}
+
+type ListSalesByPeriodRow struct {
+	Period       time.Time
+	InvoiceCount int64
+	Total        string
+}
+
+// The periods are truncated in UTC, the ones without invoices are left out
+func (q *Queries) ListSalesByPeriod(ctx context.Context, arg ListSalesByPeriodParams) ([]ListSalesByPeriodRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSalesByPeriod, arg.Granularity, arg.FromDate, arg.ToDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSalesByPeriodRow
+	for rows.Next() {
+		var i ListSalesByPeriodRow
+		if err := rows.Scan(
+			&i.Period,
+			&i.InvoiceCount,
+			&i.Total,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTopProducts = `-- name: ListTopProducts :many
+SELECT p.id, p.name, p.description, p.price, p.available_items, p.created_at, p.updated_at,
+    COALESCE(SUM(ii.count), 0)::numeric AS sold
+FROM product p
+LEFT JOIN invoice_item ii ON ii.product_id = p.id
+GROUP BY p.id
+HAVING $1::boolean OR COUNT(ii.id) > 0
+ORDER BY sold DESC, p.id
+LIMIT $2::int
+`
+
+type ListTopProductsParams struct {
+	IncludeUnsold bool
+	MaxEntries    int32
+}
+
+type ListTopProductsRow struct {
+	ID             int32
+	Name           string
+	Description    sql.NullString
+	Price          string
+	AvailableItems int32
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Sold           string
+}
+
+func (q *Queries) ListTopProducts(ctx context.Context, arg ListTopProductsParams) ([]ListTopProductsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTopProducts, arg.IncludeUnsold, arg.MaxEntries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTopProductsRow
+	for rows.Next() {
+		var i ListTopProductsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.AvailableItems,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Sold,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnusedProducts = `-- name: ListUnusedProducts :many
+SELECT p.id, p.name, p.description, p.price, p.available_items, p.created_at, p.updated_at
+FROM product p
+WHERE NOT EXISTS (SELECT 1 FROM invoice_item ii WHERE ii.product_id = p.id)
+ORDER BY p.id
+LIMIT 100
+`
+
+func (q *Queries) ListUnusedProducts(ctx context.Context) ([]Product, error) {
+	rows, err := q.db.QueryContext(ctx, listUnusedProducts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Product
+	for rows.Next() {
+		var i Product
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.AvailableItems,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reassignCustomerInvoices = `-- name: ReassignCustomerInvoices :execrows
+UPDATE invoice SET customer_id = $1::int, updated_at = NOW() WHERE customer_id = $2::int
+`
+
+type ReassignCustomerInvoicesParams struct {
+	TargetID int32
+	SourceID int32
+}
+
+func (q *Queries) ReassignCustomerInvoices(ctx context.Context, arg ReassignCustomerInvoicesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, reassignCustomerInvoices, arg.TargetID, arg.SourceID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const searchProducts = `-- name: SearchProducts :many
+SELECT p.id, p.name, p.description, p.price, p.available_items, p.created_at, p.updated_at
+FROM product p
+WHERE p.name ILIKE '%' || $1::text || '%' ESCAPE '\' OR p.description ILIKE '%' || $1::text || '%' ESCAPE '\'
+ORDER BY p.name ILIKE '%' || $1::text || '%' ESCAPE '\' DESC, p.id
+LIMIT 100
+`
+
+// The search term is matched as a substring, the products having it in the name come first. The wildcards in it
+// are escaped with a backslash
+func (q *Queries) SearchProducts(ctx context.Context, search string) ([]Product, error) {
+	rows, err := q.db.QueryContext(ctx, searchProducts, search)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Product
+	for rows.Next() {
+		var i Product
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Price,
+			&i.AvailableItems,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateCustomer = `-- name: UpdateCustomer :one
 UPDATE customer
 SET
     first_name = $2,
-    last_name = $3
+    last_name = $3,
+    updated_at = NOW()
 WHERE id = $1
 RETURNING id, first_name, last_name, created_at, updated_at
 `
@@ -504,7 +1489,8 @@ WITH
         SET
             invoice_number = $2::text,
             invoice_date = $3::timestamp,
-            customer_id = $4::int
+            customer_id = $4::int,
+            updated_at = NOW()
         WHERE id = $1
         RETURNING id, invoice_number, invoice_date, customer_id, created_at, updated_at
     )
@@ -556,13 +1542,42 @@ func (q *Queries) UpdateInvoice(ctx context.Context, arg UpdateInvoiceParams) (U
 	return i, err
 }
 
+const updateInvoiceItemCount = `-- name: UpdateInvoiceItemCount :one
+UPDATE invoice_item
+SET count = $1::numeric, updated_at = NOW()
+WHERE invoice_id = $2::int AND product_id = $3::int
+RETURNING id, invoice_id, product_id, count, unit_price, created_at, updated_at
+`
+
+type UpdateInvoiceItemCountParams struct {
+	Count     string
+	InvoiceID int32
+	ProductID int32
+}
+
+func (q *Queries) UpdateInvoiceItemCount(ctx context.Context, arg UpdateInvoiceItemCountParams) (InvoiceItem, error) {
+	row := q.db.QueryRowContext(ctx, updateInvoiceItemCount, arg.Count, arg.InvoiceID, arg.ProductID)
+	var i InvoiceItem
+	err := row.Scan(
+		&i.ID,
+		&i.InvoiceID,
+		&i.ProductID,
+		&i.Count,
+		&i.UnitPrice,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const updateProduct = `-- name: UpdateProduct :one
 UPDATE product
 SET
     name = $2,
     description = $3,
     price = $4,
-    available_items = $5
+    available_items = $5,
+    updated_at = NOW()
 WHERE id = $1
 RETURNING id, name, description, price, available_items, created_at, updated_at
 `
@@ -595,3 +1610,30 @@ func (q *Queries) UpdateProduct(ctx context.Context, arg UpdateProductParams) (P
 	)
 	return i, err
 }
+
+const updateProductPrice = `-- name: UpdateProductPrice :one
+UPDATE product
+SET price = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, description, price, available_items, created_at, updated_at
+`
+
+type UpdateProductPriceParams struct {
+	ID    int32
+	Price string
+}
+
+func (q *Queries) UpdateProductPrice(ctx context.Context, arg UpdateProductPriceParams) (Product, error) {
+	row := q.db.QueryRowContext(ctx, updateProductPrice, arg.ID, arg.Price)
+	var i Product
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Price,
+		&i.AvailableItems,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+// Store bundles the generated queries with the connection pool they run on, so several queries can be grouped
+// into one transaction
+type Store struct {
+	*Queries
+	db    *sql.DB
+	timed bool
+	// productReads coalesces the concurrent reads of the same product, see GetProduct
+	productReads utils.Coalescer[int32, Product]
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{Queries: New(db), db: db}
+}
+
+// NewTimedStore is like NewStore, but also records the time spent in the database in the request timing
+// of the context the queries are run with (see utils.WithRequestTiming)
+func NewTimedStore(db *sql.DB) *Store {
+	return &Store{Queries: New(timedDBTX{db}), db: db, timed: true}
+}
+
+// ExecTx runs fn within a transaction. The transaction is committed if fn returns nil and rolled back otherwise
+func (s *Store) ExecTx(ctx context.Context, fn func(q *Queries) error) error {
+	if err := s.execTx(ctx, nil, fn); err != nil {
+		return err
+	}
+	// The reads started before the commit may miss what it changed
+	s.productReads.ForgetAll()
+	return nil
+}
+
+// ExecSnapshotTx runs fn within a read-only REPEATABLE READ transaction, so all of its queries see the database
+// as it was when the first one started
+func (s *Store) ExecSnapshotTx(ctx context.Context, fn func(q *Queries) error) error {
+	return s.execTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}, fn)
+}
+
+func (s *Store) execTx(ctx context.Context, opts *sql.TxOptions, fn func(q *Queries) error) error {
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	q := s.WithTx(tx)
+	if s.timed {
+		q = New(timedDBTX{tx})
+	}
+	if err := fn(q); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetProduct shares one query among the concurrent reads of the same product, e.g. of a popular one. The result
+// isn't kept after the query, and the reads starting after a committed change don't join the older queries
+func (s *Store) GetProduct(ctx context.Context, id int32) (Product, error) {
+	return s.productReads.Do(ctx, id, func(ctx context.Context) (Product, error) {
+		return s.Queries.GetProduct(ctx, id)
+	})
+}
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"slices"
+	"testing"
+)
+
+func TestExecSnapshotTx(t *testing.T) {
+	fake := &FakeDB{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(3)}}}
+	db := fake.Open()
+	defer db.Close()
+	store := NewStore(db)
+
+	err := store.ExecSnapshotTx(context.Background(), func(q *Queries) error {
+		_, err := q.CountOrphanedInvoiceItems(context.Background())
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"BEGIN ISOLATION LEVEL REPEATABLE READ READ ONLY", countOrphanedInvoiceItems, "COMMIT"}
+	if statements := fake.Statements(); !slices.Equal(statements, expected) {
+		t.Errorf("expected the statements %q, got %q", expected, statements)
+	}
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+// timedDBTX adds the time spent in every call to the request timing in the context. For the queries returning
+// rows only the time until the rows are available is counted, not the time spent scanning them
+type timedDBTX struct {
+	DBTX
+}
+
+func (db timedDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer addDBTimeSince(ctx, time.Now())
+	return db.DBTX.ExecContext(ctx, query, args...)
+}
+
+func (db timedDBTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	defer addDBTimeSince(ctx, time.Now())
+	return db.DBTX.PrepareContext(ctx, query)
+}
+
+func (db timedDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer addDBTimeSince(ctx, time.Now())
+	return db.DBTX.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext defers errors until Scan, by then the query has already been executed
+func (db timedDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer addDBTimeSince(ctx, time.Now())
+	return db.DBTX.QueryRowContext(ctx, query, args...)
+}
+
+func addDBTimeSince(ctx context.Context, start time.Time) {
+	utils.AddDBTime(ctx, time.Since(start))
+}
@@ -0,0 +1,74 @@
+package database_test
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+	"github.com/egor-markin/wallcraft-go-test-task/handlers"
+	"github.com/egor-markin/wallcraft-go-test-task/middleware"
+)
+
+// TestTimedStore drives a handler through a timed store and checks the database time it reports, both for
+// the queries of the store itself and for the ones of a transaction
+func TestTimedStore(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	createdAt := time.Date(2025, 6, 22, 14, 33, 12, 0, time.UTC)
+	fake := &database.FakeDB{
+		Delay:   delay,
+		Columns: []string{"id", "name", "description", "price", "available_items", "created_at", "updated_at"},
+		Rows:    [][]driver.Value{{int64(1), "Lamp", nil, "10.00", int64(5), createdAt, createdAt}},
+	}
+	db := fake.Open()
+	defer db.Close()
+	store := database.NewTimedStore(db)
+	productHandler := &handlers.ProductHandler{Queries: store, Tx: handlers.NewTxFunc[handlers.ProductQueries](store)}
+	handler := middleware.ServerTiming(http.HandlerFunc(productHandler.ProductHandler))
+
+	dbTime := func(t *testing.T, w *httptest.ResponseRecorder) time.Duration {
+		t.Helper()
+		match := regexp.MustCompile(`^db;dur=(\d+\.\d{3}),`).FindStringSubmatch(w.Header().Get("Server-Timing"))
+		if match == nil {
+			t.Fatalf("unexpected Server-Timing header: %q", w.Header().Get("Server-Timing"))
+		}
+		milliseconds, _ := strconv.ParseFloat(match[1], 64)
+		return time.Duration(milliseconds * float64(time.Millisecond))
+	}
+
+	t.Run("Query of the store", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"/1", nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if got := dbTime(t, w); got < delay {
+			t.Errorf("expected at least %v in the database, got %v", delay, got)
+		}
+	})
+
+	t.Run("Queries of a transaction", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPatch, config.ProductsApiPrefix+"/1", strings.NewReader(`{"price":"12.00"}`))
+		req.Header.Set("Content-Type", config.ContentTypeMergePatch)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		// The lock, the update and the audit entry
+		if got := dbTime(t, w); got < 3*delay {
+			t.Errorf("expected at least %v in the database, got %v", 3*delay, got)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+type AuditQueries interface {
+	ListAuditLog(ctx context.Context, params database.ListAuditLogParams) ([]database.AuditLog, error)
+}
+
+type AuditHandler struct {
+	Queries AuditQueries
+}
+
+type auditEntryResponse struct {
+	ID        int64     `json:"id"`
+	Entity    string    `json:"entity"`
+	EntityID  *ID       `json:"entity_id"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+type auditLogResponse struct {
+	Entries []auditEntryResponse `json:"entries"`
+	// NextAfterID is the after_id of the next page, it's omitted on the last page
+	NextAfterID int64 `json:"next_after_id,omitempty"`
+}
+
+// AuditHandler lists the audit log, oldest entries first. The pages are requested with ?after_id= and ?limit=
+func (h *AuditHandler) AuditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		writeAllowedMethods(w, http.MethodGet)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	// GET /admin/audit
+	params := database.ListAuditLogParams{MaxEntries: config.DefaultAuditPageSize}
+	if value := r.URL.Query().Get("after_id"); value != "" {
+		afterID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || afterID < 0 {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, "Invalid after_id")
+			return
+		}
+		params.AfterID = afterID
+	}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > config.MaxAuditPageSize {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(config.MaxAuditPageSize))
+			return
+		}
+		params.MaxEntries = int32(limit)
+	}
+
+	entries, err := h.Queries.ListAuditLog(r.Context(), params)
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+
+	response := auditLogResponse{Entries: []auditEntryResponse{}}
+	for _, entry := range entries {
+		var entityID *ID
+		if entry.EntityID.Valid {
+			id := ID(entry.EntityID.Int32)
+			entityID = &id
+		}
+		response.Entries = append(response.Entries, auditEntryResponse{
+			ID:        entry.ID,
+			Entity:    entry.Entity,
+			EntityID:  entityID,
+			Action:    entry.Action,
+			Actor:     entry.Actor.String,
+			RequestID: entry.RequestID.String,
+			CreatedAt: entry.CreatedAt,
+		})
+	}
+	if len(entries) == int(params.MaxEntries) {
+		response.NextAfterID = entries[len(entries)-1].ID
+	}
+	writeServerResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+type auditMockQueries struct {
+	ListAuditLogFunc func(ctx context.Context, params database.ListAuditLogParams) ([]database.AuditLog, error)
+}
+
+func (m *auditMockQueries) ListAuditLog(ctx context.Context, params database.ListAuditLogParams) ([]database.AuditLog, error) {
+	return m.ListAuditLogFunc(ctx, params)
+}
+
+func TestAuditHandler(t *testing.T) {
+	mockQueries := &auditMockQueries{}
+	handler := &AuditHandler{Queries: mockQueries}
+
+	mockQueries.ListAuditLogFunc = func(ctx context.Context, params database.ListAuditLogParams) ([]database.AuditLog, error) {
+		var entries []database.AuditLog
+		for id := params.AfterID + 1; id <= 3 && len(entries) < int(params.MaxEntries); id++ {
+			entries = append(entries, database.AuditLog{
+				ID:       id,
+				Entity:   "product",
+				EntityID: sql.NullInt32{Int32: int32(id * 10), Valid: true},
+				Action:   database.AuditActionCreate,
+			})
+		}
+		return entries, nil
+	}
+
+	list := func(url string) auditLogResponse {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+
+		handler.AuditHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+
+		var response auditLogResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return response
+	}
+
+	t.Run("GET admin/audit - Pages", func(t *testing.T) {
+		first := list(config.AdminApiPrefix + "/audit?limit=2")
+		if len(first.Entries) != 2 || first.NextAfterID != 2 || *first.Entries[1].EntityID != 20 {
+			t.Errorf("unexpected first page: %+v", first)
+		}
+
+		last := list(config.AdminApiPrefix + "/audit?limit=2&after_id=2")
+		if len(last.Entries) != 1 || last.Entries[0].ID != 3 || last.NextAfterID != 0 {
+			t.Errorf("unexpected last page: %+v", last)
+		}
+	})
+
+	t.Run("GET admin/audit - Invalid limit", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.AdminApiPrefix+"/audit?limit=1000", nil)
+		w := httptest.NewRecorder()
+
+		handler.AuditHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+type CleanupQueries interface {
+	CountOrphanedInvoiceItems(ctx context.Context) (int64, error)
+	DeleteOrphanedInvoiceItems(ctx context.Context) (int64, error)
+	CreateAuditLogEntry(ctx context.Context, params database.CreateAuditLogEntryParams) error
+}
+
+type CleanupHandler struct {
+	Queries CleanupQueries
+	Tx      TxFunc[CleanupQueries]
+}
+
+type orphanedItemsResponse struct {
+	// Count is the number of the orphaned items found, they're only deleted when Applied is true
+	Count   int64 `json:"count"`
+	Applied bool  `json:"applied"`
+}
+
+// OrphanedItemsHandler finds the invoice items whose invoice or product is gone. It's a dry run unless
+// ?apply=true is passed, then the items are deleted
+func (h *CleanupHandler) OrphanedItemsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		writeAllowedMethods(w, http.MethodPost)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	// POST /admin/cleanup/orphaned-items
+	apply, err := parseBoolParam(r, "apply")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+		return
+	}
+
+	if !apply {
+		count, err := h.Queries.CountOrphanedInvoiceItems(r.Context())
+		if err != nil {
+			writeInternalServerError(w, err)
+			return
+		}
+		writeServerResponse(w, http.StatusOK, orphanedItemsResponse{Count: count})
+		return
+	}
+
+	var deleted int64
+	err = h.Tx(r.Context(), func(q CleanupQueries) error {
+		var err error
+		if deleted, err = q.DeleteOrphanedInvoiceItems(r.Context()); err != nil || deleted == 0 {
+			return err
+		}
+		return q.CreateAuditLogEntry(r.Context(), database.NewAuditEntry(r.Context(), "invoice_item", 0, database.AuditActionDelete))
+	})
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	writeServerResponse(w, http.StatusOK, orphanedItemsResponse{Count: deleted, Applied: true})
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+// cleanupMockQueries keeps the invoice items in memory, the orphans are the ones of the missing invoices
+// and products
+type cleanupMockQueries struct {
+	invoices     []int32
+	products     []int32
+	items        []database.InvoiceItem
+	auditEntries []database.CreateAuditLogEntryParams
+}
+
+func (m *cleanupMockQueries) isOrphan(item database.InvoiceItem) bool {
+	return !slices.Contains(m.invoices, item.InvoiceID) || !slices.Contains(m.products, item.ProductID)
+}
+
+func (m *cleanupMockQueries) CountOrphanedInvoiceItems(ctx context.Context) (int64, error) {
+	var count int64
+	for _, item := range m.items {
+		if m.isOrphan(item) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *cleanupMockQueries) DeleteOrphanedInvoiceItems(ctx context.Context) (int64, error) {
+	before := len(m.items)
+	m.items = slices.DeleteFunc(m.items, m.isOrphan)
+	return int64(before - len(m.items)), nil
+}
+
+func (m *cleanupMockQueries) CreateAuditLogEntry(ctx context.Context, params database.CreateAuditLogEntryParams) error {
+	m.auditEntries = append(m.auditEntries, params)
+	return nil
+}
+
+func (m *cleanupMockQueries) tx(ctx context.Context, fn func(q CleanupQueries) error) error {
+	return fn(m)
+}
+
+func TestOrphanedItemsHandler(t *testing.T) {
+	mockQueries := &cleanupMockQueries{
+		invoices: []int32{1},
+		products: []int32{10},
+		items: []database.InvoiceItem{
+			{ID: 1, InvoiceID: 1, ProductID: 10},
+			// The invoice was deleted behind the API's back
+			{ID: 2, InvoiceID: 2, ProductID: 10},
+			// And so was the product
+			{ID: 3, InvoiceID: 1, ProductID: 11},
+		},
+	}
+	handler := &CleanupHandler{Queries: mockQueries, Tx: mockQueries.tx}
+
+	cleanup := func(url string) orphanedItemsResponse {
+		req := httptest.NewRequest(http.MethodPost, url, nil)
+		w := httptest.NewRecorder()
+
+		handler.OrphanedItemsHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		var response orphanedItemsResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return response
+	}
+
+	t.Run("POST admin/cleanup/orphaned-items - Dry run", func(t *testing.T) {
+		response := cleanup(config.AdminApiPrefix + "/cleanup/orphaned-items")
+		if response.Count != 2 || response.Applied {
+			t.Errorf("expected 2 orphans found, got %+v", response)
+		}
+		if len(mockQueries.items) != 3 {
+			t.Errorf("expected the dry run to keep all the items, %d left", len(mockQueries.items))
+		}
+	})
+
+	t.Run("POST admin/cleanup/orphaned-items - Apply", func(t *testing.T) {
+		response := cleanup(config.AdminApiPrefix + "/cleanup/orphaned-items?apply=true")
+		if response.Count != 2 || !response.Applied {
+			t.Errorf("expected 2 orphans deleted, got %+v", response)
+		}
+		if len(mockQueries.items) != 1 || mockQueries.items[0].ID != 1 {
+			t.Errorf("expected only item 1 to be left, got %+v", mockQueries.items)
+		}
+		if len(mockQueries.auditEntries) != 1 || mockQueries.auditEntries[0].Action != database.AuditActionDelete {
+			t.Errorf("expected one delete audit entry, got %+v", mockQueries.auditEntries)
+		}
+
+		// Nothing is left to clean up, nor to audit
+		if response := cleanup(config.AdminApiPrefix + "/cleanup/orphaned-items?apply=true"); response.Count != 0 {
+			t.Errorf("expected no orphans left, got %+v", response)
+		}
+		if len(mockQueries.auditEntries) != 1 {
+			t.Errorf("expected no audit entry for an empty cleanup, got %d entries", len(mockQueries.auditEntries))
+		}
+	})
+}
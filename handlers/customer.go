@@ -5,8 +5,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/egor-markin/wallcraft-go-test-task/config"
 	"github.com/egor-markin/wallcraft-go-test-task/database"
@@ -16,14 +20,22 @@ import (
 
 type CustomerQueries interface {
 	ListCustomers(ctx context.Context) ([]database.Customer, error)
+	ListCustomersByIDs(ctx context.Context, ids []int32) ([]database.Customer, error)
+	ListCustomersModifiedSince(ctx context.Context, modifiedSince time.Time) ([]database.Customer, error)
+	ListCustomersByActivity(ctx context.Context, sort string) ([]database.ListCustomersByActivityRow, error)
 	CreateCustomer(ctx context.Context, params database.CreateCustomerParams) (database.Customer, error)
 	GetCustomer(ctx context.Context, id int32) (database.Customer, error)
+	GetCustomerWithInvoiceCount(ctx context.Context, id int32) (database.GetCustomerWithInvoiceCountRow, error)
 	UpdateCustomer(ctx context.Context, params database.UpdateCustomerParams) (database.Customer, error)
 	DeleteCustomer(ctx context.Context, id int32) (string, error)
+	CreateCustomers(ctx context.Context, params database.CreateCustomersParams) (int64, error)
+	ReassignCustomerInvoices(ctx context.Context, params database.ReassignCustomerInvoicesParams) (int64, error)
+	CreateAuditLogEntry(ctx context.Context, params database.CreateAuditLogEntryParams) error
 }
 
 type CustomerHandler struct {
 	Queries CustomerQueries
+	Tx      TxFunc[CustomerQueries]
 }
 
 type createCustomerRequest struct {
@@ -35,24 +47,79 @@ type updateCustomerRequest struct {
 	LastName  string `json:"last_name"`
 }
 type customerResponse struct {
-	ID        int32  `json:"id"`
+	ID        ID     `json:"id"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 }
+type customerWithInvoiceCountResponse struct {
+	customerResponse
+	InvoiceCount int64 `json:"invoice_count"`
+}
 
 func (h *CustomerHandler) CustomersHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		// GET /customers
-		customers, err := h.Queries.ListCustomers(r.Context())
+		ids, err := parseIDsParam(r, "ids")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+			return
+		}
+		strict, err := parseBoolParam(r, "strict")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+			return
+		}
+		modifiedSince, err := parseTimeParam(r, "modified_since")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+			return
+		}
+		sort, err := parseSortParam(r, customerActivitySortFields)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+			return
+		}
+		if !modifiedSince.IsZero() {
+			if ids != nil || sort != "" {
+				writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, "modified_since can't be combined with ids or sort")
+				return
+			}
+			h.writeModifiedCustomers(w, r, modifiedSince)
+			return
+		}
+		if sort != "" {
+			if ids != nil {
+				writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, "sort can't be combined with ids")
+				return
+			}
+			h.writeCustomersByActivity(w, r, sort)
+			return
+		}
+
+		var customers []database.Customer
+		if ids != nil {
+			// The missing ids are simply left out, unless ?strict=true
+			customers, err = h.Queries.ListCustomersByIDs(r.Context(), ids)
+		} else {
+			customers, err = h.Queries.ListCustomers(r.Context())
+		}
 		if err != nil {
 			writeInternalServerError(w, err)
 			return
 		}
+		if strict {
+			for _, id := range ids {
+				if !slices.ContainsFunc(customers, func(c database.Customer) bool { return c.ID == id }) {
+					writeError(w, http.StatusNotFound, config.ErrorCodeCustomerNotFound, fmt.Sprintf("customer %d not found", id))
+					return
+				}
+			}
+		}
 		response := []customerResponse{}
 		for _, customer := range customers {
 			response = append(response, customerResponse{
-				ID:        customer.ID,
+				ID:        ID(customer.ID),
 				FirstName: customer.FirstName,
 				LastName:  customer.LastName,
 			})
@@ -67,11 +134,11 @@ func (h *CustomerHandler) CustomersHandler(w http.ResponseWriter, r *http.Reques
 		}
 
 		if strings.TrimSpace(customer.FirstName) == "" {
-			http.Error(w, "First name is required", http.StatusBadRequest)
+			writeValidationError(w, config.ErrorCodeValidationRequired, "first_name", "First name is required")
 			return
 		}
 		if strings.TrimSpace(customer.LastName) == "" {
-			http.Error(w, "Last name is required", http.StatusBadRequest)
+			writeValidationError(w, config.ErrorCodeValidationRequired, "last_name", "Last name is required")
 			return
 		}
 
@@ -83,13 +150,15 @@ func (h *CustomerHandler) CustomersHandler(w http.ResponseWriter, r *http.Reques
 			writeInternalServerError(w, err)
 			return
 		}
-		writeServerResponse(w, http.StatusCreated, customerResponse{
-			ID:        createdCustomer.ID,
+		writeCreatedResponse(w, r, config.CustomersApiPrefix+"/"+strconv.Itoa(int(createdCustomer.ID)), customerResponse{
+			ID:        ID(createdCustomer.ID),
 			FirstName: createdCustomer.FirstName,
 			LastName:  createdCustomer.LastName,
 		})
+	case http.MethodOptions:
+		writeAllowedMethods(w, http.MethodGet, http.MethodPost)
 	default:
-		http.Error(w, config.MethodNotAllowedMsg, http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodPost)
 	}
 }
 
@@ -97,24 +166,50 @@ func (h *CustomerHandler) CustomerHandler(w http.ResponseWriter, r *http.Request
 	// Extract the customer ID from the URL path
 	id, err := utils.ExtractTrailingID(r.URL.Path)
 	if err != nil {
-		http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidID, "Invalid customer ID")
 		return
 	}
 
 	switch r.Method {
 	case http.MethodGet:
 		// GET /customers/{id}
+		withInvoiceCount, err := parseBoolParam(r, "with_invoice_count")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+			return
+		}
+		if withInvoiceCount {
+			customer, err := h.Queries.GetCustomerWithInvoiceCount(r.Context(), int32(id))
+			if err != nil {
+				if err == sql.ErrNoRows {
+					writeError(w, http.StatusNotFound, config.ErrorCodeCustomerNotFound, "Customer not found")
+				} else {
+					writeInternalServerError(w, err)
+				}
+				return
+			}
+			writeServerResponse(w, http.StatusOK, customerWithInvoiceCountResponse{
+				customerResponse: customerResponse{
+					ID:        ID(customer.ID),
+					FirstName: customer.FirstName,
+					LastName:  customer.LastName,
+				},
+				InvoiceCount: customer.InvoiceCount,
+			})
+			return
+		}
+
 		customer, err := h.Queries.GetCustomer(r.Context(), int32(id))
 		if err != nil {
 			if err == sql.ErrNoRows {
-				http.Error(w, "Customer not found", http.StatusNotFound)
+				writeError(w, http.StatusNotFound, config.ErrorCodeCustomerNotFound, "Customer not found")
 			} else {
 				writeInternalServerError(w, err)
 			}
 			return
 		}
 		writeServerResponse(w, http.StatusOK, customerResponse{
-			ID:        customer.ID,
+			ID:        ID(customer.ID),
 			FirstName: customer.FirstName,
 			LastName:  customer.LastName,
 		})
@@ -127,11 +222,11 @@ func (h *CustomerHandler) CustomerHandler(w http.ResponseWriter, r *http.Request
 		}
 
 		if strings.TrimSpace(customer.FirstName) == "" {
-			http.Error(w, "First name is required", http.StatusBadRequest)
+			writeValidationError(w, config.ErrorCodeValidationRequired, "first_name", "First name is required")
 			return
 		}
 		if strings.TrimSpace(customer.LastName) == "" {
-			http.Error(w, "Last name is required", http.StatusBadRequest)
+			writeValidationError(w, config.ErrorCodeValidationRequired, "last_name", "Last name is required")
 			return
 		}
 
@@ -142,14 +237,14 @@ func (h *CustomerHandler) CustomerHandler(w http.ResponseWriter, r *http.Request
 		})
 		if err != nil {
 			if err == sql.ErrNoRows {
-				http.Error(w, "Customer not found", http.StatusNotFound)
+				writeError(w, http.StatusNotFound, config.ErrorCodeCustomerNotFound, "Customer not found")
 			} else {
 				writeInternalServerError(w, err)
 			}
 			return
 		}
 		writeServerResponse(w, http.StatusOK, customerResponse{
-			ID:        updatedCustomer.ID,
+			ID:        ID(updatedCustomer.ID),
 			FirstName: updatedCustomer.FirstName,
 			LastName:  updatedCustomer.LastName,
 		})
@@ -163,7 +258,7 @@ func (h *CustomerHandler) CustomerHandler(w http.ResponseWriter, r *http.Request
 				if pqErr.Code == "23503" { // 23503 is the SQLSTATE code for foreign key violation
 					// Check the constraint name
 					if pqErr.Constraint == "invoice_customer_id_fkey" {
-						http.Error(w, "cannot delete customer: customer is referenced in the invoice table", http.StatusConflict)
+						writeError(w, http.StatusConflict, config.ErrorCodeCustomerInUse, "cannot delete customer: customer is referenced in the invoice table")
 					} else {
 						writeInternalServerError(w, err)
 					}
@@ -176,11 +271,13 @@ func (h *CustomerHandler) CustomerHandler(w http.ResponseWriter, r *http.Request
 			return
 		}
 		if deletionResult == "customer_not_found" {
-			http.Error(w, "Customer not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, config.ErrorCodeCustomerNotFound, "Customer not found")
 			return
 		}
-		w.WriteHeader(http.StatusNoContent)
+		writeDeletedResponse(w, deletedResponse{Deleted: true, ID: ID(id)})
+	case http.MethodOptions:
+		writeAllowedMethods(w, http.MethodGet, http.MethodPatch, http.MethodDelete)
 	default:
-		http.Error(w, config.MethodNotAllowedMsg, http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodPatch, http.MethodDelete)
 	}
 }
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+// customerActivitySortFields lists the fields GET /customers can be sorted by via ?sort=
+var customerActivitySortFields = []string{"last_invoice", "total_spend"}
+
+type customerActivityResponse struct {
+	customerResponse
+	// LastInvoiceDate is null for the customers without invoices
+	LastInvoiceDate *Timestamp `json:"last_invoice_date"`
+	TotalSpend      string     `json:"total_spend"`
+}
+
+// writeCustomersByActivity answers GET /customers?sort=, ordering the customers by their most recent invoice or by
+// how much they've spent on all the invoices
+func (h *CustomerHandler) writeCustomersByActivity(w http.ResponseWriter, r *http.Request, sort string) {
+	customers, err := h.Queries.ListCustomersByActivity(r.Context(), sort)
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	response := []customerActivityResponse{}
+	for _, customer := range customers {
+		activity := customerActivityResponse{
+			customerResponse: customerResponse{
+				ID:        ID(customer.ID),
+				FirstName: customer.FirstName,
+				LastName:  customer.LastName,
+			},
+			TotalSpend: customer.TotalSpend,
+		}
+		if customer.LastInvoiceDate.Valid {
+			lastInvoiceDate := Timestamp(customer.LastInvoiceDate.Time)
+			activity.LastInvoiceDate = &lastInvoiceDate
+		}
+		response = append(response, activity)
+	}
+	writeServerResponse(w, http.StatusOK, response)
+}
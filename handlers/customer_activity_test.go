@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+func TestCustomersByActivity(t *testing.T) {
+	mockQueries := &customerMockQueries{}
+	handler := &CustomerHandler{Queries: mockQueries}
+
+	// Customers 2 and 1 have invoices, 3 has none and the query puts it last
+	var sorts []string
+	mockQueries.ListCustomersByActivityFunc = func(ctx context.Context, sort string) ([]database.ListCustomersByActivityRow, error) {
+		sorts = append(sorts, sort)
+		return []database.ListCustomersByActivityRow{
+			{ID: 2, FirstName: "Jane", LastName: "Roe", LastInvoiceDate: sql.NullTime{Time: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), Valid: true}, TotalSpend: "120.50"},
+			{ID: 1, FirstName: "John", LastName: "Doe", LastInvoiceDate: sql.NullTime{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true}, TotalSpend: "30.00"},
+			{ID: 3, FirstName: "Jack", LastName: "Poe", TotalSpend: "0.00"},
+		}, nil
+	}
+	mockQueries.ListCustomersFunc = func(ctx context.Context) ([]database.Customer, error) {
+		t.Error("expected the activity query to be used")
+		return nil, nil
+	}
+
+	t.Run("GET customers - Sorted by spend", func(t *testing.T) {
+		sorts = nil
+		req := httptest.NewRequest(http.MethodGet, config.CustomersApiPrefix+"?sort=-total_spend", nil)
+		w := httptest.NewRecorder()
+
+		handler.CustomersHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if len(sorts) != 1 || sorts[0] != "-total_spend" {
+			t.Errorf("expected the -total_spend sort, got %v", sorts)
+		}
+
+		var response []customerActivityResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		expected := []ID{2, 1, 3}
+		if len(response) != len(expected) {
+			t.Fatalf("expected %d customers, got %d", len(expected), len(response))
+		}
+		for i, id := range expected {
+			if response[i].ID != id {
+				t.Errorf("position %d: expected customer %d, got %d", i, id, response[i].ID)
+			}
+		}
+		if response[0].TotalSpend != "120.50" || response[0].LastInvoiceDate == nil {
+			t.Errorf("unexpected activity of an active customer: %+v", response[0])
+		}
+		if response[2].TotalSpend != "0.00" || response[2].LastInvoiceDate != nil {
+			t.Errorf("expected no spend and a null last invoice date for an inactive customer, got %+v", response[2])
+		}
+	})
+
+	t.Run("GET customers - Sorted by the last invoice", func(t *testing.T) {
+		sorts = nil
+		req := httptest.NewRequest(http.MethodGet, config.CustomersApiPrefix+"?sort=last_invoice", nil)
+		w := httptest.NewRecorder()
+
+		handler.CustomersHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if len(sorts) != 1 || sorts[0] != "last_invoice" {
+			t.Errorf("expected the last_invoice sort, got %v", sorts)
+		}
+	})
+
+	t.Run("GET customers - Unknown sort field", func(t *testing.T) {
+		for _, url := range []string{"?sort=first_name", "?sort=total_spend&ids=1,2"} {
+			req := httptest.NewRequest(http.MethodGet, config.CustomersApiPrefix+url, nil)
+			w := httptest.NewRecorder()
+
+			handler.CustomersHandler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status code %d, got %d", url, http.StatusBadRequest, w.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+type importCustomersResponse struct {
+	Imported int               `json:"imported"`
+	Skipped  int               `json:"skipped"`
+	Errors   []importLineError `json:"errors"`
+}
+type importCustomersDryRunResponse struct {
+	WouldImport int               `json:"would_import"`
+	Skipped     int               `json:"skipped"`
+	Errors      []importLineError `json:"errors"`
+}
+type importLineError struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+// ImportHandler bulk-creates customers from a CSV file with the first_name,last_name[,email] columns.
+// Invalid rows are skipped and reported, unless ?strict=true is given, in which case nothing is imported.
+// With ?dry_run=true the file is only validated and the summary tells what the import would do
+func (h *CustomerHandler) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		writeAllowedMethods(w, http.MethodPost)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	// POST /customers/import
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != config.ContentTypeCSV {
+		writeError(w, http.StatusUnsupportedMediaType, config.ErrorCodeUnsupportedMediaType, "Content-Type must be "+config.ContentTypeCSV)
+		return
+	}
+	strict, err := parseBoolParam(r, "strict")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+		return
+	}
+	dryRun, err := parseBoolParam(r, "dry_run")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+		return
+	}
+
+	reader := csv.NewReader(http.MaxBytesReader(w, r.Body, config.MaxCustomerImportSize))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	response := importCustomersResponse{Errors: []importLineError{}}
+	var customers []database.CreateCustomerParams
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			var parseErr *csv.ParseError
+			if errors.As(err, &maxBytesErr) {
+				writeError(w, http.StatusRequestEntityTooLarge, config.ErrorCodeBodyTooLarge, "The CSV file is too large")
+			} else if errors.As(err, &parseErr) {
+				writeError(w, http.StatusBadRequest, config.ErrorCodeMalformedCSV, "Malformed CSV: "+parseErr.Error())
+			} else {
+				writeInternalServerError(w, err)
+			}
+			return
+		}
+
+		line, _ := reader.FieldPos(0)
+		if line == 1 && strings.EqualFold(record[0], "first_name") {
+			// Header row
+			continue
+		}
+
+		customer, reason := parseCustomerRecord(record)
+		if reason != "" {
+			response.Errors = append(response.Errors, importLineError{Line: line, Reason: reason})
+			response.Skipped++
+			continue
+		}
+		customers = append(customers, customer)
+	}
+
+	if strict && len(response.Errors) > 0 {
+		response.Skipped += len(customers)
+		customers = nil
+		if !dryRun {
+			writeServerResponse(w, http.StatusUnprocessableEntity, response)
+			return
+		}
+	}
+	if dryRun {
+		writeServerResponse(w, http.StatusOK, importCustomersDryRunResponse{WouldImport: len(customers), Skipped: response.Skipped, Errors: response.Errors})
+		return
+	}
+
+	err = h.Tx(r.Context(), func(q CustomerQueries) error {
+		for start := 0; start < len(customers); start += config.CustomerImportBatchSize {
+			batch := customers[start:min(start+config.CustomerImportBatchSize, len(customers))]
+			params := database.CreateCustomersParams{
+				FirstNames: make([]string, 0, len(batch)),
+				LastNames:  make([]string, 0, len(batch)),
+			}
+			for _, customer := range batch {
+				params.FirstNames = append(params.FirstNames, customer.FirstName)
+				params.LastNames = append(params.LastNames, customer.LastName)
+			}
+			imported, err := q.CreateCustomers(r.Context(), params)
+			if err != nil {
+				return err
+			}
+			response.Imported += int(imported)
+		}
+		// Nothing was imported, e.g. a file with only invalid rows, so there's nothing to audit
+		if response.Imported == 0 {
+			return nil
+		}
+		return q.CreateAuditLogEntry(r.Context(), database.NewAuditEntry(r.Context(), "customer", 0, database.AuditActionImport))
+	})
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+
+	writeServerResponse(w, http.StatusOK, response)
+}
+
+// parseCustomerRecord validates a single CSV row, returning the reason it's invalid, if any.
+// The optional email column is accepted for compatibility with CRM exports but isn't stored
+func parseCustomerRecord(record []string) (database.CreateCustomerParams, string) {
+	if len(record) < 2 || len(record) > 3 {
+		return database.CreateCustomerParams{}, "expected the first_name,last_name[,email] columns"
+	}
+
+	firstName := strings.TrimSpace(record[0])
+	lastName := strings.TrimSpace(record[1])
+	switch {
+	case firstName == "":
+		return database.CreateCustomerParams{}, "first_name is required"
+	case lastName == "":
+		return database.CreateCustomerParams{}, "last_name is required"
+	case utf8.RuneCountInString(firstName) > config.MaxCustomerNameLength:
+		return database.CreateCustomerParams{}, "first_name is too long"
+	case utf8.RuneCountInString(lastName) > config.MaxCustomerNameLength:
+		return database.CreateCustomerParams{}, "last_name is too long"
+	}
+
+	return database.CreateCustomerParams{FirstName: firstName, LastName: lastName}, ""
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+func TestImportHandler(t *testing.T) {
+	mockQueries := &customerMockQueries{}
+	handler := &CustomerHandler{Queries: mockQueries, Tx: mockQueries.tx}
+
+	csvData := "first_name,last_name,email\n" +
+		"John,Doe,john@example.com\n" +
+		",Smith\n" +
+		"Jane,Smith\n" +
+		"Alice\n"
+
+	var inserted []string
+	mockQueries.CreateCustomersFunc = func(ctx context.Context, params database.CreateCustomersParams) (int64, error) {
+		for i := range params.FirstNames {
+			inserted = append(inserted, params.FirstNames[i]+" "+params.LastNames[i])
+		}
+		return int64(len(params.FirstNames)), nil
+	}
+
+	t.Run("POST customers/import - Invalid rows are skipped", func(t *testing.T) {
+		inserted = nil
+		req := httptest.NewRequest(http.MethodPost, config.CustomersApiPrefix+"/import", strings.NewReader(csvData))
+		req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+		w := httptest.NewRecorder()
+
+		handler.ImportHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+
+		var response importCustomersResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+
+		if response.Imported != 2 || response.Skipped != 2 {
+			t.Errorf("unexpected import summary: %v", response)
+		}
+		if len(response.Errors) != 2 || response.Errors[0].Line != 3 || response.Errors[1].Line != 5 {
+			t.Errorf("unexpected import errors: %v", response.Errors)
+		}
+		if len(inserted) != 2 || inserted[0] != "John Doe" || inserted[1] != "Jane Smith" {
+			t.Errorf("unexpected inserted customers: %v", inserted)
+		}
+		if len(mockQueries.auditEntries) != 1 || mockQueries.auditEntries[0].Action != database.AuditActionImport {
+			t.Errorf("expected an import audit entry, got %v", mockQueries.auditEntries)
+		}
+	})
+
+	t.Run("POST customers/import - Nothing to import isn't audited", func(t *testing.T) {
+		inserted = nil
+		mockQueries.auditEntries = nil
+		req := httptest.NewRequest(http.MethodPost, config.CustomersApiPrefix+"/import", strings.NewReader("first_name,last_name\n,Smith\n"))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+
+		handler.ImportHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if len(inserted) != 0 || len(mockQueries.auditEntries) != 0 {
+			t.Errorf("expected neither customers nor audit entries, got %v and %v", inserted, mockQueries.auditEntries)
+		}
+	})
+
+	t.Run("POST customers/import - Strict mode imports nothing", func(t *testing.T) {
+		inserted = nil
+		req := httptest.NewRequest(http.MethodPost, config.CustomersApiPrefix+"/import?strict=true", strings.NewReader(csvData))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+
+		handler.ImportHandler(w, req)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+		if len(inserted) != 0 {
+			t.Errorf("expected no customers to be inserted, got %v", inserted)
+		}
+	})
+
+	t.Run("POST customers/import - Dry run", func(t *testing.T) {
+		for _, url := range []string{"/import?dry_run=true", "/import?dry_run=true&strict=true"} {
+			inserted = nil
+			req := httptest.NewRequest(http.MethodPost, config.CustomersApiPrefix+url, strings.NewReader(csvData))
+			req.Header.Set("Content-Type", "text/csv")
+			w := httptest.NewRecorder()
+
+			handler.ImportHandler(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("%s: expected status code %d, got %d", url, http.StatusOK, w.Code)
+			}
+			var response importCustomersDryRunResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			expectedWouldImport := 2
+			if strings.Contains(url, "strict") {
+				expectedWouldImport = 0
+			}
+			if response.WouldImport != expectedWouldImport || len(response.Errors) != 2 {
+				t.Errorf("%s: expected %d rows to be importable and 2 errors, got %+v", url, expectedWouldImport, response)
+			}
+			if len(inserted) != 0 {
+				t.Errorf("%s: expected no customers to be inserted, got %v", url, inserted)
+			}
+		}
+	})
+
+	t.Run("POST customers/import - Invalid strict", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, config.CustomersApiPrefix+"/import?strict=yes", strings.NewReader(csvData))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+
+		handler.ImportHandler(w, req)
+
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), config.ErrorCodeInvalidParameter) {
+			t.Errorf("expected status code %d with %s, got %d: %s", http.StatusBadRequest, config.ErrorCodeInvalidParameter, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("POST customers/import - Too large", func(t *testing.T) {
+		row := "John,Doe\n"
+		req := httptest.NewRequest(http.MethodPost, config.CustomersApiPrefix+"/import", strings.NewReader(strings.Repeat(row, config.MaxCustomerImportSize/len(row)+1)))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+
+		handler.ImportHandler(w, req)
+
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status code %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+		}
+	})
+
+	t.Run("POST customers/import - Wrong content type", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, config.CustomersApiPrefix+"/import", strings.NewReader(csvData))
+		req.Header.Set("Content-Type", config.ContentTypeJSON)
+		w := httptest.NewRecorder()
+
+		handler.ImportHandler(w, req)
+
+		if w.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("expected status code %d, got %d", http.StatusUnsupportedMediaType, w.Code)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+type reassignInvoicesRequest struct {
+	TargetID ID `json:"target_id"`
+}
+type reassignInvoicesResponse struct {
+	Moved int64 `json:"moved"`
+}
+
+// errSourceCustomerNotFound and errTargetCustomerNotFound abort the reassignment transaction
+var (
+	errSourceCustomerNotFound = errors.New("source customer not found")
+	errTargetCustomerNotFound = errors.New("target customer not found")
+)
+
+// ReassignInvoicesHandler moves all the invoices of a customer to another one, keeping both customers
+func (h *CustomerHandler) ReassignInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		writeAllowedMethods(w, http.MethodPost)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	// POST /customers/{id}/invoices/reassign
+	sourceID, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil || sourceID <= 0 {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidID, "Invalid customer ID")
+		return
+	}
+
+	var request reassignInvoicesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeServerParseError(w, err)
+		return
+	}
+	if request.TargetID <= 0 {
+		writeValidationError(w, config.ErrorCodeValidationOutOfRange, "target_id", "target_id should be a positive number")
+		return
+	}
+	if request.TargetID == ID(sourceID) {
+		writeValidationError(w, config.ErrorCodeValidationInvalid, "target_id", "target_id must differ from the source customer")
+		return
+	}
+
+	var moved int64
+	err = h.Tx(r.Context(), func(q CustomerQueries) error {
+		if _, err := q.GetCustomer(r.Context(), int32(sourceID)); err == sql.ErrNoRows {
+			return errSourceCustomerNotFound
+		} else if err != nil {
+			return err
+		}
+		if _, err := q.GetCustomer(r.Context(), int32(request.TargetID)); err == sql.ErrNoRows {
+			return errTargetCustomerNotFound
+		} else if err != nil {
+			return err
+		}
+
+		moved, err = q.ReassignCustomerInvoices(r.Context(), database.ReassignCustomerInvoicesParams{
+			TargetID: int32(request.TargetID),
+			SourceID: int32(sourceID),
+		})
+		if err != nil || moved == 0 {
+			return err
+		}
+		return q.CreateAuditLogEntry(r.Context(), database.NewAuditEntry(r.Context(), "customer", int32(sourceID), database.AuditActionReassignInvoices))
+	})
+	switch {
+	case errors.Is(err, errSourceCustomerNotFound):
+		writeError(w, http.StatusNotFound, config.ErrorCodeCustomerNotFound, "Customer not found")
+	case errors.Is(err, errTargetCustomerNotFound):
+		writeValidationError(w, config.ErrorCodeCustomerNotFound, "target_id", "Specified customer does not exist")
+	case err != nil:
+		writeInternalServerError(w, err)
+	default:
+		writeServerResponse(w, http.StatusOK, reassignInvoicesResponse{Moved: moved})
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+func TestReassignInvoicesHandler(t *testing.T) {
+	mockQueries := &customerMockQueries{}
+	handler := &CustomerHandler{Queries: mockQueries, Tx: mockQueries.tx}
+
+	// Customers 1 and 2 exist
+	mockQueries.GetCustomerFunc = func(ctx context.Context, id int32) (database.Customer, error) {
+		if id != 1 && id != 2 {
+			return database.Customer{}, sql.ErrNoRows
+		}
+		return database.Customer{ID: id, FirstName: "John", LastName: "Doe"}, nil
+	}
+	var reassigned *database.ReassignCustomerInvoicesParams
+	mockQueries.ReassignCustomerInvoicesFunc = func(ctx context.Context, params database.ReassignCustomerInvoicesParams) (int64, error) {
+		reassigned = &params
+		return 3, nil
+	}
+
+	reassign := func(sourceID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, config.CustomersApiPrefix+"/"+sourceID+"/invoices/reassign", bytes.NewBufferString(body))
+		req.SetPathValue("id", sourceID)
+		w := httptest.NewRecorder()
+		handler.ReassignInvoicesHandler(w, req)
+		return w
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		reassigned = nil
+		w := reassign("1", `{"target_id":2}`)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response reassignInvoicesResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if response.Moved != 3 {
+			t.Errorf("expected 3 moved invoices, got %d", response.Moved)
+		}
+		if reassigned == nil || reassigned.SourceID != 1 || reassigned.TargetID != 2 {
+			t.Errorf("unexpected reassignment: %+v", reassigned)
+		}
+	})
+
+	t.Run("Nonexistent target", func(t *testing.T) {
+		reassigned = nil
+		w := reassign("1", `{"target_id":42}`)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+		var response errorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if response.Code != config.ErrorCodeCustomerNotFound || response.Field != "target_id" {
+			t.Errorf("unexpected error response: %+v", response)
+		}
+		if reassigned != nil {
+			t.Error("expected no invoices to be moved")
+		}
+	})
+
+	t.Run("Nonexistent source", func(t *testing.T) {
+		w := reassign("42", `{"target_id":1}`)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+
+	t.Run("Same customer", func(t *testing.T) {
+		w := reassign("1", `{"target_id":1}`)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+	})
+}
@@ -7,8 +7,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/egor-markin/wallcraft-go-test-task/config"
 	"github.com/egor-markin/wallcraft-go-test-task/database"
@@ -16,17 +18,37 @@ import (
 
 // customerMockQueries implements the CustomerQueries interface for testing.
 type customerMockQueries struct {
-	ListCustomersFunc  func(ctx context.Context) ([]database.Customer, error)
-	CreateCustomerFunc func(ctx context.Context, params database.CreateCustomerParams) (database.Customer, error)
-	GetCustomerFunc    func(ctx context.Context, id int32) (database.Customer, error)
-	UpdateCustomerFunc func(ctx context.Context, params database.UpdateCustomerParams) (database.Customer, error)
-	DeleteCustomerFunc func(ctx context.Context, id int32) (string, error)
+	ListCustomersFunc               func(ctx context.Context) ([]database.Customer, error)
+	ListCustomersByIDsFunc          func(ctx context.Context, ids []int32) ([]database.Customer, error)
+	ListCustomersModifiedSinceFunc  func(ctx context.Context, modifiedSince time.Time) ([]database.Customer, error)
+	ListCustomersByActivityFunc     func(ctx context.Context, sort string) ([]database.ListCustomersByActivityRow, error)
+	CreateCustomerFunc              func(ctx context.Context, params database.CreateCustomerParams) (database.Customer, error)
+	GetCustomerFunc                 func(ctx context.Context, id int32) (database.Customer, error)
+	GetCustomerWithInvoiceCountFunc func(ctx context.Context, id int32) (database.GetCustomerWithInvoiceCountRow, error)
+	UpdateCustomerFunc              func(ctx context.Context, params database.UpdateCustomerParams) (database.Customer, error)
+	DeleteCustomerFunc              func(ctx context.Context, id int32) (string, error)
+	CreateCustomersFunc             func(ctx context.Context, params database.CreateCustomersParams) (int64, error)
+	ReassignCustomerInvoicesFunc    func(ctx context.Context, params database.ReassignCustomerInvoicesParams) (int64, error)
+
+	auditEntries []database.CreateAuditLogEntryParams
 }
 
 func (m *customerMockQueries) ListCustomers(ctx context.Context) ([]database.Customer, error) {
 	return m.ListCustomersFunc(ctx)
 }
 
+func (m *customerMockQueries) ListCustomersByIDs(ctx context.Context, ids []int32) ([]database.Customer, error) {
+	return m.ListCustomersByIDsFunc(ctx, ids)
+}
+
+func (m *customerMockQueries) ListCustomersModifiedSince(ctx context.Context, modifiedSince time.Time) ([]database.Customer, error) {
+	return m.ListCustomersModifiedSinceFunc(ctx, modifiedSince)
+}
+
+func (m *customerMockQueries) ListCustomersByActivity(ctx context.Context, sort string) ([]database.ListCustomersByActivityRow, error) {
+	return m.ListCustomersByActivityFunc(ctx, sort)
+}
+
 func (m *customerMockQueries) CreateCustomer(ctx context.Context, params database.CreateCustomerParams) (database.Customer, error) {
 	return m.CreateCustomerFunc(ctx, params)
 }
@@ -43,6 +65,28 @@ func (m *customerMockQueries) DeleteCustomer(ctx context.Context, id int32) (str
 	return m.DeleteCustomerFunc(ctx, id)
 }
 
+func (m *customerMockQueries) CreateCustomers(ctx context.Context, params database.CreateCustomersParams) (int64, error) {
+	return m.CreateCustomersFunc(ctx, params)
+}
+
+func (m *customerMockQueries) GetCustomerWithInvoiceCount(ctx context.Context, id int32) (database.GetCustomerWithInvoiceCountRow, error) {
+	return m.GetCustomerWithInvoiceCountFunc(ctx, id)
+}
+
+func (m *customerMockQueries) ReassignCustomerInvoices(ctx context.Context, params database.ReassignCustomerInvoicesParams) (int64, error) {
+	return m.ReassignCustomerInvoicesFunc(ctx, params)
+}
+
+func (m *customerMockQueries) CreateAuditLogEntry(ctx context.Context, params database.CreateAuditLogEntryParams) error {
+	m.auditEntries = append(m.auditEntries, params)
+	return nil
+}
+
+// tx runs fn against the mock itself, as there's no real transaction to begin
+func (m *customerMockQueries) tx(ctx context.Context, fn func(q CustomerQueries) error) error {
+	return fn(m)
+}
+
 func TestCustomersHandler(t *testing.T) {
 	mockQueries := &customerMockQueries{}
 	handler := &CustomerHandler{Queries: mockQueries}
@@ -78,6 +122,67 @@ func TestCustomersHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("GET customers by ids - Missing ids are omitted", func(t *testing.T) {
+		mockQueries.ListCustomersByIDsFunc = func(ctx context.Context, ids []int32) ([]database.Customer, error) {
+			if !slices.Equal(ids, []int32{1, 2, 42}) {
+				t.Errorf("unexpected ids: %v", ids)
+			}
+			return []database.Customer{
+				{ID: 1, FirstName: "John", LastName: "Doe"},
+				{ID: 2, FirstName: "Jane", LastName: "Smith"},
+			}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, config.CustomersApiPrefix+"?ids=1,2,42", nil)
+		w := httptest.NewRecorder()
+
+		handler.CustomersHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+
+		var customers []customerResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &customers); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if len(customers) != 2 || customers[0].ID != 1 || customers[1].ID != 2 {
+			t.Errorf("unexpected customers: %v", customers)
+		}
+	})
+
+	t.Run("GET customers by ids - Strict with a missing id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.CustomersApiPrefix+"?ids=1,2,42&strict=true", nil)
+		w := httptest.NewRecorder()
+
+		handler.CustomersHandler(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, w.Code)
+		}
+
+		var errResp errorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if errResp.Code != config.ErrorCodeCustomerNotFound || errResp.Error != "customer 42 not found" {
+			t.Errorf("unexpected error response: %+v", errResp)
+		}
+	})
+
+	t.Run("GET customers by ids - Invalid ids", func(t *testing.T) {
+		for _, ids := range []string{"1,abc", "1,,2", "0", "-3"} {
+			req := httptest.NewRequest(http.MethodGet, config.CustomersApiPrefix+"?ids="+ids, nil)
+			w := httptest.NewRecorder()
+
+			handler.CustomersHandler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("ids=%s: expected status code %d, got %d", ids, http.StatusBadRequest, w.Code)
+			}
+		}
+	})
+
 	t.Run("POST customers - Success", func(t *testing.T) {
 		newCustomer := createCustomerRequest{FirstName: "Alice", LastName: "Wonderland"}
 
@@ -104,6 +209,38 @@ func TestCustomersHandler(t *testing.T) {
 			t.Errorf("unexpected created customer: %v", createdCustomer)
 		}
 	})
+
+	t.Run("POST customers - Validation Error", func(t *testing.T) {
+		customerJSON, _ := json.Marshal(createCustomerRequest{FirstName: "Alice", LastName: " "})
+		req := httptest.NewRequest(http.MethodPost, config.CustomersApiPrefix, bytes.NewBuffer(customerJSON))
+		w := httptest.NewRecorder()
+
+		handler.CustomersHandler(w, req)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+
+		var response errorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+
+		if response.Field != "last_name" || response.Code != config.ErrorCodeValidationRequired {
+			t.Errorf("unexpected error response: %v", response)
+		}
+	})
+
+	t.Run("POST customers - Malformed JSON", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, config.CustomersApiPrefix, bytes.NewBufferString(`{"first_name": "Alice",`))
+		w := httptest.NewRecorder()
+
+		handler.CustomersHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
 }
 
 func TestCustomerHandler(t *testing.T) {
@@ -134,11 +271,50 @@ func TestCustomerHandler(t *testing.T) {
 			t.Fatalf("failed to unmarshal response: %v", err)
 		}
 
-		if customer.ID != c.ID || customer.FirstName != c.FirstName || customer.LastName != c.LastName {
+		if customer.ID != ID(c.ID) || customer.FirstName != c.FirstName || customer.LastName != c.LastName {
 			t.Errorf("unexpected customer: %v", customer)
 		}
 	})
 
+	t.Run("GET customers/{id} - With invoice count", func(t *testing.T) {
+		invoiceCounts := map[int32]int64{1: 3, 2: 0}
+		mockQueries.GetCustomerWithInvoiceCountFunc = func(ctx context.Context, id int32) (database.GetCustomerWithInvoiceCountRow, error) {
+			count, ok := invoiceCounts[id]
+			if !ok {
+				return database.GetCustomerWithInvoiceCountRow{}, sql.ErrNoRows
+			}
+			return database.GetCustomerWithInvoiceCountRow{ID: id, FirstName: "John", LastName: "Doe", InvoiceCount: count}, nil
+		}
+
+		for id, expected := range invoiceCounts {
+			req := httptest.NewRequest(http.MethodGet, config.CustomersApiPrefix+"/"+strconv.Itoa(int(id))+"?with_invoice_count=true", nil)
+			w := httptest.NewRecorder()
+
+			handler.CustomerHandler(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+
+			var customer map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &customer); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if customer["invoice_count"] != float64(expected) || customer["first_name"] != "John" {
+				t.Errorf("unexpected customer %d: %v", id, customer)
+			}
+		}
+
+		req := httptest.NewRequest(http.MethodGet, config.CustomersApiPrefix+"/3?with_invoice_count=true", nil)
+		w := httptest.NewRecorder()
+
+		handler.CustomerHandler(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+
 	t.Run("GET customers/{id} - Not Found", func(t *testing.T) {
 		mockQueries.GetCustomerFunc = func(ctx context.Context, id int32) (database.Customer, error) {
 			return database.Customer{}, sql.ErrNoRows
@@ -153,8 +329,12 @@ func TestCustomerHandler(t *testing.T) {
 			t.Errorf("expected status code %d, got %d", http.StatusNotFound, w.Code)
 		}
 
-		if w.Body.String() != "Customer not found\n" {
-			t.Errorf("unexpected response body: %s", w.Body.String())
+		var response errorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if response.Code != config.ErrorCodeCustomerNotFound || response.Error != "Customer not found" {
+			t.Errorf("unexpected error response: %v", response)
 		}
 	})
 
@@ -183,7 +363,7 @@ func TestCustomerHandler(t *testing.T) {
 			t.Fatalf("failed to unmarshal response: %v", err)
 		}
 
-		if updatedCustomer.ID != customerId || updatedCustomer.FirstName != updateParams.FirstName || updatedCustomer.LastName != updateParams.LastName {
+		if updatedCustomer.ID != ID(customerId) || updatedCustomer.FirstName != updateParams.FirstName || updatedCustomer.LastName != updateParams.LastName {
 			t.Errorf("unexpected updated customer: %v", updatedCustomer)
 		}
 	})
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+type ExportQueries interface {
+	ExportProducts(ctx context.Context) iter.Seq2[database.Product, error]
+	ExportCustomers(ctx context.Context) iter.Seq2[database.Customer, error]
+	ExportInvoices(ctx context.Context) iter.Seq2[database.Invoice, error]
+}
+
+type ExportHandler struct {
+	Queries ExportQueries
+	// LowStockThreshold is used for the stock status of the exported products, see ProductHandler
+	LowStockThreshold int32
+}
+
+// ExportHandler streams a whole table as newline-delimited JSON, one entity per line in the same shape as the
+// ?modified_since= lists. The rows are written as they're read, so the memory use doesn't grow with the table
+func (h *ExportHandler) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		writeAllowedMethods(w, http.MethodGet)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	// GET /admin/export/{entity}
+	switch entity := strings.TrimPrefix(r.URL.Path, config.AdminApiPrefix+"/export/"); entity {
+	case "products":
+		products := &ProductHandler{LowStockThreshold: h.LowStockThreshold}
+		writeNDJSON(w, h.Queries.ExportProducts(r.Context()), func(product database.Product) modifiedProductResponse {
+			return modifiedProductResponse{productResponse: products.newProductResponse(product), UpdatedAt: product.UpdatedAt}
+		})
+	case "customers":
+		writeNDJSON(w, h.Queries.ExportCustomers(r.Context()), func(customer database.Customer) modifiedCustomerResponse {
+			return modifiedCustomerResponse{
+				customerResponse: customerResponse{
+					ID:        ID(customer.ID),
+					FirstName: customer.FirstName,
+					LastName:  customer.LastName,
+				},
+				UpdatedAt: customer.UpdatedAt,
+			}
+		})
+	case "invoices":
+		writeNDJSON(w, h.Queries.ExportInvoices(r.Context()), func(invoice database.Invoice) modifiedInvoiceResponse {
+			return modifiedInvoiceResponse{
+				invoiceResponse: invoiceResponse{
+					ID:            ID(invoice.ID),
+					InvoiceNumber: invoice.InvoiceNumber,
+					InvoiceDate:   Timestamp(invoice.InvoiceDate),
+					CustomerID:    ID(invoice.CustomerID),
+				},
+				UpdatedAt: invoice.UpdatedAt,
+			}
+		})
+	default:
+		writeError(w, http.StatusNotFound, config.ErrorCodeNotFound, fmt.Sprintf("Unknown export entity %q, expected products, customers or invoices", entity))
+	}
+}
+
+// writeNDJSON writes a line per row. The status is only sent with the first row, so an error before it is still
+// answered with a clean 500. A later error aborts the connection instead, so that the client can't take
+// the truncated export for a complete one
+func writeNDJSON[T, R any](w http.ResponseWriter, rows iter.Seq2[T, error], toResponse func(T) R) {
+	rc := http.NewResponseController(w)
+	options := responseOptionsOf(w)
+	started := false
+	count := 0
+	for row, err := range rows {
+		var line []byte
+		if err == nil {
+			line, err = options.marshal(toResponse(row))
+		}
+		if err != nil {
+			if !started {
+				writeInternalServerError(w, err)
+				return
+			}
+			log.Println("Export aborted: ", err)
+			panic(http.ErrAbortHandler)
+		}
+
+		if !started {
+			w.Header().Set("Content-Type", config.ContentTypeNDJSON)
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			// The client is gone
+			return
+		}
+		if count++; count%config.ExportFlushRows == 0 {
+			rc.Flush()
+		}
+	}
+	if !started {
+		// An empty table is an empty body
+		w.Header().Set("Content-Type", config.ContentTypeNDJSON)
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+// exportMockQueries yields the rows it holds, followed by err when it's set
+type exportMockQueries struct {
+	products  []database.Product
+	customers []database.Customer
+	invoices  []database.Invoice
+	err       error
+}
+
+func mockExportRows[T any](rows []T, err error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for _, row := range rows {
+			if !yield(row, nil) {
+				return
+			}
+		}
+		if err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+func (m *exportMockQueries) ExportProducts(ctx context.Context) iter.Seq2[database.Product, error] {
+	return mockExportRows(m.products, m.err)
+}
+
+func (m *exportMockQueries) ExportCustomers(ctx context.Context) iter.Seq2[database.Customer, error] {
+	return mockExportRows(m.customers, m.err)
+}
+
+func (m *exportMockQueries) ExportInvoices(ctx context.Context) iter.Seq2[database.Invoice, error] {
+	return mockExportRows(m.invoices, m.err)
+}
+
+func TestExportHandler(t *testing.T) {
+	updatedAt := time.Date(2025, 6, 22, 14, 33, 12, 0, time.UTC)
+	mockQueries := &exportMockQueries{
+		products: []database.Product{
+			{ID: 1, Name: "Wallpaper", Price: "12.50", AvailableItems: 3, UpdatedAt: updatedAt},
+			{ID: 2, Name: "Glue", Description: sql.NullString{String: "Wallpaper glue", Valid: true}, Price: "4.00", AvailableItems: 40, UpdatedAt: updatedAt},
+		},
+		customers: []database.Customer{
+			{ID: 1, FirstName: "John", LastName: "Doe", UpdatedAt: updatedAt},
+		},
+	}
+	handler := &ExportHandler{Queries: mockQueries, LowStockThreshold: 5}
+
+	t.Run("GET admin/export/products", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.AdminApiPrefix+"/export/products", nil)
+		w := httptest.NewRecorder()
+
+		handler.ExportHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != config.ContentTypeNDJSON {
+			t.Errorf("expected Content-Type %s, got %s", config.ContentTypeNDJSON, contentType)
+		}
+
+		// Every line is one product on its own
+		var products []modifiedProductResponse
+		scanner := bufio.NewScanner(w.Body)
+		for scanner.Scan() {
+			var product modifiedProductResponse
+			if err := json.Unmarshal(scanner.Bytes(), &product); err != nil {
+				t.Fatalf("failed to unmarshal line %q: %v", scanner.Text(), err)
+			}
+			products = append(products, product)
+		}
+		if len(products) != 2 {
+			t.Fatalf("expected 2 products, got %d", len(products))
+		}
+		if products[0].ID != 1 || products[0].StockStatus != stockStatusLowStock || products[0].Description != nil {
+			t.Errorf("unexpected first product: %+v", products[0])
+		}
+		if products[1].Name != "Glue" || !products[1].UpdatedAt.Equal(updatedAt) {
+			t.Errorf("unexpected second product: %+v", products[1])
+		}
+	})
+
+	t.Run("GET admin/export/customers", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.AdminApiPrefix+"/export/customers", nil)
+		w := httptest.NewRecorder()
+
+		handler.ExportHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+		if len(lines) != 1 {
+			t.Fatalf("expected 1 line, got %d", len(lines))
+		}
+		var customer modifiedCustomerResponse
+		if err := json.Unmarshal([]byte(lines[0]), &customer); err != nil {
+			t.Fatalf("failed to unmarshal line %q: %v", lines[0], err)
+		}
+		if customer.ID != 1 || customer.FirstName != "John" {
+			t.Errorf("unexpected customer: %+v", customer)
+		}
+	})
+
+	t.Run("GET admin/export/invoices - Empty table", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.AdminApiPrefix+"/export/invoices", nil)
+		w := httptest.NewRecorder()
+
+		handler.ExportHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("expected an empty body, got %q", w.Body.String())
+		}
+	})
+
+	t.Run("GET admin/export/unknown", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.AdminApiPrefix+"/export/invoice_items", nil)
+		w := httptest.NewRecorder()
+
+		handler.ExportHandler(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+
+	t.Run("GET admin/export/invoices - Query error", func(t *testing.T) {
+		handler := &ExportHandler{Queries: &exportMockQueries{err: errors.New("connection refused")}}
+		req := httptest.NewRequest(http.MethodGet, config.AdminApiPrefix+"/export/invoices", nil)
+		w := httptest.NewRecorder()
+
+		handler.ExportHandler(w, req)
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("expected status code %d, got %d", http.StatusInternalServerError, w.Code)
+		}
+	})
+
+	t.Run("GET admin/export/customers - Error after the first rows", func(t *testing.T) {
+		handler := &ExportHandler{Queries: &exportMockQueries{customers: mockQueries.customers, err: errors.New("connection reset")}}
+		req := httptest.NewRequest(http.MethodGet, config.AdminApiPrefix+"/export/customers", nil)
+		w := httptest.NewRecorder()
+
+		defer func() {
+			if recovered := recover(); recovered != http.ErrAbortHandler {
+				t.Errorf("expected the handler to abort, got %v", recovered)
+			}
+		}()
+		handler.ExportHandler(w, req)
+	})
+
+	t.Run("POST admin/export/products", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, config.AdminApiPrefix+"/export/products", nil)
+		w := httptest.NewRecorder()
+
+		handler.ExportHandler(w, req)
+
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected status code %d, got %d", http.StatusMethodNotAllowed, w.Code)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// parseFields parses a comma-separated ?fields= projection, checking every name against the allowed ones.
+// It returns nil when no projection is requested
+func parseFields(raw string, allowed []string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	for field := range strings.SplitSeq(raw, ",") {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(allowed, field) {
+			return nil, fmt.Errorf("Unknown field %q, the supported fields are: %s", field, strings.Join(allowed, ","))
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// selectFields keeps only the given JSON fields of every item, encoded with the options. The items are plain
+// response structs
+func selectFields[T any](options ResponseOptions, items []T, fields []string) []map[string]any {
+	result := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		all := options.convert(reflect.ValueOf(item)).(jsonObject)
+
+		selected := make(map[string]any, len(fields))
+		for _, field := range fields {
+			selected[field] = all.get(field)
+		}
+		result = append(result, selected)
+	}
+	return result
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ID is a row id in the requests and responses. It's always accepted both as a JSON number and as a string,
+// and encoded as a number unless ResponseOptions.IDsAsStrings is set
+type ID int32
+
+func (id ID) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(id), 10), nil
+}
+
+func (id *ID) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	data = bytes.Trim(data, `"`)
+	i, err := strconv.ParseInt(string(data), 10, 32)
+	if errors.Is(err, strconv.ErrRange) {
+		return &idRangeError{value: string(data)}
+	} else if err != nil {
+		return fmt.Errorf("invalid id %s", data)
+	}
+	*id = ID(i)
+	return nil
+}
+
+// idRangeError is an id that doesn't fit the int32 id columns. The JSON decoder doesn't tell which field
+// a custom unmarshaler failed on, so the value is reported instead
+type idRangeError struct {
+	value string
+}
+
+func (e *idRangeError) Error() string {
+	return fmt.Sprintf("id %s is out of range, ids can't be greater than %d", e.value, math.MaxInt32)
+}
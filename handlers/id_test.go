@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestIDSerialization(t *testing.T) {
+	t.Parallel()
+	product := productResponse{ID: 33, Name: "Mouse", Price: "10.00"}
+
+	t.Run("Numeric by default", func(t *testing.T) {
+		data, err := json.Marshal(product)
+		if err != nil {
+			t.Fatalf("failed to marshal: %v", err)
+		}
+		if !strings.Contains(string(data), `"id":33,`) {
+			t.Errorf("unexpected JSON: %s", data)
+		}
+	})
+
+	t.Run("Strings when enabled", func(t *testing.T) {
+		data, err := ResponseOptions{IDsAsStrings: true}.marshal(invoiceResponse{ID: 33, CustomerID: 7})
+		if err != nil {
+			t.Fatalf("failed to marshal: %v", err)
+		}
+		if !strings.Contains(string(data), `"id":"33",`) || !strings.Contains(string(data), `"customer_id":"7"`) {
+			t.Errorf("unexpected JSON: %s", data)
+		}
+	})
+
+	t.Run("Requests accept both", func(t *testing.T) {
+		for _, body := range []string{`{"customer_id":7}`, `{"customer_id":"7"}`} {
+			var invoice createInvoiceRequest
+			if err := json.Unmarshal([]byte(body), &invoice); err != nil {
+				t.Fatalf("failed to unmarshal %s: %v", body, err)
+			}
+			if invoice.CustomerID != 7 {
+				t.Errorf("unexpected customer_id parsed from %s: %d", body, invoice.CustomerID)
+			}
+		}
+
+		var invoice createInvoiceRequest
+		if err := json.Unmarshal([]byte(`{"customer_id":"seven"}`), &invoice); err == nil {
+			t.Error("expected an error for a non-numeric id")
+		}
+	})
+}
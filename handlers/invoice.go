@@ -5,70 +5,124 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/egor-markin/wallcraft-go-test-task/config"
 	"github.com/egor-markin/wallcraft-go-test-task/database"
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
 	"github.com/lib/pq"
 )
 
 type InvoiceQueries interface {
 	ListInvoices(ctx context.Context) ([]database.Invoice, error)
+	ListInvoicesModifiedSince(ctx context.Context, modifiedSince time.Time) ([]database.Invoice, error)
+	ListInvoiceTotals(ctx context.Context, ids []int32) ([]database.ListInvoiceTotalsRow, error)
 	CreateInvoice(ctx context.Context, params database.CreateInvoiceParams) (database.Invoice, error)
 	GetInvoice(ctx context.Context, id int32) (database.Invoice, error)
+	GetInvoiceDocument(ctx context.Context, id int32) (database.GetInvoiceDocumentRow, error)
 	UpdateInvoice(ctx context.Context, params database.UpdateInvoiceParams) (database.UpdateInvoiceRow, error)
 	DeleteInvoice(ctx context.Context, id int32) (string, error)
-	ListProductsFromInvoice(ctx context.Context, invoiceID int32) ([]database.ListProductsFromInvoiceRow, error)
+	ListProductsFromInvoice(ctx context.Context, params database.ListProductsFromInvoiceParams) ([]database.ListProductsFromInvoiceRow, error)
+	ListProductsFromInvoiceWithoutSum(ctx context.Context, params database.ListProductsFromInvoiceWithoutSumParams) ([]database.ListProductsFromInvoiceWithoutSumRow, error)
+	ListProductsFromInvoiceWithRunningTotal(ctx context.Context, params database.ListProductsFromInvoiceWithRunningTotalParams) ([]database.ListProductsFromInvoiceWithRunningTotalRow, error)
 	AddProductToInvoice(ctx context.Context, params database.AddProductToInvoiceParams) (database.InvoiceItem, error)
+	UpdateInvoiceItemCount(ctx context.Context, params database.UpdateInvoiceItemCountParams) (database.InvoiceItem, error)
+	DiffInvoiceItems(ctx context.Context, params database.DiffInvoiceItemsParams) ([]database.DiffInvoiceItemsRow, error)
 	DeleteProductFromInvoice(ctx context.Context, params database.DeleteProductFromInvoiceParams) (string, error)
+	GetCustomer(ctx context.Context, id int32) (database.Customer, error)
+	ListProductStock(ctx context.Context, ids []int32) ([]database.ListProductStockRow, error)
+	CreateAuditLogEntry(ctx context.Context, params database.CreateAuditLogEntryParams) error
 }
 
 type InvoiceHandler struct {
 	Queries InvoiceQueries
+	Tx      TxFunc[InvoiceQueries]
+	// SnapshotTx runs the reads that have to agree with each other, like the invoice document
+	SnapshotTx TxFunc[InvoiceQueries]
+	// NumberPattern restricts the invoice numbers when set, it has to match the whole number
+	NumberPattern *regexp.Regexp
+	// UppercaseNumbers converts the invoice numbers to upper case before they are matched and stored
+	UppercaseNumbers bool
+	// DiffAcrossCustomers allows comparing the invoices of different customers
+	DiffAcrossCustomers bool
+	// AggregateTimeout bounds the queries summing up whole invoices, zero disables it
+	AggregateTimeout time.Duration
+	// RetryAfter is sent with the 503 of a timed out aggregate query when it's not zero
+	RetryAfter time.Duration
 }
 
 type createInvoiceRequest struct {
 	InvoiceNumber string     `json:"invoice_number"`
-	InvoiceDate   *time.Time `json:"invoice_date,omitempty"`
-	CustomerID    int32      `json:"customer_id"`
+	InvoiceDate   *Timestamp `json:"invoice_date,omitempty"`
+	CustomerID    ID         `json:"customer_id"`
 }
 type updateInvoiceRequest struct {
 	InvoiceNumber string    `json:"invoice_number"`
-	InvoiceDate   time.Time `json:"invoice_date"`
-	CustomerID    int32     `json:"customer_id"`
+	InvoiceDate   Timestamp `json:"invoice_date"`
+	CustomerID    ID        `json:"customer_id"`
 }
 type invoiceResponse struct {
-	ID            int32     `json:"id"`
+	ID            ID        `json:"id"`
 	InvoiceNumber string    `json:"invoice_number"`
-	InvoiceDate   time.Time `json:"invoice_date"`
-	CustomerID    int32     `json:"customer_id"`
+	InvoiceDate   Timestamp `json:"invoice_date"`
+	CustomerID    ID        `json:"customer_id"`
+	// Customer is only resolved on creation with ?verify_customer=true
+	Customer *customerResponse `json:"customer,omitempty"`
+	// Items are only included with ?expand=items
+	Items *[]invoiceProductResponse `json:"items,omitempty"`
 }
 
+// invoiceExpandFields lists the related resources ?expand= can include in an invoice
+var invoiceExpandFields = []string{"items"}
+
 type createInvoiceItemRequest struct {
-	Count int32 `json:"count"`
+	// Count may be fractional for products sold by weight, e.g. 2.5 (kg). Both JSON numbers and strings are accepted
+	Count json.Number `json:"count"`
 }
 type invoiceItemResponse struct {
-	ID        int32 `json:"id"`
-	InvoiceID int32 `json:"invoice_id"`
-	ProductID int32 `json:"product_id"`
-	Count     int32 `json:"count"`
+	ID        ID     `json:"id"`
+	InvoiceID ID     `json:"invoice_id"`
+	ProductID ID     `json:"product_id"`
+	Count     string `json:"count"`
+	// UnitPrice is the price of the product when it was added, the later price changes don't affect the invoice
+	UnitPrice string `json:"unit_price"`
 }
 type invoiceProductResponse struct {
-	ID          int32  `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Price       string `json:"price"`
-	Count       int32  `json:"count"`
-	Sum         string `json:"sum"`
+	ID           ID      `json:"id"`
+	Name         string  `json:"name"`
+	Description  *string `json:"description"`
+	Price        string  `json:"price"`
+	Count        string  `json:"count"`
+	Sum          string  `json:"sum"`
+	RunningTotal string  `json:"running_total,omitempty"`
 }
 
+// invoiceProductFields lists the fields of invoiceProductResponse that can be requested via ?fields=
+var invoiceProductFields = []string{"id", "name", "description", "price", "count", "sum", "running_total"}
+
+// invoiceProductSortFields lists the fields the invoice items can be sorted by via ?sort=, the default order is by id
+var invoiceProductSortFields = []string{"name", "count", "price", "sum"}
+
 func (h *InvoiceHandler) InvoicesHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		// GET /invoices
+		modifiedSince, err := parseTimeParam(r, "modified_since")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+			return
+		}
+		if !modifiedSince.IsZero() {
+			h.writeModifiedInvoices(w, r, modifiedSince)
+			return
+		}
+
 		invoices, err := h.Queries.ListInvoices(r.Context())
 		if err != nil {
 			writeInternalServerError(w, err)
@@ -77,15 +131,26 @@ func (h *InvoiceHandler) InvoicesHandler(w http.ResponseWriter, r *http.Request)
 		response := []invoiceResponse{}
 		for _, invoice := range invoices {
 			response = append(response, invoiceResponse{
-				ID:            invoice.ID,
+				ID:            ID(invoice.ID),
 				InvoiceNumber: invoice.InvoiceNumber,
-				InvoiceDate:   invoice.InvoiceDate,
-				CustomerID:    invoice.CustomerID,
+				InvoiceDate:   Timestamp(invoice.InvoiceDate),
+				CustomerID:    ID(invoice.CustomerID),
 			})
 		}
 		writeServerResponse(w, http.StatusOK, response)
 	case http.MethodPost:
 		// POST /invoices
+		verifyCustomer, err := parseBoolParam(r, "verify_customer")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+			return
+		}
+		expand, err := parseExpandParam(r, invoiceExpandFields)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+			return
+		}
+
 		var invoiceCreate createInvoiceRequest
 		if err := json.NewDecoder(r.Body).Decode(&invoiceCreate); err != nil {
 			writeServerParseError(w, err)
@@ -93,38 +158,64 @@ func (h *InvoiceHandler) InvoicesHandler(w http.ResponseWriter, r *http.Request)
 		}
 
 		if strings.TrimSpace(invoiceCreate.InvoiceNumber) == "" {
-			http.Error(w, "invoice_number must not be empty", http.StatusBadRequest)
+			writeValidationError(w, config.ErrorCodeValidationRequired, "invoice_number", "invoice_number must not be empty")
+			return
+		}
+		if invoiceCreate.InvoiceNumber, err = h.normalizeInvoiceNumber(invoiceCreate.InvoiceNumber); err != nil {
+			writeValidationError(w, config.ErrorCodeValidationInvalid, "invoice_number", err.Error())
 			return
 		}
 		if invoiceCreate.CustomerID <= 0 {
-			http.Error(w, "customer_id should be a positive number", http.StatusBadRequest)
+			writeValidationError(w, config.ErrorCodeValidationOutOfRange, "customer_id", "customer_id should be a positive number")
 			return
 		}
 
 		// invoiceDate is optional, if not provided, use the current time
 		var invoiceDate time.Time
-		if invoiceCreate.InvoiceDate != nil && !invoiceCreate.InvoiceDate.IsZero() {
-			invoiceDate = *invoiceCreate.InvoiceDate
+		if invoiceCreate.InvoiceDate != nil && !time.Time(*invoiceCreate.InvoiceDate).IsZero() {
+			invoiceDate = time.Time(*invoiceCreate.InvoiceDate)
 		} else {
 			invoiceDate = time.Now()
 		}
 
-		createdInvoice, err := h.Queries.CreateInvoice(r.Context(), database.CreateInvoiceParams{
+		params := database.CreateInvoiceParams{
 			InvoiceNumber: invoiceCreate.InvoiceNumber,
 			InvoiceDate:   invoiceDate,
-			CustomerID:    invoiceCreate.CustomerID,
-		})
+			CustomerID:    int32(invoiceCreate.CustomerID),
+		}
+		var createdInvoice database.Invoice
+		var customer *customerResponse
+		if verifyCustomer {
+			// The customer is looked up in the same transaction, so a missing one is reported before the insert
+			err = h.Tx(r.Context(), func(q InvoiceQueries) error {
+				c, err := q.GetCustomer(r.Context(), params.CustomerID)
+				if err != nil {
+					return err
+				}
+				customer = &customerResponse{ID: ID(c.ID), FirstName: c.FirstName, LastName: c.LastName}
+				if createdInvoice, err = q.CreateInvoice(r.Context(), params); err != nil {
+					return err
+				}
+				return q.CreateAuditLogEntry(r.Context(), database.NewAuditEntry(r.Context(), "invoice", createdInvoice.ID, database.AuditActionCreate))
+			})
+			if err == sql.ErrNoRows {
+				writeError(w, http.StatusNotFound, config.ErrorCodeCustomerNotFound, fmt.Sprintf("customer %d not found", params.CustomerID))
+				return
+			}
+		} else {
+			createdInvoice, err = h.Queries.CreateInvoice(r.Context(), params)
+		}
 		if err != nil {
 			var pqErr *pq.Error
 			if errors.As(err, &pqErr) {
 				switch pqErr.Code {
 				case "23505":
 					// Unique constraint violation
-					http.Error(w, "Invoice number must be unique", http.StatusConflict)
+					writeError(w, http.StatusConflict, config.ErrorCodeInvoiceNumberDuplicate, "Invoice number must be unique")
 					return
 				case "23503":
 					// Foreign key violation
-					http.Error(w, "Specified customer does not exist", http.StatusBadRequest)
+					writeValidationError(w, config.ErrorCodeCustomerNotFound, "customer_id", "Specified customer does not exist")
 					return
 				default:
 					writeInternalServerError(w, err)
@@ -135,14 +226,22 @@ func (h *InvoiceHandler) InvoicesHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		writeServerResponse(w, http.StatusCreated, invoiceResponse{
-			ID:            createdInvoice.ID,
+		response := invoiceResponse{
+			ID:            ID(createdInvoice.ID),
 			InvoiceNumber: createdInvoice.InvoiceNumber,
-			InvoiceDate:   createdInvoice.InvoiceDate,
-			CustomerID:    createdInvoice.CustomerID,
-		})
+			InvoiceDate:   Timestamp(createdInvoice.InvoiceDate),
+			CustomerID:    ID(createdInvoice.CustomerID),
+			Customer:      customer,
+		}
+		if slices.Contains(expand, "items") {
+			// A new invoice has no items yet
+			response.Items = &[]invoiceProductResponse{}
+		}
+		writeCreatedResponse(w, r, config.InvoicesApiPrefix+"/"+strconv.Itoa(int(createdInvoice.ID)), response)
+	case http.MethodOptions:
+		writeAllowedMethods(w, http.MethodGet, http.MethodPost)
 	default:
-		http.Error(w, config.MethodNotAllowedMsg, http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodPost)
 	}
 }
 
@@ -166,14 +265,49 @@ func (h *InvoiceHandler) InvoiceHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 	if invoiceIdx == -1 || len(segments) <= invoiceIdx+1 {
-		http.Error(w, "Invalid invoice path", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidID, "Invalid invoice path")
 		return
 	}
 
 	// Extract invoice ID
-	invoiceID, err := strconv.Atoi(segments[invoiceIdx+1])
+	invoiceID, err := strconv.ParseInt(segments[invoiceIdx+1], 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid invoice ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidID, "Invalid invoice ID")
+		return
+	}
+
+	if len(segments) > invoiceIdx+2 && segments[invoiceIdx+2] == "diff" {
+		if len(segments) != invoiceIdx+4 {
+			writeError(w, http.StatusNotFound, config.ErrorCodeNotFound, "Not found")
+			return
+		}
+		otherInvoiceID, err := strconv.ParseInt(segments[invoiceIdx+3], 10, 32)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidID, "Invalid invoice ID")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			// GET /invoices/{invoice_id}/diff/{other_invoice_id}
+			h.writeInvoiceDiff(w, r, int32(invoiceID), int32(otherInvoiceID))
+		case http.MethodOptions:
+			writeAllowedMethods(w, http.MethodGet)
+		default:
+			writeMethodNotAllowed(w, http.MethodGet)
+		}
+		return
+	}
+
+	if len(segments) == invoiceIdx+3 && segments[invoiceIdx+2] == "full" {
+		switch r.Method {
+		case http.MethodGet:
+			// GET /invoices/{invoice_id}/full
+			h.writeInvoiceDocument(w, r, int32(invoiceID))
+		case http.MethodOptions:
+			writeAllowedMethods(w, http.MethodGet)
+		default:
+			writeMethodNotAllowed(w, http.MethodGet)
+		}
 		return
 	}
 
@@ -184,35 +318,54 @@ func (h *InvoiceHandler) InvoiceHandler(w http.ResponseWriter, r *http.Request)
 			switch r.Method {
 			case http.MethodGet:
 				// GET /invoices/{invoice_id}/products
-				items, err := h.Queries.ListProductsFromInvoice(r.Context(), int32(invoiceID))
+				fields, err := parseFields(r.URL.Query().Get("fields"), invoiceProductFields)
+				if err != nil {
+					writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+					return
+				}
+
+				sort, err := parseSortParam(r, invoiceProductSortFields)
+				if err != nil {
+					writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+					return
+				}
+
+				// The sums are computed by the database, so skip them when the client doesn't need them
+				withRunningTotal, err := parseBoolParam(r, "running_total")
+				if err != nil {
+					writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+					return
+				}
+				// Requesting the running_total field computes it as well
+				withRunningTotal = withRunningTotal || slices.Contains(fields, "running_total")
+				withSum := fields == nil || slices.Contains(fields, "sum")
+				response, err := h.listInvoiceProducts(r.Context(), h.Queries, int32(invoiceID), sort, withSum, withRunningTotal)
 				if err != nil {
 					if err == sql.ErrNoRows {
-						http.Error(w, "Invoice not found", http.StatusNotFound)
+						writeError(w, http.StatusNotFound, config.ErrorCodeInvoiceNotFound, "Invoice not found")
 					} else {
 						writeInternalServerError(w, err)
 					}
 					return
 				}
-				response := []invoiceProductResponse{}
-				for _, item := range items {
-					response = append(response, invoiceProductResponse{
-						ID:          item.ID,
-						Name:        item.Name,
-						Description: item.Description.String,
-						Price:       item.Price,
-						Count:       item.Count,
-						Sum:         item.Sum,
-					})
+				if fields != nil {
+					writeServerResponse(w, http.StatusOK, selectFields(responseOptionsOf(w), response, fields))
+					return
 				}
 				writeServerResponse(w, http.StatusOK, response)
+			case http.MethodPatch:
+				// PATCH /invoices/{invoice_id}/products
+				h.updateItemCounts(w, r, int32(invoiceID))
+			case http.MethodOptions:
+				writeAllowedMethods(w, http.MethodGet, http.MethodPatch)
 			default:
-				http.Error(w, config.MethodNotAllowedMsg, http.StatusMethodNotAllowed)
+				writeMethodNotAllowed(w, http.MethodGet, http.MethodPatch)
 			}
 			return
 		} else if len(segments) == invoiceIdx+4 {
-			productID, err := strconv.Atoi(segments[invoiceIdx+3])
+			productID, err := strconv.ParseInt(segments[invoiceIdx+3], 10, 32)
 			if err != nil {
-				http.Error(w, "Invalid product ID", http.StatusBadRequest)
+				writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidID, "Invalid product ID")
 				return
 			}
 			if r.Method == http.MethodDelete {
@@ -222,11 +375,15 @@ func (h *InvoiceHandler) InvoiceHandler(w http.ResponseWriter, r *http.Request)
 					writeInternalServerError(w, err)
 					return
 				}
+				// Deleting an item that isn't there is always 404, also when a concurrent request has just deleted it
+				// ("delete_failed": the item existed when the query started but was gone by the time of the delete)
 				switch result {
-				case "invoice_item_not_found":
-					http.Error(w, "Provided invoice doesn't contain the specified product", http.StatusNotFound)
+				case "success":
+					writeDeletedResponse(w, deletedInvoiceItemResponse{Deleted: true, InvoiceID: ID(invoiceID), ProductID: ID(productID)})
+				case "invoice_item_not_found", "delete_failed":
+					writeError(w, http.StatusNotFound, config.ErrorCodeInvoiceItemNotFound, "Provided invoice doesn't contain the specified product")
 				default:
-					w.WriteHeader(http.StatusNoContent)
+					writeInternalServerError(w, fmt.Errorf("unexpected DeleteProductFromInvoice result %q", result))
 				}
 			} else if r.Method == http.MethodPost {
 				// POST /invoices/{invoice_id}/products/{product_id}
@@ -236,16 +393,21 @@ func (h *InvoiceHandler) InvoiceHandler(w http.ResponseWriter, r *http.Request)
 					return
 				}
 
-				if params.Count <= 0 {
-					http.Error(w, "count must be greater than 0", http.StatusBadRequest)
+				if msg := validateItemCount(params.Count.String()); msg != "" {
+					writeValidationError(w, config.ErrorCodeValidationInvalid, "count", msg)
 					return
 				}
 
 				item, err := h.Queries.AddProductToInvoice(r.Context(), database.AddProductToInvoiceParams{
 					InvoiceID: int32(invoiceID),
 					ProductID: int32(productID),
-					Count:     params.Count,
+					Count:     params.Count.String(),
 				})
+				if err == sql.ErrNoRows {
+					// The price is taken from the product, nothing is inserted when there's no such product
+					writeError(w, http.StatusNotFound, config.ErrorCodeProductNotFound, "The provided product does not exist")
+					return
+				}
 				if err != nil {
 					if pqErr, ok := err.(*pq.Error); ok {
 						// Check if the error is a foreign key violation
@@ -253,15 +415,15 @@ func (h *InvoiceHandler) InvoiceHandler(w http.ResponseWriter, r *http.Request)
 							constraint := pqErr.Constraint
 							switch constraint {
 							case "invoice_item_product_id_fkey":
-								http.Error(w, "The provided product does not exist", http.StatusNotFound)
+								writeError(w, http.StatusNotFound, config.ErrorCodeProductNotFound, "The provided product does not exist")
 							case "invoice_item_invoice_id_fkey":
-								http.Error(w, "The provided invoice does not exist", http.StatusNotFound)
+								writeError(w, http.StatusNotFound, config.ErrorCodeInvoiceNotFound, "The provided invoice does not exist")
 							default:
 								writeInternalServerError(w, err)
 							}
 						} else if pqErr, ok := err.(*pq.Error); ok {
 							if pqErr.Constraint == "invoice_item_count_check" {
-								http.Error(w, "count must be greater than 0", http.StatusBadRequest)
+								writeValidationError(w, config.ErrorCodeValidationOutOfRange, "count", "count must be greater than 0")
 							} else {
 								writeInternalServerError(w, err)
 							}
@@ -273,18 +435,22 @@ func (h *InvoiceHandler) InvoiceHandler(w http.ResponseWriter, r *http.Request)
 					}
 					return
 				}
-				writeServerResponse(w, http.StatusCreated, invoiceItemResponse{
-					ID:        item.ID,
-					InvoiceID: item.InvoiceID,
-					ProductID: item.ProductID,
+				// There's no separate item resource, the item is addressed by its invoice and product
+				writeCreatedResponse(w, r, r.URL.Path, invoiceItemResponse{
+					ID:        ID(item.ID),
+					InvoiceID: ID(item.InvoiceID),
+					ProductID: ID(item.ProductID),
 					Count:     item.Count,
+					UnitPrice: item.UnitPrice,
 				})
+			} else if r.Method == http.MethodOptions {
+				writeAllowedMethods(w, http.MethodPost, http.MethodDelete)
 			} else {
-				http.Error(w, config.MethodNotAllowedMsg, http.StatusMethodNotAllowed)
+				writeMethodNotAllowed(w, http.MethodPost, http.MethodDelete)
 			}
 			return
 		} else {
-			http.Error(w, "Not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, config.ErrorCodeNotFound, "Not found")
 			return
 		}
 	}
@@ -293,21 +459,35 @@ func (h *InvoiceHandler) InvoiceHandler(w http.ResponseWriter, r *http.Request)
 	switch r.Method {
 	case http.MethodGet:
 		// GET /invoices/{invoice_id}
+		expand, err := parseExpandParam(r, invoiceExpandFields)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+			return
+		}
 		invoice, err := h.Queries.GetInvoice(r.Context(), int32(invoiceID))
 		if err != nil {
 			if err == sql.ErrNoRows {
-				http.Error(w, "Invoice not found", http.StatusNotFound)
+				writeError(w, http.StatusNotFound, config.ErrorCodeInvoiceNotFound, "Invoice not found")
 			} else {
 				writeInternalServerError(w, err)
 			}
 			return
 		}
-		writeServerResponse(w, http.StatusOK, invoiceResponse{
-			ID:            invoice.ID,
+		response := invoiceResponse{
+			ID:            ID(invoice.ID),
 			InvoiceNumber: invoice.InvoiceNumber,
-			InvoiceDate:   invoice.InvoiceDate,
-			CustomerID:    invoice.CustomerID,
-		})
+			InvoiceDate:   Timestamp(invoice.InvoiceDate),
+			CustomerID:    ID(invoice.CustomerID),
+		}
+		if slices.Contains(expand, "items") {
+			items, err := h.listInvoiceProducts(r.Context(), h.Queries, invoice.ID, "", true, false)
+			if err != nil {
+				writeInternalServerError(w, err)
+				return
+			}
+			response.Items = &items
+		}
+		writeServerResponse(w, http.StatusOK, response)
 	case http.MethodPatch:
 		// PATCH /invoices/{invoice_id}
 		var invoiceUpdate updateInvoiceRequest
@@ -317,23 +497,27 @@ func (h *InvoiceHandler) InvoiceHandler(w http.ResponseWriter, r *http.Request)
 		}
 
 		if strings.TrimSpace(invoiceUpdate.InvoiceNumber) == "" {
-			http.Error(w, "invoice_number must not be empty", http.StatusBadRequest)
+			writeValidationError(w, config.ErrorCodeValidationRequired, "invoice_number", "invoice_number must not be empty")
+			return
+		}
+		if invoiceUpdate.InvoiceNumber, err = h.normalizeInvoiceNumber(invoiceUpdate.InvoiceNumber); err != nil {
+			writeValidationError(w, config.ErrorCodeValidationInvalid, "invoice_number", err.Error())
 			return
 		}
-		if invoiceUpdate.InvoiceDate.IsZero() {
-			http.Error(w, "invoice_date must be provided", http.StatusBadRequest)
+		if time.Time(invoiceUpdate.InvoiceDate).IsZero() {
+			writeValidationError(w, config.ErrorCodeValidationRequired, "invoice_date", "invoice_date must be provided")
 			return
 		}
 		if invoiceUpdate.CustomerID <= 0 {
-			http.Error(w, "customer_id should be a positive number", http.StatusBadRequest)
+			writeValidationError(w, config.ErrorCodeValidationOutOfRange, "customer_id", "customer_id should be a positive number")
 			return
 		}
 
 		updatedInvoice, err := h.Queries.UpdateInvoice(r.Context(), database.UpdateInvoiceParams{
 			ID:            int32(invoiceID),
 			InvoiceNumber: invoiceUpdate.InvoiceNumber,
-			InvoiceDate:   invoiceUpdate.InvoiceDate,
-			CustomerID:    invoiceUpdate.CustomerID,
+			InvoiceDate:   time.Time(invoiceUpdate.InvoiceDate),
+			CustomerID:    int32(invoiceUpdate.CustomerID),
 		})
 		if err != nil {
 			var pqErr *pq.Error
@@ -341,11 +525,11 @@ func (h *InvoiceHandler) InvoiceHandler(w http.ResponseWriter, r *http.Request)
 				switch pqErr.Code {
 				case "23505":
 					// Unique constraint violation
-					http.Error(w, "Invoice number must be unique", http.StatusConflict)
+					writeError(w, http.StatusConflict, config.ErrorCodeInvoiceNumberDuplicate, "Invoice number must be unique")
 					return
 				case "23503":
 					// Foreign key violation
-					http.Error(w, "Specified customer does not exist", http.StatusBadRequest)
+					writeValidationError(w, config.ErrorCodeCustomerNotFound, "customer_id", "Specified customer does not exist")
 					return
 				default:
 					writeInternalServerError(w, err)
@@ -358,7 +542,7 @@ func (h *InvoiceHandler) InvoiceHandler(w http.ResponseWriter, r *http.Request)
 		if updatedInvoice.Result != "success" {
 			switch updatedInvoice.Result {
 			case "invoice_not_found":
-				http.Error(w, "Invoice not found", http.StatusNotFound)
+				writeError(w, http.StatusNotFound, config.ErrorCodeInvoiceNotFound, "Invoice not found")
 				return
 			default:
 				writeInternalServerError(w, err)
@@ -366,10 +550,10 @@ func (h *InvoiceHandler) InvoiceHandler(w http.ResponseWriter, r *http.Request)
 			}
 		}
 		writeServerResponse(w, http.StatusOK, invoiceResponse{
-			ID:            updatedInvoice.ID.Int32,
+			ID:            ID(updatedInvoice.ID.Int32),
 			InvoiceNumber: updatedInvoice.InvoiceNumber.String,
-			InvoiceDate:   updatedInvoice.InvoiceDate.Time,
-			CustomerID:    updatedInvoice.CustomerID.Int32,
+			InvoiceDate:   Timestamp(updatedInvoice.InvoiceDate.Time),
+			CustomerID:    ID(updatedInvoice.CustomerID.Int32),
 		})
 	case http.MethodDelete:
 		// DELETE /invoices/{invoice_id}
@@ -381,7 +565,7 @@ func (h *InvoiceHandler) InvoiceHandler(w http.ResponseWriter, r *http.Request)
 				if pqErr.Code == "23503" { // 23503 is the SQLSTATE code for foreign key violation
 					// Check the constraint name
 					if pqErr.Constraint == "invoice_item_invoice_id_fkey" {
-						http.Error(w, "cannot delete invoice: invoice is referenced in the invoice_item table", http.StatusConflict)
+						writeError(w, http.StatusConflict, config.ErrorCodeInvoiceInUse, "cannot delete invoice: invoice is referenced in the invoice_item table")
 					} else {
 						writeInternalServerError(w, err)
 					}
@@ -394,11 +578,98 @@ func (h *InvoiceHandler) InvoiceHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		if deletionResult == "invoice_not_found" {
-			http.Error(w, "Invoice not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, config.ErrorCodeInvoiceNotFound, "Invoice not found")
 			return
 		}
-		w.WriteHeader(http.StatusNoContent)
+		writeDeletedResponse(w, deletedResponse{Deleted: true, ID: ID(invoiceID)})
+	case http.MethodOptions:
+		writeAllowedMethods(w, http.MethodGet, http.MethodPatch, http.MethodDelete)
 	default:
-		http.Error(w, config.MethodNotAllowedMsg, http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodPatch, http.MethodDelete)
+	}
+}
+
+func (h *InvoiceHandler) listInvoiceProducts(ctx context.Context, q InvoiceQueries, invoiceID int32, sort string, withSum, withRunningTotal bool) ([]invoiceProductResponse, error) {
+	response := []invoiceProductResponse{}
+	if withRunningTotal {
+		items, err := q.ListProductsFromInvoiceWithRunningTotal(ctx, database.ListProductsFromInvoiceWithRunningTotalParams{InvoiceID: invoiceID, Sort: sort})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			response = append(response, invoiceProductResponse{
+				ID:           ID(item.ID),
+				Name:         item.Name,
+				Description:  nullableString(item.Description),
+				Price:        item.Price,
+				Count:        item.Count,
+				Sum:          item.Sum,
+				RunningTotal: item.RunningTotal,
+			})
+		}
+		return response, nil
+	}
+
+	if !withSum {
+		items, err := q.ListProductsFromInvoiceWithoutSum(ctx, database.ListProductsFromInvoiceWithoutSumParams{InvoiceID: invoiceID, Sort: sort})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			response = append(response, invoiceProductResponse{
+				ID:          ID(item.ID),
+				Name:        item.Name,
+				Description: nullableString(item.Description),
+				Price:       item.Price,
+				Count:       item.Count,
+			})
+		}
+		return response, nil
+	}
+
+	items, err := q.ListProductsFromInvoice(ctx, database.ListProductsFromInvoiceParams{InvoiceID: invoiceID, Sort: sort})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		response = append(response, invoiceProductResponse{
+			ID:          ID(item.ID),
+			Name:        item.Name,
+			Description: nullableString(item.Description),
+			Price:       item.Price,
+			Count:       item.Count,
+			Sum:         item.Sum,
+		})
+	}
+	return response, nil
+}
+
+// validateItemCount checks an invoice item count, returning the reason it's invalid, if any
+func validateItemCount(count string) string {
+	if count == "" {
+		return "count must be greater than 0"
+	}
+	d, err := utils.ParseDecimal(count)
+	switch {
+	case err != nil:
+		return "count must be a decimal number"
+	case d.Value.Sign() <= 0:
+		return "count must be greater than 0"
+	case d.FractionDigits > config.MaxItemCountFractionDigits:
+		return fmt.Sprintf("count must have at most %d decimal places", config.MaxItemCountFractionDigits)
+	case d.IntegerDigits > config.MaxItemCountIntegerDigits:
+		return "count is too large"
+	}
+	return ""
+}
+
+// normalizeInvoiceNumber applies the configured case and checks the number against NumberPattern
+func (h *InvoiceHandler) normalizeInvoiceNumber(number string) (string, error) {
+	if h.UppercaseNumbers {
+		number = strings.ToUpper(number)
+	}
+	if h.NumberPattern != nil && !h.NumberPattern.MatchString(number) {
+		return "", fmt.Errorf("invoice_number must match the %s pattern", h.NumberPattern)
 	}
+	return number, nil
 }
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+const (
+	invoiceDiffAdded   = "added"
+	invoiceDiffRemoved = "removed"
+	invoiceDiffChanged = "changed"
+)
+
+type invoiceDiffResponse struct {
+	FromInvoiceID ID                        `json:"from_invoice_id"`
+	ToInvoiceID   ID                        `json:"to_invoice_id"`
+	Lines         []invoiceDiffLineResponse `json:"lines"`
+	// TotalDelta is how much the total of the second invoice differs from the first one
+	TotalDelta string `json:"total_delta"`
+}
+
+// invoiceDiffLineResponse describes a product whose count or unit price differs, the count is null on the invoice
+// without it
+type invoiceDiffLineResponse struct {
+	ProductID ID      `json:"product_id"`
+	Name      string  `json:"name"`
+	Price     string  `json:"price"`
+	Status    string  `json:"status"`
+	FromCount *string `json:"from_count"`
+	ToCount   *string `json:"to_count"`
+	SumDelta  string  `json:"sum_delta"`
+}
+
+// writeInvoiceDiff compares the lines of the two invoices, e.g. a revised invoice with its original
+func (h *InvoiceHandler) writeInvoiceDiff(w http.ResponseWriter, r *http.Request, fromID, toID int32) {
+	var customerID int32
+	for _, id := range []int32{fromID, toID} {
+		invoice, err := h.Queries.GetInvoice(r.Context(), id)
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, config.ErrorCodeInvoiceNotFound, "Invoice not found")
+			return
+		} else if err != nil {
+			writeInternalServerError(w, err)
+			return
+		}
+		if customerID != 0 && invoice.CustomerID != customerID && !h.DiffAcrossCustomers {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, "The invoices belong to different customers")
+			return
+		}
+		customerID = invoice.CustomerID
+	}
+
+	lines, err := h.Queries.DiffInvoiceItems(r.Context(), database.DiffInvoiceItemsParams{FromInvoiceID: fromID, ToInvoiceID: toID})
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+
+	response := invoiceDiffResponse{FromInvoiceID: ID(fromID), ToInvoiceID: ID(toID), Lines: []invoiceDiffLineResponse{}, TotalDelta: "0.00"}
+	for _, line := range lines {
+		status := invoiceDiffChanged
+		if !line.FromCount.Valid {
+			status = invoiceDiffAdded
+		} else if !line.ToCount.Valid {
+			status = invoiceDiffRemoved
+		}
+		response.Lines = append(response.Lines, invoiceDiffLineResponse{
+			ProductID: ID(line.ProductID),
+			Name:      line.Name,
+			Price:     line.Price,
+			Status:    status,
+			FromCount: nullableString(line.FromCount),
+			ToCount:   nullableString(line.ToCount),
+			SumDelta:  line.SumDelta,
+		})
+		response.TotalDelta = line.TotalDelta
+	}
+	writeServerResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+func TestInvoiceDiff(t *testing.T) {
+	mockQueries := &invoiceMockQueries{}
+	handler := &InvoiceHandler{Queries: mockQueries, Tx: mockQueries.tx}
+
+	// Invoices 1, 2 and 4 belong to customer 10, invoice 3 to customer 20. Invoice 4 is invoice 1 repriced
+	mockQueries.GetInvoiceFunc = func(ctx context.Context, id int32) (database.Invoice, error) {
+		switch id {
+		case 1, 2, 4:
+			return database.Invoice{ID: id, CustomerID: 10}, nil
+		case 3:
+			return database.Invoice{ID: id, CustomerID: 20}, nil
+		}
+		return database.Invoice{}, sql.ErrNoRows
+	}
+	mockQueries.DiffInvoiceItemsFunc = func(ctx context.Context, params database.DiffInvoiceItemsParams) ([]database.DiffInvoiceItemsRow, error) {
+		if params.FromInvoiceID == 1 && params.ToInvoiceID == 4 {
+			return []database.DiffInvoiceItemsRow{
+				{ProductID: 3, Name: "Changed", Price: "3.00", FromCount: sql.NullString{String: "2", Valid: true}, ToCount: sql.NullString{String: "2", Valid: true}, SumDelta: "2.00", TotalDelta: "2.00"},
+			}, nil
+		}
+		if params.FromInvoiceID != 1 || params.ToInvoiceID != 2 {
+			return nil, nil
+		}
+		return []database.DiffInvoiceItemsRow{
+			{ProductID: 1, Name: "Added", Price: "5.00", ToCount: sql.NullString{String: "2", Valid: true}, SumDelta: "10.00", TotalDelta: "7.00"},
+			{ProductID: 2, Name: "Removed", Price: "3.00", FromCount: sql.NullString{String: "1", Valid: true}, SumDelta: "-3.00", TotalDelta: "7.00"},
+			{ProductID: 3, Name: "Changed", Price: "2.00", FromCount: sql.NullString{String: "1", Valid: true}, ToCount: sql.NullString{String: "1", Valid: true}, SumDelta: "0.00", TotalDelta: "7.00"},
+		}, nil
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+path, nil)
+		w := httptest.NewRecorder()
+		handler.InvoiceHandler(w, req)
+		return w
+	}
+
+	t.Run("GET invoice diff - Added, removed and changed lines", func(t *testing.T) {
+		w := get("/1/diff/2")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var response invoiceDiffResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if response.FromInvoiceID != 1 || response.ToInvoiceID != 2 {
+			t.Errorf("expected invoices 1 and 2, got %d and %d", response.FromInvoiceID, response.ToInvoiceID)
+		}
+		if response.TotalDelta != "7.00" {
+			t.Errorf("expected total delta 7.00, got %s", response.TotalDelta)
+		}
+		expected := []string{invoiceDiffAdded, invoiceDiffRemoved, invoiceDiffChanged}
+		if len(response.Lines) != len(expected) {
+			t.Fatalf("expected %d lines, got %d", len(expected), len(response.Lines))
+		}
+		for i, status := range expected {
+			if response.Lines[i].Status != status {
+				t.Errorf("line %d: expected status %s, got %s", i, status, response.Lines[i].Status)
+			}
+		}
+		if response.Lines[0].FromCount != nil || response.Lines[1].ToCount != nil {
+			t.Errorf("expected the missing counts to be null")
+		}
+	})
+
+	t.Run("GET invoice diff - Only the unit price changed", func(t *testing.T) {
+		w := get("/1/diff/4")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response invoiceDiffResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if len(response.Lines) != 1 || response.Lines[0].Status != invoiceDiffChanged || *response.Lines[0].FromCount != *response.Lines[0].ToCount {
+			t.Fatalf("expected one changed line with the same count, got %+v", response.Lines)
+		}
+		if response.Lines[0].SumDelta != "2.00" || response.TotalDelta != "2.00" {
+			t.Errorf("expected sum and total deltas of 2.00, got %s and %s", response.Lines[0].SumDelta, response.TotalDelta)
+		}
+	})
+
+	t.Run("GET invoice diff - Identical invoices", func(t *testing.T) {
+		w := get("/2/diff/1")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		var response invoiceDiffResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if len(response.Lines) != 0 || response.TotalDelta != "0.00" {
+			t.Errorf("expected no lines and a zero delta, got %+v", response)
+		}
+	})
+
+	t.Run("GET invoice diff - Missing invoice", func(t *testing.T) {
+		for _, path := range []string{"/9/diff/1", "/1/diff/9"} {
+			if w := get(path); w.Code != http.StatusNotFound {
+				t.Errorf("%s: expected status code %d, got %d", path, http.StatusNotFound, w.Code)
+			}
+		}
+	})
+
+	t.Run("GET invoice diff - Different customers", func(t *testing.T) {
+		if w := get("/1/diff/3"); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+
+		handler.DiffAcrossCustomers = true
+		defer func() { handler.DiffAcrossCustomers = false }()
+		if w := get("/1/diff/3"); w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("GET invoice diff - Invalid ID", func(t *testing.T) {
+		if w := get("/1/diff/abc"); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
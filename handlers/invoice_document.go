@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+)
+
+// invoiceDocumentResponse is everything needed to render an invoice. Total covers all the items, also when there
+// are more of them than the item list returns
+type invoiceDocumentResponse struct {
+	Invoice  invoiceResponse          `json:"invoice"`
+	Customer customerResponse         `json:"customer"`
+	Items    []invoiceProductResponse `json:"items"`
+	Total    string                   `json:"total"`
+}
+
+// writeInvoiceDocument answers GET /invoices/{id}/full with the invoice, its customer, its items and its total,
+// read with two queries instead of the separate calls. Both run in one snapshot, so the total always matches the
+// items, and share the aggregate timeout
+func (h *InvoiceHandler) writeInvoiceDocument(w http.ResponseWriter, r *http.Request, invoiceID int32) {
+	response, err := withQueryTimeout(r.Context(), h.AggregateTimeout, "GetInvoiceDocument", func(ctx context.Context) (invoiceDocumentResponse, error) {
+		var response invoiceDocumentResponse
+		err := h.SnapshotTx(ctx, func(q InvoiceQueries) error {
+			document, err := q.GetInvoiceDocument(ctx, invoiceID)
+			if err != nil {
+				return err
+			}
+			items, err := h.listInvoiceProducts(ctx, q, invoiceID, "", true, false)
+			if err != nil {
+				return err
+			}
+			response = invoiceDocumentResponse{
+				Invoice: invoiceResponse{
+					ID:            ID(document.ID),
+					InvoiceNumber: document.InvoiceNumber,
+					InvoiceDate:   Timestamp(document.InvoiceDate),
+					CustomerID:    ID(document.CustomerID),
+				},
+				Customer: customerResponse{
+					ID:        ID(document.CustomerID),
+					FirstName: document.FirstName,
+					LastName:  document.LastName,
+				},
+				Items: items,
+				Total: document.Total,
+			}
+			return nil
+		})
+		return response, err
+	})
+	if err == sql.ErrNoRows {
+		writeError(w, http.StatusNotFound, config.ErrorCodeInvoiceNotFound, "Invoice not found")
+		return
+	} else if err != nil {
+		writeQueryError(w, err, h.RetryAfter)
+		return
+	}
+
+	writeServerResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+func TestInvoiceDocument(t *testing.T) {
+	mockQueries := &invoiceMockQueries{}
+	// The document has to be read in a single snapshot, the queries check they're run within one
+	inSnapshot := false
+	snapshotTx := func(ctx context.Context, fn func(q InvoiceQueries) error) error {
+		inSnapshot = true
+		defer func() { inSnapshot = false }()
+		return fn(mockQueries)
+	}
+	handler := &InvoiceHandler{Queries: mockQueries, Tx: mockQueries.tx, SnapshotTx: snapshotTx}
+
+	mockQueries.GetInvoiceDocumentFunc = func(ctx context.Context, id int32) (database.GetInvoiceDocumentRow, error) {
+		if !inSnapshot {
+			t.Error("the invoice document was read outside of the snapshot")
+		}
+		if id != 1 {
+			return database.GetInvoiceDocumentRow{}, sql.ErrNoRows
+		}
+		return database.GetInvoiceDocumentRow{
+			ID:            1,
+			InvoiceNumber: "INV-001",
+			InvoiceDate:   time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+			CustomerID:    4,
+			FirstName:     "Ada",
+			LastName:      "Byron",
+			Total:         "45.00",
+		}, nil
+	}
+	mockQueries.ListProductsFromInvoiceFunc = func(ctx context.Context, params database.ListProductsFromInvoiceParams) ([]database.ListProductsFromInvoiceRow, error) {
+		if !inSnapshot {
+			t.Error("the invoice items were read outside of the snapshot")
+		}
+		return []database.ListProductsFromInvoiceRow{
+			{ID: 5, Name: "Lamp", Price: "10.00", Count: "3", Sum: "30.00"},
+			{ID: 6, Name: "Shade", Price: "15.00", Count: "1", Sum: "15.00"},
+		}, nil
+	}
+
+	t.Run("GET invoice full - Success", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/1/full", nil)
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response map[string]json.RawMessage
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if keys := slices.Sorted(maps.Keys(response)); !slices.Equal(keys, []string{"customer", "invoice", "items", "total"}) {
+			t.Errorf("unexpected members of the document: %v", keys)
+		}
+
+		var document invoiceDocumentResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &document); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if document.Invoice.InvoiceNumber != "INV-001" || document.Invoice.CustomerID != 4 {
+			t.Errorf("unexpected invoice: %+v", document.Invoice)
+		}
+		if document.Customer.ID != 4 || document.Customer.LastName != "Byron" {
+			t.Errorf("unexpected customer: %+v", document.Customer)
+		}
+		if len(document.Items) != 2 || document.Items[1].Sum != "15.00" || document.Total != "45.00" {
+			t.Errorf("unexpected items or total: %+v, %s", document.Items, document.Total)
+		}
+	})
+
+	t.Run("GET invoice full - Timeout covers the items", func(t *testing.T) {
+		handler := &InvoiceHandler{Queries: mockQueries, SnapshotTx: snapshotTx, AggregateTimeout: time.Minute}
+		listItems := mockQueries.ListProductsFromInvoiceFunc
+		defer func() { mockQueries.ListProductsFromInvoiceFunc = listItems }()
+		mockQueries.ListProductsFromInvoiceFunc = func(ctx context.Context, params database.ListProductsFromInvoiceParams) ([]database.ListProductsFromInvoiceRow, error) {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("expected the items to be read with the aggregate timeout")
+			}
+			return listItems(ctx, params)
+		}
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/1/full", nil)
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("GET invoice full - Not found", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/2/full", nil)
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+type updateItemCountRequest struct {
+	ProductID ID          `json:"product_id"`
+	Count     json.Number `json:"count"`
+}
+
+// updateItemCountResult reports the outcome of every requested count update, in the order of the request
+type updateItemCountResult struct {
+	ProductID ID     `json:"product_id"`
+	Status    string `json:"status"`
+	Count     string `json:"count,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+const (
+	itemCountUpdateUpdated    = "updated"
+	itemCountUpdateNotFound   = "not_found"
+	itemCountUpdateInvalid    = "invalid"
+	itemCountUpdateNotApplied = "not_applied"
+)
+
+// errItemCountUpdateFailed rolls back the all-or-nothing count update transaction
+var errItemCountUpdateFailed = errors.New("some of the item count updates failed")
+
+// updateItemCounts changes the counts of several lines of an invoice in one transaction, the same way as
+// PATCH /products/prices does with prices. The lines of the products that aren't on the invoice are reported
+// as not_found, they're never added
+func (h *InvoiceHandler) updateItemCounts(w http.ResponseWriter, r *http.Request, invoiceID int32) {
+	allOrNothing, err := parseBoolParam(r, "all_or_nothing")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+		return
+	}
+
+	var updates []updateItemCountRequest
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		writeServerParseError(w, err)
+		return
+	}
+
+	results := make([]updateItemCountResult, len(updates))
+	failed := false
+	for i, update := range updates {
+		results[i] = updateItemCountResult{ProductID: update.ProductID}
+		if msg := validateItemCount(update.Count.String()); msg != "" {
+			results[i].Status = itemCountUpdateInvalid
+			results[i].Error = msg
+			failed = true
+		}
+	}
+
+	if !failed || !allOrNothing {
+		err = h.Tx(r.Context(), func(q InvoiceQueries) error {
+			if _, err := q.GetInvoice(r.Context(), invoiceID); err != nil {
+				return err
+			}
+			updated := false
+			for i, update := range updates {
+				if results[i].Status != "" {
+					continue
+				}
+				item, err := q.UpdateInvoiceItemCount(r.Context(), database.UpdateInvoiceItemCountParams{
+					Count:     update.Count.String(),
+					InvoiceID: invoiceID,
+					ProductID: int32(update.ProductID),
+				})
+				if err == sql.ErrNoRows {
+					results[i].Status = itemCountUpdateNotFound
+					results[i].Error = "Provided invoice doesn't contain the specified product"
+					failed = true
+					continue
+				} else if err != nil {
+					return err
+				}
+				results[i].Status = itemCountUpdateUpdated
+				results[i].Count = item.Count
+				updated = true
+			}
+			if failed && allOrNothing {
+				return errItemCountUpdateFailed
+			}
+			if !updated {
+				return nil
+			}
+			return q.CreateAuditLogEntry(r.Context(), database.NewAuditEntry(r.Context(), "invoice", invoiceID, database.AuditActionUpdate))
+		})
+		if err == sql.ErrNoRows {
+			writeError(w, http.StatusNotFound, config.ErrorCodeInvoiceNotFound, "Invoice not found")
+			return
+		}
+		if err != nil && err != errItemCountUpdateFailed {
+			writeInternalServerError(w, err)
+			return
+		}
+	}
+
+	if failed && allOrNothing {
+		// Nothing has been changed, the counts that would have been updated are reported as such
+		for i := range results {
+			if results[i].Status == "" || results[i].Status == itemCountUpdateUpdated {
+				results[i].Status = itemCountUpdateNotApplied
+				results[i].Count = ""
+			}
+		}
+		writeServerResponse(w, http.StatusUnprocessableEntity, results)
+		return
+	}
+
+	writeServerResponse(w, http.StatusOK, results)
+}
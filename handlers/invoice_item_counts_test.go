@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+func TestUpdateInvoiceItemCounts(t *testing.T) {
+	mockQueries := &invoiceMockQueries{}
+	handler := &InvoiceHandler{Queries: mockQueries, Tx: mockQueries.tx}
+
+	mockQueries.GetInvoiceFunc = func(ctx context.Context, id int32) (database.Invoice, error) {
+		if id != 1 {
+			return database.Invoice{}, sql.ErrNoRows
+		}
+		return database.Invoice{ID: id, InvoiceNumber: "INV-001", CustomerID: 10}, nil
+	}
+	// Products 1 and 2 are on the invoice
+	var updated []database.UpdateInvoiceItemCountParams
+	mockQueries.UpdateInvoiceItemCountFunc = func(ctx context.Context, params database.UpdateInvoiceItemCountParams) (database.InvoiceItem, error) {
+		if params.ProductID != 1 && params.ProductID != 2 {
+			return database.InvoiceItem{}, sql.ErrNoRows
+		}
+		updated = append(updated, params)
+		return database.InvoiceItem{InvoiceID: params.InvoiceID, ProductID: params.ProductID, Count: params.Count}, nil
+	}
+
+	body := `[{"product_id":1,"count":3},{"product_id":2,"count":"2.5"},{"product_id":7,"count":1},{"product_id":2,"count":0}]`
+
+	t.Run("PATCH invoice items - Partial success", func(t *testing.T) {
+		updated = nil
+		req := httptest.NewRequest(http.MethodPatch, config.InvoicesApiPrefix+"/1/products", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var results []updateItemCountResult
+		if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		expected := []updateItemCountResult{
+			{ProductID: 1, Status: itemCountUpdateUpdated, Count: "3"},
+			{ProductID: 2, Status: itemCountUpdateUpdated, Count: "2.5"},
+			{ProductID: 7, Status: itemCountUpdateNotFound},
+			{ProductID: 2, Status: itemCountUpdateInvalid},
+		}
+		if len(results) != len(expected) {
+			t.Fatalf("expected %d results, got %d", len(expected), len(results))
+		}
+		for i := range expected {
+			if results[i].ProductID != expected[i].ProductID || results[i].Status != expected[i].Status || results[i].Count != expected[i].Count {
+				t.Errorf("line %d: expected %+v, got %+v", i+1, expected[i], results[i])
+			}
+		}
+		if len(updated) != 2 || updated[0].InvoiceID != 1 {
+			t.Errorf("unexpected updates: %+v", updated)
+		}
+	})
+
+	t.Run("PATCH invoice items - All or nothing", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPatch, config.InvoicesApiPrefix+"/1/products?all_or_nothing=true", bytes.NewBufferString(`[{"product_id":1,"count":3},{"product_id":7,"count":1}]`))
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+
+		var results []updateItemCountResult
+		if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if len(results) != 2 || results[0].Status != itemCountUpdateNotApplied || results[0].Count != "" || results[1].Status != itemCountUpdateNotFound {
+			t.Errorf("unexpected results: %+v", results)
+		}
+	})
+
+	t.Run("PATCH invoice items - Invoice not found", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPatch, config.InvoicesApiPrefix+"/2/products", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
@@ -6,31 +6,56 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/egor-markin/wallcraft-go-test-task/config"
 	"github.com/egor-markin/wallcraft-go-test-task/database"
+	"github.com/lib/pq"
 )
 
 type invoiceMockQueries struct {
-	ListInvoicesFunc             func(ctx context.Context) ([]database.Invoice, error)
-	CreateInvoiceFunc            func(ctx context.Context, params database.CreateInvoiceParams) (database.Invoice, error)
-	GetInvoiceFunc               func(ctx context.Context, id int32) (database.Invoice, error)
-	UpdateInvoiceFunc            func(ctx context.Context, params database.UpdateInvoiceParams) (database.UpdateInvoiceRow, error)
-	DeleteInvoiceFunc            func(ctx context.Context, id int32) (string, error)
-	ListProductsFromInvoiceFunc  func(ctx context.Context, invoiceID int32) ([]database.ListProductsFromInvoiceRow, error)
-	AddProductToInvoiceFunc      func(ctx context.Context, params database.AddProductToInvoiceParams) (database.InvoiceItem, error)
-	DeleteProductFromInvoiceFunc func(ctx context.Context, params database.DeleteProductFromInvoiceParams) (string, error)
+	ListInvoicesFunc                            func(ctx context.Context) ([]database.Invoice, error)
+	ListInvoicesModifiedSinceFunc               func(ctx context.Context, modifiedSince time.Time) ([]database.Invoice, error)
+	ListInvoiceTotalsFunc                       func(ctx context.Context, ids []int32) ([]database.ListInvoiceTotalsRow, error)
+	CreateInvoiceFunc                           func(ctx context.Context, params database.CreateInvoiceParams) (database.Invoice, error)
+	GetInvoiceFunc                              func(ctx context.Context, id int32) (database.Invoice, error)
+	GetInvoiceDocumentFunc                      func(ctx context.Context, id int32) (database.GetInvoiceDocumentRow, error)
+	UpdateInvoiceFunc                           func(ctx context.Context, params database.UpdateInvoiceParams) (database.UpdateInvoiceRow, error)
+	DeleteInvoiceFunc                           func(ctx context.Context, id int32) (string, error)
+	ListProductsFromInvoiceFunc                 func(ctx context.Context, params database.ListProductsFromInvoiceParams) ([]database.ListProductsFromInvoiceRow, error)
+	ListProductsFromInvoiceWithoutSumFunc       func(ctx context.Context, params database.ListProductsFromInvoiceWithoutSumParams) ([]database.ListProductsFromInvoiceWithoutSumRow, error)
+	ListProductsFromInvoiceWithRunningTotalFunc func(ctx context.Context, params database.ListProductsFromInvoiceWithRunningTotalParams) ([]database.ListProductsFromInvoiceWithRunningTotalRow, error)
+	AddProductToInvoiceFunc                     func(ctx context.Context, params database.AddProductToInvoiceParams) (database.InvoiceItem, error)
+	UpdateInvoiceItemCountFunc                  func(ctx context.Context, params database.UpdateInvoiceItemCountParams) (database.InvoiceItem, error)
+	DeleteProductFromInvoiceFunc                func(ctx context.Context, params database.DeleteProductFromInvoiceParams) (string, error)
+	DiffInvoiceItemsFunc                        func(ctx context.Context, params database.DiffInvoiceItemsParams) ([]database.DiffInvoiceItemsRow, error)
+	GetCustomerFunc                             func(ctx context.Context, id int32) (database.Customer, error)
+	ListProductStockFunc                        func(ctx context.Context, ids []int32) ([]database.ListProductStockRow, error)
 }
 
 func (m *invoiceMockQueries) ListInvoices(ctx context.Context) ([]database.Invoice, error) {
 	return m.ListInvoicesFunc(ctx)
 }
 
+func (m *invoiceMockQueries) ListInvoicesModifiedSince(ctx context.Context, modifiedSince time.Time) ([]database.Invoice, error) {
+	return m.ListInvoicesModifiedSinceFunc(ctx, modifiedSince)
+}
+
+func (m *invoiceMockQueries) GetInvoiceDocument(ctx context.Context, id int32) (database.GetInvoiceDocumentRow, error) {
+	return m.GetInvoiceDocumentFunc(ctx, id)
+}
+
+func (m *invoiceMockQueries) ListInvoiceTotals(ctx context.Context, ids []int32) ([]database.ListInvoiceTotalsRow, error) {
+	return m.ListInvoiceTotalsFunc(ctx, ids)
+}
+
 func (m *invoiceMockQueries) CreateInvoice(ctx context.Context, params database.CreateInvoiceParams) (database.Invoice, error) {
 	return m.CreateInvoiceFunc(ctx, params)
 }
@@ -47,21 +72,54 @@ func (m *invoiceMockQueries) DeleteInvoice(ctx context.Context, id int32) (strin
 	return m.DeleteInvoiceFunc(ctx, id)
 }
 
-func (m *invoiceMockQueries) ListProductsFromInvoice(ctx context.Context, invoiceID int32) ([]database.ListProductsFromInvoiceRow, error) {
-	return m.ListProductsFromInvoiceFunc(ctx, invoiceID)
+func (m *invoiceMockQueries) ListProductsFromInvoice(ctx context.Context, params database.ListProductsFromInvoiceParams) ([]database.ListProductsFromInvoiceRow, error) {
+	return m.ListProductsFromInvoiceFunc(ctx, params)
+}
+
+func (m *invoiceMockQueries) ListProductsFromInvoiceWithoutSum(ctx context.Context, params database.ListProductsFromInvoiceWithoutSumParams) ([]database.ListProductsFromInvoiceWithoutSumRow, error) {
+	return m.ListProductsFromInvoiceWithoutSumFunc(ctx, params)
+}
+
+func (m *invoiceMockQueries) ListProductsFromInvoiceWithRunningTotal(ctx context.Context, params database.ListProductsFromInvoiceWithRunningTotalParams) ([]database.ListProductsFromInvoiceWithRunningTotalRow, error) {
+	return m.ListProductsFromInvoiceWithRunningTotalFunc(ctx, params)
 }
 
 func (m *invoiceMockQueries) AddProductToInvoice(ctx context.Context, params database.AddProductToInvoiceParams) (database.InvoiceItem, error) {
 	return m.AddProductToInvoiceFunc(ctx, params)
 }
 
+func (m *invoiceMockQueries) UpdateInvoiceItemCount(ctx context.Context, params database.UpdateInvoiceItemCountParams) (database.InvoiceItem, error) {
+	return m.UpdateInvoiceItemCountFunc(ctx, params)
+}
+
 func (m *invoiceMockQueries) DeleteProductFromInvoice(ctx context.Context, params database.DeleteProductFromInvoiceParams) (string, error) {
 	return m.DeleteProductFromInvoiceFunc(ctx, params)
 }
 
+func (m *invoiceMockQueries) DiffInvoiceItems(ctx context.Context, params database.DiffInvoiceItemsParams) ([]database.DiffInvoiceItemsRow, error) {
+	return m.DiffInvoiceItemsFunc(ctx, params)
+}
+
+func (m *invoiceMockQueries) GetCustomer(ctx context.Context, id int32) (database.Customer, error) {
+	return m.GetCustomerFunc(ctx, id)
+}
+
+func (m *invoiceMockQueries) ListProductStock(ctx context.Context, ids []int32) ([]database.ListProductStockRow, error) {
+	return m.ListProductStockFunc(ctx, ids)
+}
+
+func (m *invoiceMockQueries) CreateAuditLogEntry(ctx context.Context, params database.CreateAuditLogEntryParams) error {
+	return nil
+}
+
+// tx runs fn against the mock itself, as there's no real transaction to begin
+func (m *invoiceMockQueries) tx(ctx context.Context, fn func(q InvoiceQueries) error) error {
+	return fn(m)
+}
+
 func TestInvoicesHandler(t *testing.T) {
 	mockQueries := &invoiceMockQueries{}
-	handler := &InvoiceHandler{Queries: mockQueries}
+	handler := &InvoiceHandler{Queries: mockQueries, Tx: mockQueries.tx}
 
 	t.Run("GET invoices - Success", func(t *testing.T) {
 		mockQueries.ListInvoicesFunc = func(ctx context.Context) ([]database.Invoice, error) {
@@ -106,7 +164,7 @@ func TestInvoicesHandler(t *testing.T) {
 				ID:            3,
 				InvoiceNumber: newInvoice.InvoiceNumber,
 				InvoiceDate:   time.Now().UTC(),
-				CustomerID:    newInvoice.CustomerID,
+				CustomerID:    int32(newInvoice.CustomerID),
 			}, nil
 		}
 
@@ -129,6 +187,117 @@ func TestInvoicesHandler(t *testing.T) {
 			t.Errorf("unexpected created invoice: %v", createdInvoice)
 		}
 	})
+
+	t.Run("POST invoices - Validation Error", func(t *testing.T) {
+		invoiceJSON, _ := json.Marshal(createInvoiceRequest{InvoiceNumber: "INV-004", CustomerID: -1})
+		req := httptest.NewRequest(http.MethodPost, config.InvoicesApiPrefix, bytes.NewBuffer(invoiceJSON))
+		w := httptest.NewRecorder()
+
+		handler.InvoicesHandler(w, req)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+
+		var response errorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+
+		if response.Field != "customer_id" || response.Code != config.ErrorCodeValidationOutOfRange {
+			t.Errorf("unexpected error response: %v", response)
+		}
+	})
+
+	t.Run("POST invoices - Duplicate invoice number", func(t *testing.T) {
+		mockQueries.CreateInvoiceFunc = func(ctx context.Context, params database.CreateInvoiceParams) (database.Invoice, error) {
+			return database.Invoice{}, &pq.Error{Code: "23505"}
+		}
+
+		invoiceJSON, _ := json.Marshal(createInvoiceRequest{InvoiceNumber: "INV-001", CustomerID: 1})
+		req := httptest.NewRequest(http.MethodPost, config.InvoicesApiPrefix, bytes.NewBuffer(invoiceJSON))
+		w := httptest.NewRecorder()
+
+		handler.InvoicesHandler(w, req)
+
+		if w.Code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, w.Code)
+		}
+
+		var response errorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if response.Code != config.ErrorCodeInvoiceNumberDuplicate {
+			t.Errorf("unexpected error response: %v", response)
+		}
+	})
+
+	t.Run("POST invoices - Verify an existing customer", func(t *testing.T) {
+		mockQueries.GetCustomerFunc = func(ctx context.Context, id int32) (database.Customer, error) {
+			return database.Customer{ID: id, FirstName: "Alice", LastName: "Smith"}, nil
+		}
+		mockQueries.CreateInvoiceFunc = func(ctx context.Context, params database.CreateInvoiceParams) (database.Invoice, error) {
+			return database.Invoice{ID: 5, InvoiceNumber: params.InvoiceNumber, InvoiceDate: params.InvoiceDate, CustomerID: params.CustomerID}, nil
+		}
+
+		invoiceJSON, _ := json.Marshal(createInvoiceRequest{InvoiceNumber: "INV-005", CustomerID: 7})
+		req := httptest.NewRequest(http.MethodPost, config.InvoicesApiPrefix+"?verify_customer=true", bytes.NewBuffer(invoiceJSON))
+		w := httptest.NewRecorder()
+
+		handler.InvoicesHandler(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("expected status code %d, got %d", http.StatusCreated, w.Code)
+		}
+
+		var createdInvoice invoiceResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &createdInvoice); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if createdInvoice.Customer == nil || createdInvoice.Customer.ID != 7 || createdInvoice.Customer.FirstName != "Alice" {
+			t.Errorf("unexpected created invoice: %+v", createdInvoice)
+		}
+	})
+
+	t.Run("POST invoices - Verify a missing customer", func(t *testing.T) {
+		mockQueries.GetCustomerFunc = func(ctx context.Context, id int32) (database.Customer, error) {
+			return database.Customer{}, sql.ErrNoRows
+		}
+		mockQueries.CreateInvoiceFunc = func(ctx context.Context, params database.CreateInvoiceParams) (database.Invoice, error) {
+			t.Error("the invoice must not be inserted")
+			return database.Invoice{}, errors.New("unexpected insert")
+		}
+
+		invoiceJSON, _ := json.Marshal(createInvoiceRequest{InvoiceNumber: "INV-006", CustomerID: 8})
+		req := httptest.NewRequest(http.MethodPost, config.InvoicesApiPrefix+"?verify_customer=true", bytes.NewBuffer(invoiceJSON))
+		w := httptest.NewRecorder()
+
+		handler.InvoicesHandler(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, w.Code)
+		}
+
+		var response errorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if response.Code != config.ErrorCodeCustomerNotFound || response.Error != "customer 8 not found" {
+			t.Errorf("unexpected error response: %v", response)
+		}
+	})
+
+	t.Run("POST invoices - Malformed JSON", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, config.InvoicesApiPrefix, bytes.NewBufferString(`invoice_number=INV-004`))
+		w := httptest.NewRecorder()
+
+		handler.InvoicesHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
 }
 
 func TestInvoiceHandler(t *testing.T) {
@@ -164,7 +333,7 @@ func TestInvoiceHandler(t *testing.T) {
 			t.Fatalf("failed to unmarshal response: %v", err)
 		}
 
-		if invoice.ID != inv.ID || invoice.InvoiceNumber != inv.InvoiceNumber || invoice.CustomerID != inv.CustomerID {
+		if invoice.ID != ID(inv.ID) || invoice.InvoiceNumber != inv.InvoiceNumber || invoice.CustomerID != ID(inv.CustomerID) {
 			t.Errorf("unexpected invoice: %v", invoice)
 		}
 	})
@@ -183,8 +352,12 @@ func TestInvoiceHandler(t *testing.T) {
 			t.Errorf("expected status code %d, got %d", http.StatusNotFound, w.Code)
 		}
 
-		if w.Body.String() != "Invoice not found\n" {
-			t.Errorf("unexpected response body: %s", w.Body.String())
+		var response errorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if response.Code != config.ErrorCodeInvoiceNotFound || response.Error != "Invoice not found" {
+			t.Errorf("unexpected error response: %v", response)
 		}
 	})
 
@@ -192,7 +365,7 @@ func TestInvoiceHandler(t *testing.T) {
 		invoiceID := int32(24)
 		updateParams := updateInvoiceRequest{
 			InvoiceNumber: "INV-UPDATED",
-			InvoiceDate:   time.Date(2025, time.March, 6, 15, 4, 5, 0, time.UTC),
+			InvoiceDate:   Timestamp(time.Date(2025, time.March, 6, 15, 4, 5, 0, time.UTC)),
 			CustomerID:    50,
 		}
 		mockQueries.UpdateInvoiceFunc = func(ctx context.Context, params database.UpdateInvoiceParams) (database.UpdateInvoiceRow, error) {
@@ -203,8 +376,8 @@ func TestInvoiceHandler(t *testing.T) {
 				Result:        "success",
 				ID:            sql.NullInt32{Int32: invoiceID, Valid: true},
 				InvoiceNumber: sql.NullString{String: updateParams.InvoiceNumber, Valid: true},
-				InvoiceDate:   sql.NullTime{Time: updateParams.InvoiceDate, Valid: true},
-				CustomerID:    sql.NullInt32{Int32: updateParams.CustomerID, Valid: true},
+				InvoiceDate:   sql.NullTime{Time: time.Time(updateParams.InvoiceDate), Valid: true},
+				CustomerID:    sql.NullInt32{Int32: int32(updateParams.CustomerID), Valid: true},
 			}, nil
 		}
 
@@ -223,11 +396,35 @@ func TestInvoiceHandler(t *testing.T) {
 			t.Fatalf("failed to unmarshal response: %v", err)
 		}
 
-		if updatedInvoice.ID != invoiceID || updatedInvoice.InvoiceNumber != updateParams.InvoiceNumber || updatedInvoice.InvoiceDate != updateParams.InvoiceDate || updatedInvoice.CustomerID != updateParams.CustomerID {
+		if updatedInvoice.ID != ID(invoiceID) || updatedInvoice.InvoiceNumber != updateParams.InvoiceNumber || updatedInvoice.InvoiceDate != updateParams.InvoiceDate || updatedInvoice.CustomerID != updateParams.CustomerID {
 			t.Errorf("unexpected updated invoice: %v", updatedInvoice)
 		}
 	})
 
+	t.Run("PATCH invoices/{id} - Nonexistent customer", func(t *testing.T) {
+		mockQueries.UpdateInvoiceFunc = func(ctx context.Context, params database.UpdateInvoiceParams) (database.UpdateInvoiceRow, error) {
+			return database.UpdateInvoiceRow{}, &pq.Error{Code: "23503", Constraint: "invoice_customer_id_fkey"}
+		}
+
+		updateJSON, _ := json.Marshal(updateInvoiceRequest{InvoiceNumber: "INV-UPDATED", InvoiceDate: Timestamp(time.Now()), CustomerID: 999})
+		req := httptest.NewRequest(http.MethodPatch, config.InvoicesApiPrefix+"/24", bytes.NewBuffer(updateJSON))
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+
+		var response errorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if response.Field != "customer_id" || response.Code != config.ErrorCodeCustomerNotFound {
+			t.Errorf("unexpected error response: %v", response)
+		}
+	})
+
 	t.Run("DELETE invoices/{id} - Success", func(t *testing.T) {
 		var invoiceID int32 = 444
 		mockQueries.DeleteInvoiceFunc = func(ctx context.Context, id int32) (string, error) {
@@ -256,11 +453,11 @@ func TestInvoiceItemHandler(t *testing.T) {
 	t.Run("GET invoice items - Success", func(t *testing.T) {
 		mockInvoiceID := int32(45)
 		list := []database.ListProductsFromInvoiceRow{
-			{ID: 1, Name: "Product 1", Price: "100.0", Count: 2},
-			{ID: 2, Name: "Product 2", Price: "300.0", Count: 4},
+			{ID: 1, Name: "Product 1", Price: "100.0", Count: "2"},
+			{ID: 2, Name: "Product 2", Price: "300.0", Count: "4"},
 		}
-		mockQueries.ListProductsFromInvoiceFunc = func(ctx context.Context, invoiceID int32) ([]database.ListProductsFromInvoiceRow, error) {
-			if invoiceID != mockInvoiceID {
+		mockQueries.ListProductsFromInvoiceFunc = func(ctx context.Context, params database.ListProductsFromInvoiceParams) ([]database.ListProductsFromInvoiceRow, error) {
+			if params.InvoiceID != mockInvoiceID {
 				return nil, sql.ErrNoRows
 			}
 			return list, nil
@@ -289,12 +486,156 @@ func TestInvoiceItemHandler(t *testing.T) {
 
 	})
 
+	t.Run("GET invoice items - Sparse fieldset", func(t *testing.T) {
+		mockInvoiceID := int32(46)
+		mockQueries.ListProductsFromInvoiceFunc = func(ctx context.Context, params database.ListProductsFromInvoiceParams) ([]database.ListProductsFromInvoiceRow, error) {
+			return nil, errors.New("the sum must not be computed")
+		}
+		mockQueries.ListProductsFromInvoiceWithoutSumFunc = func(ctx context.Context, params database.ListProductsFromInvoiceWithoutSumParams) ([]database.ListProductsFromInvoiceWithoutSumRow, error) {
+			if params.InvoiceID != mockInvoiceID {
+				return nil, sql.ErrNoRows
+			}
+			return []database.ListProductsFromInvoiceWithoutSumRow{
+				{ID: 1, Name: "Product 1", Description: sql.NullString{String: "Description 1", Valid: true}, Price: "100.0", Count: "2"},
+			}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/"+strconv.Itoa(int(mockInvoiceID))+"/products?fields=id,name,count", nil)
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+
+		expected := `[{"count":"2","id":1,"name":"Product 1"}]` + "\n"
+		if w.Body.String() != expected {
+			t.Errorf("unexpected response body: %s", w.Body.String())
+		}
+	})
+
+	t.Run("GET invoice items - Running total", func(t *testing.T) {
+		mockInvoiceID := int32(47)
+		mockQueries.ListProductsFromInvoiceWithRunningTotalFunc = func(ctx context.Context, params database.ListProductsFromInvoiceWithRunningTotalParams) ([]database.ListProductsFromInvoiceWithRunningTotalRow, error) {
+			if params.InvoiceID != mockInvoiceID {
+				return nil, sql.ErrNoRows
+			}
+			return []database.ListProductsFromInvoiceWithRunningTotalRow{
+				{ID: 1, Name: "Product 1", Price: "0.10", Count: "3", Sum: "0.30", RunningTotal: "0.30"},
+				{ID: 2, Name: "Product 2", Price: "0.20", Count: "1", Sum: "0.20", RunningTotal: "0.50"},
+			}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/"+strconv.Itoa(int(mockInvoiceID))+"/products?running_total=true", nil)
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+
+		var fetchedProducts []invoiceProductResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &fetchedProducts); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+
+		if len(fetchedProducts) != 2 || fetchedProducts[0].RunningTotal != "0.30" || fetchedProducts[1].RunningTotal != "0.50" {
+			t.Errorf("unexpected running totals: %v", fetchedProducts)
+		}
+	})
+
+	t.Run("GET invoice items - Requested running_total field", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/47/products?fields=id,running_total", nil)
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var fetchedProducts []map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &fetchedProducts); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if len(fetchedProducts) != 2 || fetchedProducts[1]["running_total"] != "0.50" {
+			t.Errorf("expected the running totals to be computed, got %v", fetchedProducts)
+		}
+	})
+
+	t.Run("GET invoice items - Invalid running_total", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/46/products?running_total=yes", nil)
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("GET invoice items - Unknown field", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/46/products?fields=id,secret", nil)
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("GET invoice items - Sorted by sum", func(t *testing.T) {
+		var sort string
+		mockQueries.ListProductsFromInvoiceFunc = func(ctx context.Context, params database.ListProductsFromInvoiceParams) ([]database.ListProductsFromInvoiceRow, error) {
+			sort = params.Sort
+			// The database returns the lines already sorted
+			return []database.ListProductsFromInvoiceRow{
+				{ID: 3, Name: "Product 3", Price: "9.50", Count: "20", Sum: "190.00"},
+				{ID: 1, Name: "Product 1", Price: "100.00", Count: "1", Sum: "100.00"},
+				{ID: 2, Name: "Product 2", Price: "2.00", Count: "1.5", Sum: "3.00"},
+			}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/47/products?sort=-sum", nil)
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if sort != "-sum" {
+			t.Errorf("expected the -sum order to be requested, got %q", sort)
+		}
+
+		var fetchedProducts []invoiceProductResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &fetchedProducts); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if len(fetchedProducts) != 3 || fetchedProducts[0].ID != 3 || fetchedProducts[1].ID != 1 || fetchedProducts[2].ID != 2 {
+			t.Errorf("unexpected order: %v", fetchedProducts)
+		}
+	})
+
+	t.Run("GET invoice items - Unsupported sort", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/47/products?sort=-description", nil)
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
 	// POST /invoices/{invoice_id}/products
 	t.Run("POST invoice items - Success", func(t *testing.T) {
 		mockInvoiceID := int32(98)
 		mockProductID := int32(99)
-		mockCount := int32(24)
-		params := createInvoiceItemRequest{Count: mockCount}
+		mockCount := "2.5"
+		params := createInvoiceItemRequest{Count: json.Number(mockCount)}
 		mockQueries.AddProductToInvoiceFunc = func(ctx context.Context, p database.AddProductToInvoiceParams) (database.InvoiceItem, error) {
 			if p.InvoiceID != mockInvoiceID {
 				return database.InvoiceItem{}, errors.New("unexpected invoice ID")
@@ -323,12 +664,40 @@ func TestInvoiceItemHandler(t *testing.T) {
 			t.Fatalf("failed to unmarshal response: %v", err)
 		}
 
-		if createdInvoiceItem.ID <= 0 || createdInvoiceItem.InvoiceID != mockInvoiceID || createdInvoiceItem.ProductID != mockProductID || createdInvoiceItem.Count != params.Count {
+		if createdInvoiceItem.ID <= 0 || createdInvoiceItem.InvoiceID != ID(mockInvoiceID) || createdInvoiceItem.ProductID != ID(mockProductID) || createdInvoiceItem.Count != mockCount {
 			t.Errorf("unexpected created product: %v", createdInvoiceItem)
 		}
 
 	})
 
+	t.Run("POST invoice items - Count as a string", func(t *testing.T) {
+		mockQueries.AddProductToInvoiceFunc = func(ctx context.Context, p database.AddProductToInvoiceParams) (database.InvoiceItem, error) {
+			return database.InvoiceItem{ID: 1, InvoiceID: p.InvoiceID, ProductID: p.ProductID, Count: p.Count}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodPost, config.InvoicesApiPrefix+"/1/products/2", bytes.NewBufferString(`{"count": "0.125"}`))
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("expected status code %d, got %d", http.StatusCreated, w.Code)
+		}
+	})
+
+	t.Run("POST invoice items - Invalid count", func(t *testing.T) {
+		for _, count := range []string{`0`, `-1.5`, `1.2345`, `"abc"`, `12345678`} {
+			req := httptest.NewRequest(http.MethodPost, config.InvoicesApiPrefix+"/1/products/2", bytes.NewBufferString(`{"count": `+count+`}`))
+			w := httptest.NewRecorder()
+
+			handler.InvoiceHandler(w, req)
+
+			if w.Code != http.StatusUnprocessableEntity && w.Code != http.StatusBadRequest {
+				t.Errorf("count %s: expected status code %d or %d, got %d", count, http.StatusUnprocessableEntity, http.StatusBadRequest, w.Code)
+			}
+		}
+	})
+
 	// DELETE /invoices/{invoice_id}/products/{product_id}
 	t.Run("DELETE invoice items - Success", func(t *testing.T) {
 		var mockInvoiceID int32 = 678
@@ -352,4 +721,231 @@ func TestInvoiceItemHandler(t *testing.T) {
 			t.Errorf("expected status code %d, got %d", http.StatusNoContent, w.Code)
 		}
 	})
+
+	t.Run("DELETE invoice items - Delete again", func(t *testing.T) {
+		present := true
+		mockQueries.DeleteProductFromInvoiceFunc = func(ctx context.Context, params database.DeleteProductFromInvoiceParams) (string, error) {
+			if !present {
+				return "invoice_item_not_found", nil
+			}
+			present = false
+			return "success", nil
+		}
+
+		for _, expected := range []int{http.StatusNoContent, http.StatusNotFound, http.StatusNotFound} {
+			req := httptest.NewRequest(http.MethodDelete, config.InvoicesApiPrefix+"/1/products/2", nil)
+			w := httptest.NewRecorder()
+
+			handler.InvoiceHandler(w, req)
+
+			if w.Code != expected {
+				t.Errorf("expected status code %d, got %d", expected, w.Code)
+			}
+		}
+	})
+
+	t.Run("DELETE invoice items - Concurrently deleted", func(t *testing.T) {
+		mockQueries.DeleteProductFromInvoiceFunc = func(ctx context.Context, params database.DeleteProductFromInvoiceParams) (string, error) {
+			return "delete_failed", nil
+		}
+
+		req := httptest.NewRequest(http.MethodDelete, config.InvoicesApiPrefix+"/1/products/2", nil)
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
+
+func TestInvoiceNumberPattern(t *testing.T) {
+	mockQueries := &invoiceMockQueries{}
+	var stored string
+	mockQueries.CreateInvoiceFunc = func(ctx context.Context, params database.CreateInvoiceParams) (database.Invoice, error) {
+		stored = params.InvoiceNumber
+		return database.Invoice{ID: 1, InvoiceNumber: params.InvoiceNumber, InvoiceDate: params.InvoiceDate, CustomerID: params.CustomerID}, nil
+	}
+	pattern := regexp.MustCompile(`^(?:INV-[0-9]{4})$`)
+
+	tests := []struct {
+		name           string
+		handler        *InvoiceHandler
+		invoiceNumber  string
+		expectedStatus int
+		expectedStored string
+	}{
+		{name: "Matching number", handler: &InvoiceHandler{Queries: mockQueries, Tx: mockQueries.tx, NumberPattern: pattern, UppercaseNumbers: true}, invoiceNumber: "inv-0042", expectedStatus: http.StatusCreated, expectedStored: "INV-0042"},
+		{name: "Non-matching number", handler: &InvoiceHandler{Queries: mockQueries, Tx: mockQueries.tx, NumberPattern: pattern}, invoiceNumber: "INV-42", expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Case-sensitive without normalization", handler: &InvoiceHandler{Queries: mockQueries, Tx: mockQueries.tx, NumberPattern: pattern}, invoiceNumber: "inv-0042", expectedStatus: http.StatusUnprocessableEntity},
+		{name: "No pattern", handler: &InvoiceHandler{Queries: mockQueries, Tx: mockQueries.tx}, invoiceNumber: "2024/17", expectedStatus: http.StatusCreated, expectedStored: "2024/17"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored = ""
+			invoiceJSON, _ := json.Marshal(createInvoiceRequest{InvoiceNumber: tt.invoiceNumber, CustomerID: 1})
+			req := httptest.NewRequest(http.MethodPost, config.InvoicesApiPrefix, bytes.NewBuffer(invoiceJSON))
+			w := httptest.NewRecorder()
+
+			tt.handler.InvoicesHandler(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status code %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if stored != tt.expectedStored {
+				t.Errorf("expected the invoice number %q to be stored, got %q", tt.expectedStored, stored)
+			}
+			if tt.expectedStatus == http.StatusUnprocessableEntity {
+				var response errorResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatalf("failed to unmarshal response: %v", err)
+				}
+				if response.Field != "invoice_number" || response.Code != config.ErrorCodeValidationInvalid {
+					t.Errorf("unexpected error response: %v", response)
+				}
+			}
+		})
+	}
+}
+
+func TestInvoiceExpandItems(t *testing.T) {
+	mockQueries := &invoiceMockQueries{}
+	handler := &InvoiceHandler{Queries: mockQueries, Tx: mockQueries.tx}
+
+	mockQueries.CreateInvoiceFunc = func(ctx context.Context, params database.CreateInvoiceParams) (database.Invoice, error) {
+		return database.Invoice{ID: 3, InvoiceNumber: params.InvoiceNumber, InvoiceDate: params.InvoiceDate, CustomerID: params.CustomerID}, nil
+	}
+	mockQueries.GetInvoiceFunc = func(ctx context.Context, id int32) (database.Invoice, error) {
+		return database.Invoice{ID: id, InvoiceNumber: "INV-001", CustomerID: 1}, nil
+	}
+	mockQueries.ListProductsFromInvoiceFunc = func(ctx context.Context, params database.ListProductsFromInvoiceParams) ([]database.ListProductsFromInvoiceRow, error) {
+		return []database.ListProductsFromInvoiceRow{{ID: 5, Name: "Lamp", Price: "10.00", Count: "2", Sum: "20.00"}}, nil
+	}
+
+	t.Run("POST invoices - Expanded items", func(t *testing.T) {
+		body := `{"invoice_number":"INV-003","customer_id":1}`
+		req := httptest.NewRequest(http.MethodPost, config.InvoicesApiPrefix+"?expand=items", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+
+		handler.InvoicesHandler(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		var response map[string]json.RawMessage
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if items := string(response["items"]); items != "[]" {
+			t.Errorf("expected an empty items array, got %s", items)
+		}
+	})
+
+	t.Run("GET invoice - Expanded items", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/1?expand=items", nil)
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response invoiceResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if response.Items == nil || len(*response.Items) != 1 || (*response.Items)[0].Sum != "20.00" {
+			t.Errorf("expected the invoice item inline, got %+v", response.Items)
+		}
+	})
+
+	t.Run("GET invoice - Lean by default", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/1", nil)
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if strings.Contains(w.Body.String(), `"items"`) {
+			t.Errorf("expected no items without expand, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("GET invoice - Unknown expand", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/1?expand=customer", nil)
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestInvoiceItemPriceSnapshot(t *testing.T) {
+	mockQueries := &invoiceMockQueries{}
+	handler := &InvoiceHandler{Queries: mockQueries, Tx: mockQueries.tx}
+
+	// A small in-memory store: the items record the price of the product when they're added, like the query does
+	prices := map[int32]string{7: "10.00"}
+	var items []database.InvoiceItem
+	mockQueries.AddProductToInvoiceFunc = func(ctx context.Context, params database.AddProductToInvoiceParams) (database.InvoiceItem, error) {
+		price, ok := prices[params.ProductID]
+		if !ok {
+			return database.InvoiceItem{}, sql.ErrNoRows
+		}
+		item := database.InvoiceItem{ID: int32(len(items) + 1), InvoiceID: params.InvoiceID, ProductID: params.ProductID, Count: params.Count, UnitPrice: price}
+		items = append(items, item)
+		return item, nil
+	}
+	mockQueries.ListProductsFromInvoiceFunc = func(ctx context.Context, params database.ListProductsFromInvoiceParams) ([]database.ListProductsFromInvoiceRow, error) {
+		var rows []database.ListProductsFromInvoiceRow
+		for _, item := range items {
+			price, _ := strconv.ParseFloat(item.UnitPrice, 64)
+			count, _ := strconv.ParseFloat(item.Count, 64)
+			rows = append(rows, database.ListProductsFromInvoiceRow{ID: item.ProductID, Name: "Lamp", Price: item.UnitPrice, Count: item.Count, Sum: fmt.Sprintf("%.2f", price*count)})
+		}
+		return rows, nil
+	}
+
+	req := httptest.NewRequest(http.MethodPost, config.InvoicesApiPrefix+"/1/products/7", bytes.NewBufferString(`{"count": "3"}`))
+	w := httptest.NewRecorder()
+	handler.InvoiceHandler(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created invoiceItemResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if created.UnitPrice != "10.00" {
+		t.Errorf("expected unit price 10.00, got %q", created.UnitPrice)
+	}
+
+	// The product gets more expensive after it's been invoiced
+	prices[7] = "12.50"
+
+	req = httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/1/products", nil)
+	w = httptest.NewRecorder()
+	handler.InvoiceHandler(w, req)
+	var lines []invoiceProductResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &lines); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(lines) != 1 || lines[0].Price != "10.00" || lines[0].Sum != "30.00" {
+		t.Errorf("expected the line at the invoiced price, got %+v", lines)
+	}
+
+	t.Run("POST invoice item - Missing product", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, config.InvoicesApiPrefix+"/1/products/8", bytes.NewBufferString(`{"count": "1"}`))
+		w := httptest.NewRecorder()
+
+		handler.InvoiceHandler(w, req)
+
+		if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), config.ErrorCodeProductNotFound) {
+			t.Errorf("expected a %d product.not_found, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+		}
+	})
 }
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+type invoiceTotalResponse struct {
+	InvoiceID ID     `json:"invoice_id"`
+	Total     string `json:"total"`
+}
+
+// TotalsHandler sums up several invoices at once: GET /invoices/totals?ids=1,2,3. The invoices without items
+// total 0.00 and the ids of the missing invoices are left out
+func (h *InvoiceHandler) TotalsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		writeAllowedMethods(w, http.MethodGet)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	// GET /invoices/totals
+	ids, err := parseIDsParam(r, "ids")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+		return
+	}
+	if len(ids) == 0 {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, "ids is required")
+		return
+	}
+	if len(ids) > config.MaxInvoiceTotalsIDs {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, "At most "+strconv.Itoa(config.MaxInvoiceTotalsIDs)+" ids are allowed")
+		return
+	}
+
+	totals, err := withQueryTimeout(r.Context(), h.AggregateTimeout, "ListInvoiceTotals", func(ctx context.Context) ([]database.ListInvoiceTotalsRow, error) {
+		return h.Queries.ListInvoiceTotals(ctx, ids)
+	})
+	if err != nil {
+		writeQueryError(w, err, h.RetryAfter)
+		return
+	}
+	response := []invoiceTotalResponse{}
+	for _, total := range totals {
+		response = append(response, invoiceTotalResponse{InvoiceID: ID(total.InvoiceID), Total: total.Total})
+	}
+	writeServerResponse(w, http.StatusOK, response)
+}
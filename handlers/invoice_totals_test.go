@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+func TestInvoiceTotals(t *testing.T) {
+	mockQueries := &invoiceMockQueries{}
+	handler := &InvoiceHandler{Queries: mockQueries, Tx: mockQueries.tx}
+
+	// Invoice 1 has items, 2 has none and 3 doesn't exist
+	totals := map[int32]string{1: "31.48", 2: "0.00"}
+	mockQueries.ListInvoiceTotalsFunc = func(ctx context.Context, ids []int32) ([]database.ListInvoiceTotalsRow, error) {
+		var rows []database.ListInvoiceTotalsRow
+		for _, id := range slices.Sorted(slices.Values(ids)) {
+			if total, ok := totals[id]; ok {
+				rows = append(rows, database.ListInvoiceTotalsRow{InvoiceID: id, Total: total})
+			}
+		}
+		return rows, nil
+	}
+
+	t.Run("GET invoices/totals - With and without items", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/totals?ids=2,3,1", nil)
+		w := httptest.NewRecorder()
+
+		handler.TotalsHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		expected := `[{"invoice_id":1,"total":"31.48"},{"invoice_id":2,"total":"0.00"}]`
+		if body := strings.TrimSpace(w.Body.String()); body != expected {
+			t.Errorf("expected %s, got %s", expected, body)
+		}
+	})
+
+	tooMany := make([]string, config.MaxInvoiceTotalsIDs+1)
+	for i := range tooMany {
+		tooMany[i] = strconv.Itoa(i + 1)
+	}
+	for name, query := range map[string]string{
+		"Missing ids": "",
+		"Invalid ids": "?ids=1,abc",
+		"Too many":    "?ids=" + strings.Join(tooMany, ","),
+	} {
+		t.Run("GET invoices/totals - "+name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/totals"+query, nil)
+			w := httptest.NewRecorder()
+
+			handler.TotalsHandler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+}
+
+func TestInvoiceTotalsTimeout(t *testing.T) {
+	mockQueries := &invoiceMockQueries{}
+	handler := &InvoiceHandler{Queries: mockQueries, Tx: mockQueries.tx, AggregateTimeout: 20 * time.Millisecond, RetryAfter: 5 * time.Second}
+
+	// The sum over an invoice with a huge number of items takes a while, the query stops when it's cancelled
+	itemsPerInvoice := map[int32]int{1: 10, 2: 5_000_000}
+	mockQueries.ListInvoiceTotalsFunc = func(ctx context.Context, ids []int32) ([]database.ListInvoiceTotalsRow, error) {
+		var rows []database.ListInvoiceTotalsRow
+		for _, id := range ids {
+			select {
+			case <-time.After(time.Duration(itemsPerInvoice[id]) * time.Microsecond):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			rows = append(rows, database.ListInvoiceTotalsRow{InvoiceID: id, Total: "1.00"})
+		}
+		return rows, nil
+	}
+
+	t.Run("GET invoices/totals - Within the bound", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/totals?ids=1", nil)
+		w := httptest.NewRecorder()
+
+		handler.TotalsHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("GET invoices/totals - Too many items", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"/totals?ids=1,2", nil)
+		w := httptest.NewRecorder()
+
+		start := time.Now()
+		handler.TotalsHandler(w, req)
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("expected the query to be cancelled at the timeout, it took %v", elapsed)
+		}
+		if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), config.ErrorCodeQueryTimeout) {
+			t.Errorf("expected a %d %s, got %d: %s", http.StatusServiceUnavailable, config.ErrorCodeQueryTimeout, w.Code, w.Body.String())
+		}
+		if retryAfter := w.Header().Get("Retry-After"); retryAfter != "5" {
+			t.Errorf("expected Retry-After 5, got %q", retryAfter)
+		}
+	})
+}
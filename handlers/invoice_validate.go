@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+type validateInvoiceRequest struct {
+	InvoiceNumber string                       `json:"invoice_number"`
+	CustomerID    ID                           `json:"customer_id"`
+	Items         []validateInvoiceItemRequest `json:"items"`
+}
+type validateInvoiceItemRequest struct {
+	ProductID ID          `json:"product_id"`
+	Count     json.Number `json:"count"`
+}
+
+type validateInvoiceResponse struct {
+	Valid  bool                     `json:"valid"`
+	Errors []invoiceValidationError `json:"errors"`
+}
+
+// invoiceValidationError refers either to an invoice field or to an item, by its 1-based line number
+type invoiceValidationError struct {
+	Line   int    `json:"line,omitempty"`
+	Field  string `json:"field,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// ValidateHandler checks an invoice together with its items the same way creating them would,
+// but only reads from the database and reports all the problems found at once
+func (h *InvoiceHandler) ValidateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		writeAllowedMethods(w, http.MethodPost)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	// POST /invoices/validate
+	var invoice validateInvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&invoice); err != nil {
+		writeServerParseError(w, err)
+		return
+	}
+	if len(invoice.Items) > config.MaxAvailabilityCartLines {
+		writeValidationError(w, config.ErrorCodeValidationOutOfRange, "items", fmt.Sprintf("An invoice can have at most %d items", config.MaxAvailabilityCartLines))
+		return
+	}
+
+	response := validateInvoiceResponse{Errors: []invoiceValidationError{}}
+	addError := func(line int, field, reason string) {
+		response.Errors = append(response.Errors, invoiceValidationError{Line: line, Field: field, Reason: reason})
+	}
+
+	if strings.TrimSpace(invoice.InvoiceNumber) == "" {
+		addError(0, "invoice_number", "invoice_number must not be empty")
+	} else if _, err := h.normalizeInvoiceNumber(invoice.InvoiceNumber); err != nil {
+		addError(0, "invoice_number", err.Error())
+	}
+	if invoice.CustomerID <= 0 {
+		addError(0, "customer_id", "customer_id should be a positive number")
+	} else if _, err := h.Queries.GetCustomer(r.Context(), int32(invoice.CustomerID)); err == sql.ErrNoRows {
+		addError(0, "customer_id", "Specified customer does not exist")
+	} else if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+
+	// A single query for all the items, the unknown products are simply missing from the result
+	ids := make([]int32, 0, len(invoice.Items))
+	for _, item := range invoice.Items {
+		ids = append(ids, int32(item.ProductID))
+	}
+	stock, err := h.Queries.ListProductStock(r.Context(), ids)
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	available := make(map[ID]int32, len(stock))
+	for _, product := range stock {
+		available[ID(product.ID)] = product.AvailableItems
+	}
+
+	// The stock has to cover all the lines of a product together
+	requested := map[ID]*big.Rat{}
+	for i, item := range invoice.Items {
+		line := i + 1
+		if msg := validateItemCount(item.Count.String()); msg != "" {
+			addError(line, "count", msg)
+			continue
+		}
+		availableItems, ok := available[item.ProductID]
+		if !ok {
+			addError(line, "product_id", "The provided product does not exist")
+			continue
+		}
+
+		count, _ := utils.ParseDecimal(item.Count.String())
+		if requested[item.ProductID] == nil {
+			requested[item.ProductID] = new(big.Rat)
+		}
+		total := requested[item.ProductID].Add(requested[item.ProductID], count.Value)
+		if total.Cmp(big.NewRat(int64(availableItems), 1)) > 0 {
+			addError(line, "count", fmt.Sprintf("insufficient stock: %d available", availableItems))
+		}
+	}
+
+	response.Valid = len(response.Errors) == 0
+	writeServerResponse(w, http.StatusOK, response)
+}
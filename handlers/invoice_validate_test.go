@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+func TestValidateHandler(t *testing.T) {
+	mockQueries := &invoiceMockQueries{}
+	handler := &InvoiceHandler{Queries: mockQueries}
+
+	mockQueries.GetCustomerFunc = func(ctx context.Context, id int32) (database.Customer, error) {
+		if id != 1 {
+			return database.Customer{}, sql.ErrNoRows
+		}
+		return database.Customer{ID: id}, nil
+	}
+	stockQueries := 0
+	mockQueries.ListProductStockFunc = func(ctx context.Context, ids []int32) ([]database.ListProductStockRow, error) {
+		stockQueries++
+		if slices.Contains(ids, 10) {
+			return []database.ListProductStockRow{{ID: 10, AvailableItems: 5}}, nil
+		}
+		return nil, nil
+	}
+
+	validate := func(body string) validateInvoiceResponse {
+		req := httptest.NewRequest(http.MethodPost, config.InvoicesApiPrefix+"/validate", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		handler.ValidateHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+
+		var response validateInvoiceResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return response
+	}
+
+	t.Run("POST invoices/validate - Valid", func(t *testing.T) {
+		response := validate(`{"invoice_number":"INV-1","customer_id":1,"items":[{"product_id":10,"count":2},{"product_id":10,"count":3}]}`)
+
+		if !response.Valid || len(response.Errors) != 0 {
+			t.Errorf("unexpected response: %v", response)
+		}
+	})
+
+	t.Run("POST invoices/validate - Line errors", func(t *testing.T) {
+		response := validate(`{"invoice_number":"INV-1","customer_id":2,"items":[{"product_id":10,"count":4},{"product_id":10,"count":2},{"product_id":11,"count":1},{"product_id":10,"count":0}]}`)
+
+		if response.Valid {
+			t.Error("expected the invoice to be invalid")
+		}
+		expected := []invoiceValidationError{
+			{Field: "customer_id", Reason: "Specified customer does not exist"},
+			{Line: 2, Field: "count", Reason: "insufficient stock: 5 available"},
+			{Line: 3, Field: "product_id", Reason: "The provided product does not exist"},
+			{Line: 4, Field: "count", Reason: "count must be greater than 0"},
+		}
+		if len(response.Errors) != len(expected) {
+			t.Fatalf("unexpected errors: %v", response.Errors)
+		}
+		for i := range expected {
+			if response.Errors[i] != expected[i] {
+				t.Errorf("expected error %v, got %v", expected[i], response.Errors[i])
+			}
+		}
+	})
+
+	t.Run("POST invoices/validate - Single stock query", func(t *testing.T) {
+		stockQueries = 0
+		validate(`{"invoice_number":"INV-1","customer_id":1,"items":[{"product_id":10,"count":1},{"product_id":11,"count":1},{"product_id":12,"count":1}]}`)
+
+		if stockQueries != 1 {
+			t.Errorf("expected a single stock query, got %d", stockQueries)
+		}
+	})
+
+	t.Run("POST invoices/validate - Too many items", func(t *testing.T) {
+		items := strings.Repeat(`{"product_id":10,"count":1},`, config.MaxAvailabilityCartLines+1)
+		body := `{"invoice_number":"INV-1","customer_id":1,"items":[` + strings.TrimSuffix(items, ",") + `]}`
+		req := httptest.NewRequest(http.MethodPost, config.InvoicesApiPrefix+"/validate", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		handler.ValidateHandler(w, req)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+)
+
+// The ?modified_since= lists are a change feed for the clients keeping a local copy: the rows are ordered by
+// updated_at, which the clients pass back as modified_since the next time to get only what changed after it
+
+type modifiedProductResponse struct {
+	productResponse
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type modifiedCustomerResponse struct {
+	customerResponse
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type modifiedInvoiceResponse struct {
+	invoiceResponse
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// writeModifiedProducts answers GET /products?modified_since=
+func (h *ProductHandler) writeModifiedProducts(w http.ResponseWriter, r *http.Request, since time.Time) {
+	products, err := h.Queries.ListProductsModifiedSince(r.Context(), since)
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	response := []modifiedProductResponse{}
+	for _, product := range products {
+		response = append(response, modifiedProductResponse{productResponse: h.newProductResponse(product), UpdatedAt: product.UpdatedAt})
+	}
+	writeServerResponse(w, http.StatusOK, response)
+}
+
+// writeModifiedCustomers answers GET /customers?modified_since=
+func (h *CustomerHandler) writeModifiedCustomers(w http.ResponseWriter, r *http.Request, since time.Time) {
+	customers, err := h.Queries.ListCustomersModifiedSince(r.Context(), since)
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	response := []modifiedCustomerResponse{}
+	for _, customer := range customers {
+		response = append(response, modifiedCustomerResponse{
+			customerResponse: customerResponse{
+				ID:        ID(customer.ID),
+				FirstName: customer.FirstName,
+				LastName:  customer.LastName,
+			},
+			UpdatedAt: customer.UpdatedAt,
+		})
+	}
+	writeServerResponse(w, http.StatusOK, response)
+}
+
+// writeModifiedInvoices answers GET /invoices?modified_since=
+func (h *InvoiceHandler) writeModifiedInvoices(w http.ResponseWriter, r *http.Request, since time.Time) {
+	invoices, err := h.Queries.ListInvoicesModifiedSince(r.Context(), since)
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	response := []modifiedInvoiceResponse{}
+	for _, invoice := range invoices {
+		response = append(response, modifiedInvoiceResponse{
+			invoiceResponse: invoiceResponse{
+				ID:            ID(invoice.ID),
+				InvoiceNumber: invoice.InvoiceNumber,
+				InvoiceDate:   Timestamp(invoice.InvoiceDate),
+				CustomerID:    ID(invoice.CustomerID),
+			},
+			UpdatedAt: invoice.UpdatedAt,
+		})
+	}
+	writeServerResponse(w, http.StatusOK, response)
+}
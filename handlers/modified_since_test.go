@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+func TestModifiedSince(t *testing.T) {
+	old := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	recent := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("GET products modified since", func(t *testing.T) {
+		mockQueries := &productMockQueries{}
+		handler := &ProductHandler{Queries: mockQueries}
+		// The mock filters like the query does
+		mockQueries.ListProductsModifiedSinceFunc = func(ctx context.Context, modifiedSince time.Time) ([]database.Product, error) {
+			var products []database.Product
+			for _, product := range []database.Product{
+				{ID: 1, Name: "Old", Price: "1.00", UpdatedAt: old},
+				{ID: 2, Name: "Recent", Price: "2.00", UpdatedAt: recent},
+			} {
+				if product.UpdatedAt.After(modifiedSince) {
+					products = append(products, product)
+				}
+			}
+			return products, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"?modified_since=2025-01-01T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+		handler.ProductsHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response []modifiedProductResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if len(response) != 1 || response[0].ID != 2 || !response[0].UpdatedAt.Equal(recent) {
+			t.Errorf("expected only the recently modified product, got %+v", response)
+		}
+	})
+
+	t.Run("GET customers modified since", func(t *testing.T) {
+		mockQueries := &customerMockQueries{}
+		handler := &CustomerHandler{Queries: mockQueries}
+		var since time.Time
+		mockQueries.ListCustomersModifiedSinceFunc = func(ctx context.Context, modifiedSince time.Time) ([]database.Customer, error) {
+			since = modifiedSince
+			return []database.Customer{{ID: 3, FirstName: "John", LastName: "Doe", UpdatedAt: recent}}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, config.CustomersApiPrefix+"?modified_since=2025-01-01T03:00:00%2B03:00", nil)
+		w := httptest.NewRecorder()
+		handler.CustomersHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if expected := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC); !since.Equal(expected) {
+			t.Errorf("expected modified_since %v, got %v", expected, since)
+		}
+	})
+
+	t.Run("GET invoices modified since", func(t *testing.T) {
+		mockQueries := &invoiceMockQueries{}
+		handler := &InvoiceHandler{Queries: mockQueries}
+		mockQueries.ListInvoicesModifiedSinceFunc = func(ctx context.Context, modifiedSince time.Time) ([]database.Invoice, error) {
+			return []database.Invoice{{ID: 4, InvoiceNumber: "INV-004", CustomerID: 3, UpdatedAt: recent}}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, config.InvoicesApiPrefix+"?modified_since=2025-01-01T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+		handler.InvoicesHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response []modifiedInvoiceResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if len(response) != 1 || response[0].ID != 4 {
+			t.Errorf("expected invoice 4, got %+v", response)
+		}
+	})
+
+	t.Run("Invalid timestamp", func(t *testing.T) {
+		handler := &ProductHandler{Queries: &productMockQueries{}}
+		for _, value := range []string{"2025-01-01", "yesterday", "1735689600"} {
+			req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"?modified_since="+value, nil)
+			w := httptest.NewRecorder()
+			handler.ProductsHandler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status code %d, got %d", value, http.StatusBadRequest, w.Code)
+			}
+		}
+	})
+
+	t.Run("Combined with another filter", func(t *testing.T) {
+		handler := &ProductHandler{Queries: &productMockQueries{}}
+		req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"?modified_since=2025-01-01T00:00:00Z&unused=true", nil)
+		w := httptest.NewRecorder()
+		handler.ProductsHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
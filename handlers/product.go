@@ -5,9 +5,14 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/egor-markin/wallcraft-go-test-task/config"
 	"github.com/egor-markin/wallcraft-go-test-task/database"
@@ -17,14 +22,34 @@ import (
 
 type ProductQueries interface {
 	ListProducts(ctx context.Context) ([]database.Product, error)
+	ListUnusedProducts(ctx context.Context) ([]database.Product, error)
+	ListProductStock(ctx context.Context, ids []int32) ([]database.ListProductStockRow, error)
+	ListProductsWithStockValue(ctx context.Context) ([]database.ListProductsWithStockValueRow, error)
+	SearchProducts(ctx context.Context, search string) ([]database.Product, error)
+	ListProductsModifiedSince(ctx context.Context, modifiedSince time.Time) ([]database.Product, error)
+	ListTopProducts(ctx context.Context, params database.ListTopProductsParams) ([]database.ListTopProductsRow, error)
 	CreateProduct(ctx context.Context, params database.CreateProductParams) (database.Product, error)
 	GetProduct(ctx context.Context, id int32) (database.Product, error)
+	GetProductForUpdate(ctx context.Context, id int32) (database.Product, error)
 	UpdateProduct(ctx context.Context, params database.UpdateProductParams) (database.Product, error)
+	UpdateProductPrice(ctx context.Context, params database.UpdateProductPriceParams) (database.Product, error)
 	DeleteProduct(ctx context.Context, id int32) (string, error)
+	CreateAuditLogEntry(ctx context.Context, params database.CreateAuditLogEntryParams) error
 }
 
 type ProductHandler struct {
 	Queries ProductQueries
+	Tx      TxFunc[ProductQueries]
+	// LowStockThreshold is the number of available items at or below which a product is reported as low on stock
+	LowStockThreshold int32
+	// MaxPriceIntegerDigits limits the digits before the decimal point of a price, zero means what the column fits
+	MaxPriceIntegerDigits int
+	// MaxDescriptionLength limits the description length in characters, zero disables the limit
+	MaxDescriptionLength int
+	// MaxAvailableItems limits the available items of a product, zero disables the limit
+	MaxAvailableItems int32
+	// Cache keeps the product list to survive short database outages, nil disables it
+	Cache *ProductsCache
 }
 
 type createProductRequest struct {
@@ -40,31 +65,94 @@ type updateProductRequest struct {
 	AvailableItems int32  `json:"available_items"`
 }
 type productResponse struct {
-	ID             int32  `json:"id"`
-	Name           string `json:"name"`
-	Description    string `json:"description"`
-	Price          string `json:"price"`
-	AvailableItems int32  `json:"available_items"`
+	ID             ID      `json:"id"`
+	Name           string  `json:"name"`
+	Description    *string `json:"description"`
+	Price          string  `json:"price"`
+	AvailableItems int32   `json:"available_items"`
+	StockStatus    string  `json:"stock_status"`
 }
 
+const (
+	stockStatusInStock    = "in_stock"
+	stockStatusLowStock   = "low_stock"
+	stockStatusOutOfStock = "out_of_stock"
+)
+
 func (h *ProductHandler) ProductsHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		// GET /products
-		products, err := h.Queries.ListProducts(r.Context())
+		unused, err := parseBoolParam(r, "unused")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+			return
+		}
+		withStockValue, err := parseBoolParam(r, "with_stock_value")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+			return
+		}
+		search := strings.TrimSpace(r.URL.Query().Get("search"))
+		modifiedSince, err := parseTimeParam(r, "modified_since")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+			return
+		}
+		if !modifiedSince.IsZero() {
+			if unused || withStockValue || search != "" {
+				writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, "modified_since can't be combined with unused, with_stock_value or search")
+				return
+			}
+			h.writeModifiedProducts(w, r, modifiedSince)
+			return
+		}
+		if search != "" {
+			if unused || withStockValue {
+				writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, "search can't be combined with unused or with_stock_value")
+				return
+			}
+			h.writeSearchedProducts(w, r, search)
+			return
+		}
+		if withStockValue {
+			if unused {
+				writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, "unused and with_stock_value can't be combined")
+				return
+			}
+			h.writeProductsWithStockValue(w, r)
+			return
+		}
+
+		var products []database.Product
+		generation := h.Cache.currentGeneration()
+		if unused {
+			// Products that don't appear on any invoice
+			products, err = h.Queries.ListUnusedProducts(r.Context())
+		} else {
+			cached, fresh, ok := h.Cache.get()
+			if ok && fresh {
+				writeServerResponse(w, http.StatusOK, cached)
+				return
+			}
+			products, err = h.Queries.ListProducts(r.Context())
+			if err != nil && ok {
+				log.Println("Serving the cached products: ", err)
+				w.Header().Set("Warning", `111 - "Revalidation Failed"`)
+				writeServerResponse(w, http.StatusOK, cached)
+				return
+			}
+		}
 		if err != nil {
 			writeInternalServerError(w, err)
 			return
 		}
 		response := []productResponse{}
 		for _, product := range products {
-			response = append(response, productResponse{
-				ID:             product.ID,
-				Name:           product.Name,
-				Description:    product.Description.String,
-				Price:          product.Price,
-				AvailableItems: product.AvailableItems,
-			})
+			response = append(response, h.newProductResponse(product))
+		}
+		if !unused {
+			h.Cache.store(response, generation)
 		}
 		writeServerResponse(w, http.StatusOK, response)
 	case http.MethodPost:
@@ -76,19 +164,23 @@ func (h *ProductHandler) ProductsHandler(w http.ResponseWriter, r *http.Request)
 		}
 
 		if strings.TrimSpace(product.Name) == "" {
-			http.Error(w, "Product name is required", http.StatusBadRequest)
+			writeValidationError(w, config.ErrorCodeValidationRequired, "name", "Product name is required")
 			return
 		}
-		if strings.TrimSpace(product.Price) == "" {
-			http.Error(w, "Product price is required", http.StatusBadRequest)
+		if msg := h.validatePrice(product.Price); msg != "" {
+			writeValidationError(w, config.ErrorCodeValidationInvalid, "price", msg)
 			return
 		}
-		if _, err := strconv.ParseFloat(product.Price, 64); err != nil {
-			http.Error(w, "Invalid price", http.StatusBadRequest)
+		if product.AvailableItems < 0 {
+			writeValidationError(w, config.ErrorCodeValidationOutOfRange, "available_items", "available_items must be greater than or equal to 0")
 			return
 		}
-		if product.AvailableItems < 0 {
-			http.Error(w, "available_items must be greater than or equal to 0", http.StatusBadRequest)
+		if h.MaxAvailableItems > 0 && product.AvailableItems > h.MaxAvailableItems {
+			writeValidationError(w, config.ErrorCodeValidationOutOfRange, "available_items", fmt.Sprintf("available_items must be less than or equal to %d", h.MaxAvailableItems))
+			return
+		}
+		if h.MaxDescriptionLength > 0 && utf8.RuneCountInString(product.Description) > h.MaxDescriptionLength {
+			writeValidationError(w, config.ErrorCodeValidationOutOfRange, "description", fmt.Sprintf("description must not be longer than %d characters", h.MaxDescriptionLength))
 			return
 		}
 
@@ -101,9 +193,9 @@ func (h *ProductHandler) ProductsHandler(w http.ResponseWriter, r *http.Request)
 		if err != nil {
 			if pqErr, ok := err.(*pq.Error); ok {
 				if pqErr.Constraint == "product_available_items_check" {
-					http.Error(w, "available_items must be greater than or equal to 0", http.StatusBadRequest)
+					writeValidationError(w, config.ErrorCodeValidationOutOfRange, "available_items", "available_items must be greater than or equal to 0")
 				} else if pqErr.Constraint == "product_price_check" {
-					http.Error(w, "price should be a positive number", http.StatusBadRequest)
+					writeValidationError(w, config.ErrorCodeValidationOutOfRange, "price", "price should be a positive number")
 				} else {
 					writeInternalServerError(w, err)
 				}
@@ -112,16 +204,13 @@ func (h *ProductHandler) ProductsHandler(w http.ResponseWriter, r *http.Request)
 			}
 			return
 		}
+		h.Cache.invalidate()
 
-		writeServerResponse(w, http.StatusCreated, productResponse{
-			ID:             createdProduct.ID,
-			Name:           createdProduct.Name,
-			Description:    createdProduct.Description.String,
-			Price:          createdProduct.Price,
-			AvailableItems: createdProduct.AvailableItems,
-		})
+		writeCreatedResponse(w, r, config.ProductsApiPrefix+"/"+strconv.Itoa(int(createdProduct.ID)), h.newProductResponse(createdProduct))
+	case http.MethodOptions:
+		writeAllowedMethods(w, http.MethodGet, http.MethodPost)
 	default:
-		http.Error(w, config.MethodNotAllowedMsg, http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodPost)
 	}
 }
 
@@ -129,7 +218,7 @@ func (h *ProductHandler) ProductHandler(w http.ResponseWriter, r *http.Request)
 	// Extract the product ID from the URL path
 	id, err := utils.ExtractTrailingID(r.URL.Path)
 	if err != nil {
-		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidID, "Invalid product ID")
 		return
 	}
 
@@ -139,73 +228,86 @@ func (h *ProductHandler) ProductHandler(w http.ResponseWriter, r *http.Request)
 		product, err := h.Queries.GetProduct(r.Context(), int32(id))
 		if err != nil {
 			if err == sql.ErrNoRows {
-				http.Error(w, "Product not found", http.StatusNotFound)
+				writeError(w, http.StatusNotFound, config.ErrorCodeProductNotFound, "Product not found")
 			} else {
 				writeInternalServerError(w, err)
 			}
 			return
 		}
-		writeServerResponse(w, http.StatusOK, productResponse{
-			ID:             product.ID,
-			Name:           product.Name,
-			Description:    product.Description.String,
-			Price:          product.Price,
-			AvailableItems: product.AvailableItems,
-		})
+		writeServerResponse(w, http.StatusOK, h.newProductResponse(product))
 	case http.MethodPatch:
 		// PATCH /products/{id}
 		var product updateProductRequest
-		if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
-			writeServerParseError(w, err)
-			return
-		}
-
-		if strings.TrimSpace(product.Name) == "" {
-			http.Error(w, "Product name is required", http.StatusBadRequest)
-			return
-		}
-		if strings.TrimSpace(product.Price) == "" {
-			http.Error(w, "Product price is required", http.StatusBadRequest)
-			return
-		}
-		if _, err := strconv.ParseFloat(product.Price, 64); err != nil {
-			http.Error(w, "Invalid price", http.StatusBadRequest)
-			return
+		var applyPatch func(current database.Product) (updateProductRequest, error)
+		switch mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType {
+		case config.ContentTypeMergePatch:
+			// Merge patch: only the fields present in the document are changed
+			var patch map[string]json.RawMessage
+			if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+				writeServerParseError(w, err)
+				return
+			}
+			applyPatch = func(current database.Product) (updateProductRequest, error) {
+				return applyProductMergePatch(current, patch)
+			}
+		case config.ContentTypeJSONPatch:
+			var operations []jsonPatchOperation
+			if err := json.NewDecoder(r.Body).Decode(&operations); err != nil {
+				writeServerParseError(w, err)
+				return
+			}
+			applyPatch = func(current database.Product) (updateProductRequest, error) {
+				return applyProductJSONPatch(current, operations)
+			}
+		default:
+			if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
+				writeServerParseError(w, err)
+				return
+			}
 		}
-		if product.AvailableItems < 0 {
-			http.Error(w, "available_items must be greater than or equal to 0", http.StatusBadRequest)
-			return
+		var updatedProduct database.Product
+		var err error
+		if applyPatch != nil {
+			// Both patch formats only describe the changes, so they're applied to the current product. It's locked
+			// until the update, a concurrent PATCH can't be overwritten with the values read before it
+			err = h.Tx(r.Context(), func(q ProductQueries) error {
+				current, err := q.GetProductForUpdate(r.Context(), int32(id))
+				if err != nil {
+					return err
+				}
+				patched, err := applyPatch(current)
+				if err != nil {
+					return &invalidPatchError{err: err}
+				}
+				if updatedProduct, err = h.updateProduct(r.Context(), q, int32(id), patched); err != nil {
+					return err
+				}
+				return q.CreateAuditLogEntry(r.Context(), database.NewAuditEntry(r.Context(), "product", int32(id), database.AuditActionUpdate))
+			})
+		} else {
+			updatedProduct, err = h.updateProduct(r.Context(), h.Queries, int32(id), product)
 		}
-
-		updatedProduct, err := h.Queries.UpdateProduct(r.Context(), database.UpdateProductParams{
-			ID:             int32(id),
-			Name:           product.Name,
-			Description:    sql.NullString{String: product.Description, Valid: product.Description != ""},
-			Price:          product.Price,
-			AvailableItems: product.AvailableItems,
-		})
 		if err != nil {
-			if err == sql.ErrNoRows {
-				http.Error(w, "Product not found", http.StatusNotFound)
-			} else if pqErr, ok := err.(*pq.Error); ok {
-				if pqErr.Constraint == "product_available_items_check" {
-					http.Error(w, "available_items must be greater than or equal to 0", http.StatusBadRequest)
-				} else {
-					writeInternalServerError(w, err)
-				}
-			} else {
+			var invalid *productValidationError
+			var invalidPatch *invalidPatchError
+			var pqErr *pq.Error
+			switch {
+			case errors.As(err, &invalid):
+				writeValidationError(w, invalid.code, invalid.field, invalid.message)
+			case errors.As(err, &invalidPatch):
+				writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, invalidPatch.Error())
+			case err == sql.ErrNoRows:
+				writeError(w, http.StatusNotFound, config.ErrorCodeProductNotFound, "Product not found")
+			case errors.As(err, &pqErr) && pqErr.Constraint == "product_available_items_check":
+				writeValidationError(w, config.ErrorCodeValidationOutOfRange, "available_items", "available_items must be greater than or equal to 0")
+			default:
 				writeInternalServerError(w, err)
 			}
 			return
 		}
+		h.Cache.invalidate()
 
-		writeServerResponse(w, http.StatusOK, productResponse{
-			ID:             updatedProduct.ID,
-			Name:           updatedProduct.Name,
-			Description:    updatedProduct.Description.String,
-			Price:          updatedProduct.Price,
-			AvailableItems: updatedProduct.AvailableItems,
-		})
+		writeServerResponse(w, http.StatusOK, h.newProductResponse(updatedProduct))
 	case http.MethodDelete:
 		// DELETE /products/{id}
 		deletionResult, err := h.Queries.DeleteProduct(r.Context(), int32(id))
@@ -216,7 +318,7 @@ func (h *ProductHandler) ProductHandler(w http.ResponseWriter, r *http.Request)
 				if pqErr.Code == "23503" { // 23503 is the SQLSTATE code for foreign key violation
 					// Check the constraint name
 					if pqErr.Constraint == "invoice_item_product_id_fkey" {
-						http.Error(w, "cannot delete product: product is referenced in the invoice_item table", http.StatusConflict)
+						writeError(w, http.StatusConflict, config.ErrorCodeProductInUse, "cannot delete product: product is referenced in the invoice_item table")
 					} else {
 						writeInternalServerError(w, err)
 					}
@@ -229,11 +331,125 @@ func (h *ProductHandler) ProductHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		if deletionResult == "product_not_found" {
-			http.Error(w, "Product not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, config.ErrorCodeProductNotFound, "Product not found")
 			return
 		}
-		w.WriteHeader(http.StatusNoContent)
+		h.Cache.invalidate()
+		writeDeletedResponse(w, deletedResponse{Deleted: true, ID: ID(id)})
+	case http.MethodOptions:
+		writeAllowedMethods(w, http.MethodGet, http.MethodPatch, http.MethodDelete)
+	default:
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodPatch, http.MethodDelete)
+	}
+}
+
+// applyProductMergePatch applies a JSON Merge Patch (RFC 7386) document to the product. A null value clears
+// the description, the other fields can't be nulled
+func applyProductMergePatch(product database.Product, patch map[string]json.RawMessage) (updateProductRequest, error) {
+	result := updateProductRequest{
+		Name:           product.Name,
+		Description:    product.Description.String,
+		Price:          product.Price,
+		AvailableItems: product.AvailableItems,
+	}
+	fields := map[string]any{
+		"name":            &result.Name,
+		"description":     &result.Description,
+		"price":           &result.Price,
+		"available_items": &result.AvailableItems,
+	}
+	for name, value := range patch {
+		target, ok := fields[name]
+		if !ok {
+			// Unknown fields are ignored, same as with a plain PATCH
+			continue
+		}
+		if string(value) == "null" {
+			if name != "description" {
+				return result, fmt.Errorf("%s can't be null", name)
+			}
+			// An empty description is stored as NULL
+			result.Description = ""
+			continue
+		}
+		if err := json.Unmarshal(value, target); err != nil {
+			return result, fmt.Errorf("Invalid %s: %v", name, err)
+		}
+	}
+	return result, nil
+}
+
+// productValidationError is answered with 422 like writeValidationError
+type productValidationError struct {
+	code, field, message string
+}
+
+func (e *productValidationError) Error() string {
+	return e.message
+}
+
+// invalidPatchError is a patch document that can't be applied to the product, it's answered with 400
+type invalidPatchError struct {
+	err error
+}
+
+func (e *invalidPatchError) Error() string {
+	return e.err.Error()
+}
+
+// updateProduct validates the product and saves it with q, which is either h.Queries or a transaction
+func (h *ProductHandler) updateProduct(ctx context.Context, q ProductQueries, id int32, product updateProductRequest) (database.Product, error) {
+	if strings.TrimSpace(product.Name) == "" {
+		return database.Product{}, &productValidationError{config.ErrorCodeValidationRequired, "name", "Product name is required"}
+	}
+	if msg := h.validatePrice(product.Price); msg != "" {
+		return database.Product{}, &productValidationError{config.ErrorCodeValidationInvalid, "price", msg}
+	}
+	if product.AvailableItems < 0 {
+		return database.Product{}, &productValidationError{config.ErrorCodeValidationOutOfRange, "available_items", "available_items must be greater than or equal to 0"}
+	}
+	if h.MaxAvailableItems > 0 && product.AvailableItems > h.MaxAvailableItems {
+		return database.Product{}, &productValidationError{config.ErrorCodeValidationOutOfRange, "available_items", fmt.Sprintf("available_items must be less than or equal to %d", h.MaxAvailableItems)}
+	}
+	if h.MaxDescriptionLength > 0 && utf8.RuneCountInString(product.Description) > h.MaxDescriptionLength {
+		return database.Product{}, &productValidationError{config.ErrorCodeValidationOutOfRange, "description", fmt.Sprintf("description must not be longer than %d characters", h.MaxDescriptionLength)}
+	}
+
+	return q.UpdateProduct(ctx, database.UpdateProductParams{
+		ID:             id,
+		Name:           product.Name,
+		Description:    sql.NullString{String: product.Description, Valid: product.Description != ""},
+		Price:          product.Price,
+		AvailableItems: product.AvailableItems,
+	})
+}
+
+func (h *ProductHandler) newProductResponse(product database.Product) productResponse {
+	return productResponse{
+		ID:             ID(product.ID),
+		Name:           product.Name,
+		Description:    nullableString(product.Description),
+		Price:          product.Price,
+		AvailableItems: product.AvailableItems,
+		StockStatus:    h.stockStatus(product.AvailableItems),
+	}
+}
+
+// nullableString maps NULL to nil, so that it's encoded as null rather than as an empty string
+func nullableString(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+func (h *ProductHandler) stockStatus(availableItems int32) string {
+	switch {
+	case availableItems <= 0:
+		return stockStatusOutOfStock
+	case availableItems <= h.LowStockThreshold:
+		return stockStatusLowStock
 	default:
-		http.Error(w, config.MethodNotAllowedMsg, http.StatusMethodNotAllowed)
+		return stockStatusInStock
 	}
 }
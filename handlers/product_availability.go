@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+type cartLineRequest struct {
+	ProductID ID          `json:"product_id"`
+	Count     json.Number `json:"count"`
+}
+type cartLineAvailabilityResponse struct {
+	ProductID   ID     `json:"product_id"`
+	Requested   string `json:"requested"`
+	Available   int32  `json:"available"`
+	Fulfillable bool   `json:"fulfillable"`
+}
+
+// AvailabilityHandler reports which lines of a cart can be fulfilled from the current stock, without reserving
+// anything. The lines of the same product are fulfillable only as long as the stock covers all of them together
+func (h *ProductHandler) AvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		writeAllowedMethods(w, http.MethodPost)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	// POST /products/availability
+	var cart []cartLineRequest
+	if err := json.NewDecoder(r.Body).Decode(&cart); err != nil {
+		writeServerParseError(w, err)
+		return
+	}
+	if len(cart) > config.MaxAvailabilityCartLines {
+		writeValidationError(w, config.ErrorCodeValidationOutOfRange, "", fmt.Sprintf("A cart can have at most %d lines", config.MaxAvailabilityCartLines))
+		return
+	}
+
+	ids := make([]int32, 0, len(cart))
+	for i, line := range cart {
+		if msg := validateItemCount(line.Count.String()); msg != "" {
+			writeValidationError(w, config.ErrorCodeValidationInvalid, fmt.Sprintf("[%d].count", i), msg)
+			return
+		}
+		ids = append(ids, int32(line.ProductID))
+	}
+
+	// A single query for the whole cart, the unknown products are simply missing from the result
+	stock, err := h.Queries.ListProductStock(r.Context(), ids)
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	available := make(map[ID]int32, len(stock))
+	for _, product := range stock {
+		available[ID(product.ID)] = product.AvailableItems
+	}
+
+	response := make([]cartLineAvailabilityResponse, 0, len(cart))
+	requested := map[ID]*big.Rat{}
+	for _, line := range cart {
+		count, _ := utils.ParseDecimal(line.Count.String())
+		if requested[line.ProductID] == nil {
+			requested[line.ProductID] = new(big.Rat)
+		}
+		total := requested[line.ProductID].Add(requested[line.ProductID], count.Value)
+		response = append(response, cartLineAvailabilityResponse{
+			ProductID:   line.ProductID,
+			Requested:   line.Count.String(),
+			Available:   available[line.ProductID],
+			Fulfillable: total.Cmp(big.NewRat(int64(available[line.ProductID]), 1)) <= 0,
+		})
+	}
+
+	writeServerResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+func TestAvailabilityHandler(t *testing.T) {
+	mockQueries := &productMockQueries{}
+	handler := &ProductHandler{Queries: mockQueries}
+
+	queries := 0
+	mockQueries.ListProductStockFunc = func(ctx context.Context, ids []int32) ([]database.ListProductStockRow, error) {
+		queries++
+		return []database.ListProductStockRow{{ID: 1, AvailableItems: 5}, {ID: 2, AvailableItems: 0}}, nil
+	}
+
+	t.Run("POST products/availability - Per line results", func(t *testing.T) {
+		body := `[{"product_id":1,"count":3},{"product_id":2,"count":1},{"product_id":99,"count":1},{"product_id":1,"count":2.5}]`
+		req := httptest.NewRequest(http.MethodPost, config.ProductsApiPrefix+"/availability", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		handler.AvailabilityHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+
+		var lines []cartLineAvailabilityResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &lines); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+
+		expected := []cartLineAvailabilityResponse{
+			{ProductID: 1, Requested: "3", Available: 5, Fulfillable: true},
+			{ProductID: 2, Requested: "1", Available: 0, Fulfillable: false},
+			{ProductID: 99, Requested: "1", Available: 0, Fulfillable: false},
+			{ProductID: 1, Requested: "2.5", Available: 5, Fulfillable: false},
+		}
+		if len(lines) != len(expected) {
+			t.Fatalf("unexpected lines: %v", lines)
+		}
+		for i := range expected {
+			if lines[i] != expected[i] {
+				t.Errorf("expected line %v, got %v", expected[i], lines[i])
+			}
+		}
+		if queries != 1 {
+			t.Errorf("expected a single query, got %d", queries)
+		}
+	})
+
+	t.Run("POST products/availability - Invalid count", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, config.ProductsApiPrefix+"/availability", strings.NewReader(`[{"product_id":1,"count":0}]`))
+		w := httptest.NewRecorder()
+
+		handler.AvailabilityHandler(w, req)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+	})
+}
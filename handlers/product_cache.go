@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+)
+
+// ProductsCache keeps the last successful GET /products response. It's served as is for TTL, and after that
+// only if the database fails, for at most config.MaxProductsCacheStaleness. A nil cache is disabled
+type ProductsCache struct {
+	TTL time.Duration
+
+	mu       sync.Mutex
+	products []productResponse
+	storedAt time.Time
+	// generation is bumped by every invalidation, so that a list read before a write isn't stored after it
+	generation uint64
+}
+
+// get returns the cached products, if any, and whether they are still fresh
+func (c *ProductsCache) get() (products []productResponse, fresh, ok bool) {
+	if c == nil {
+		return nil, false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.products == nil {
+		return nil, false, false
+	}
+	age := time.Since(c.storedAt)
+	if age > c.TTL+config.MaxProductsCacheStaleness {
+		return nil, false, false
+	}
+	return c.products, age <= c.TTL, true
+}
+
+// currentGeneration is taken before reading the products to be stored, see store
+func (c *ProductsCache) currentGeneration() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// store caches the products read at the given generation, unless the cache was invalidated since then: the
+// products may miss a write committed during the read
+func (c *ProductsCache) store(products []productResponse, generation uint64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	c.products = products
+	c.storedAt = time.Now()
+}
+
+// invalidate drops the cached products, it's called after every product write
+func (c *ProductsCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.products = nil
+	c.generation++
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+func TestProductsCache(t *testing.T) {
+	mockQueries := &productMockQueries{}
+	handler := &ProductHandler{Queries: mockQueries, Cache: &ProductsCache{TTL: time.Minute}}
+
+	queries := 0
+	var dbErr error
+	mockQueries.ListProductsFunc = func(ctx context.Context) ([]database.Product, error) {
+		queries++
+		if dbErr != nil {
+			return nil, dbErr
+		}
+		return []database.Product{{ID: 1, Name: "Product 1", Price: "10.00"}}, nil
+	}
+	mockQueries.CreateProductFunc = func(ctx context.Context, params database.CreateProductParams) (database.Product, error) {
+		return database.Product{ID: 2, Name: params.Name, Price: params.Price}, nil
+	}
+
+	list := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ProductsHandler(w, httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix, nil))
+		return w
+	}
+
+	t.Run("Cache hit", func(t *testing.T) {
+		list()
+		w := list()
+
+		if w.Code != http.StatusOK || queries != 1 {
+			t.Errorf("expected a single query, got %d (status code %d)", queries, w.Code)
+		}
+	})
+
+	t.Run("Stale on error", func(t *testing.T) {
+		handler.Cache.storedAt = time.Now().Add(-2 * time.Minute)
+		dbErr = errors.New("connection refused")
+		defer func() { dbErr = nil }()
+
+		w := list()
+
+		if w.Code != http.StatusOK || w.Header().Get("Warning") == "" {
+			t.Errorf("expected a stale response, got status code %d and Warning %q", w.Code, w.Header().Get("Warning"))
+		}
+		if queries != 2 {
+			t.Errorf("expected the expired cache to be revalidated, got %d queries", queries)
+		}
+	})
+
+	t.Run("Too stale on error", func(t *testing.T) {
+		handler.Cache.storedAt = time.Now().Add(-time.Minute - config.MaxProductsCacheStaleness - time.Second)
+		dbErr = errors.New("connection refused")
+		defer func() { dbErr = nil }()
+
+		w := list()
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("expected status code %d, got %d", http.StatusInternalServerError, w.Code)
+		}
+	})
+
+	t.Run("Invalidated after POST", func(t *testing.T) {
+		list()
+		before := queries
+
+		w := httptest.NewRecorder()
+		handler.ProductsHandler(w, httptest.NewRequest(http.MethodPost, config.ProductsApiPrefix, bytes.NewBufferString(`{"name":"Product 2","price":"5.00"}`)))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d", http.StatusCreated, w.Code)
+		}
+		list()
+
+		if queries != before+1 {
+			t.Errorf("expected the list to be queried again after the POST, got %d queries", queries-before)
+		}
+	})
+
+	t.Run("Write committed during the read", func(t *testing.T) {
+		handler.Cache.invalidate()
+		listProducts := mockQueries.ListProductsFunc
+		mockQueries.ListProductsFunc = func(ctx context.Context) ([]database.Product, error) {
+			// A PATCH commits after the products were read, but before they're stored
+			defer handler.Cache.invalidate()
+			return listProducts(ctx)
+		}
+		list()
+		mockQueries.ListProductsFunc = listProducts
+		before := queries
+
+		list()
+
+		if queries != before+1 {
+			t.Errorf("expected the products read before the write not to be cached, got %d queries", queries-before)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+// jsonPatchOperation is one operation of a JSON Patch (RFC 6902) document
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// applyProductJSONPatch applies the operations of a JSON Patch (RFC 6902) document to the product in order. Only
+// the add, replace, remove and test operations on the top-level fields are supported, and only the description can
+// be removed or set to null. The result is validated like any other update by the caller
+func applyProductJSONPatch(product database.Product, operations []jsonPatchOperation) (updateProductRequest, error) {
+	result := updateProductRequest{
+		Name:           product.Name,
+		Description:    product.Description.String,
+		Price:          product.Price,
+		AvailableItems: product.AvailableItems,
+	}
+	fields := map[string]any{
+		"name":            &result.Name,
+		"description":     &result.Description,
+		"price":           &result.Price,
+		"available_items": &result.AvailableItems,
+	}
+	for i, operation := range operations {
+		name, isPointer := strings.CutPrefix(operation.Path, "/")
+		target, ok := fields[name]
+		if !isPointer || !ok {
+			return result, fmt.Errorf("Unsupported path %q in operation %d", operation.Path, i)
+		}
+
+		switch operation.Op {
+		case "add", "replace":
+			// The fields always exist, so adding one replaces its value
+			if operation.Value == nil {
+				return result, fmt.Errorf("Operation %d has no value", i)
+			}
+			if string(operation.Value) == "null" {
+				if name != "description" {
+					return result, fmt.Errorf("%s can't be null", name)
+				}
+				result.Description = ""
+				continue
+			}
+			if err := json.Unmarshal(operation.Value, target); err != nil {
+				return result, fmt.Errorf("Invalid %s: %v", name, err)
+			}
+		case "remove":
+			if name != "description" {
+				return result, fmt.Errorf("%s can't be removed", name)
+			}
+			result.Description = ""
+		case "test":
+			if equal, err := jsonPatchValueEquals(target, operation.Value); err != nil {
+				return result, fmt.Errorf("Invalid %s: %v", name, err)
+			} else if !equal {
+				return result, fmt.Errorf("Test of %s failed in operation %d", name, i)
+			}
+		default:
+			return result, fmt.Errorf("Unsupported op %q in operation %d, the supported ops are: add,replace,remove,test", operation.Op, i)
+		}
+	}
+	return result, nil
+}
+
+// jsonPatchValueEquals compares the current value of a field with the value of a test operation. A missing
+// description equals null
+func jsonPatchValueEquals(target any, value json.RawMessage) (bool, error) {
+	switch current := target.(type) {
+	case *string:
+		if string(value) == "null" {
+			return *current == "", nil
+		}
+		var expected string
+		err := json.Unmarshal(value, &expected)
+		return err == nil && expected == *current, err
+	case *int32:
+		var expected int32
+		err := json.Unmarshal(value, &expected)
+		return err == nil && expected == *current, err
+	}
+	return false, fmt.Errorf("unsupported field type %T", target)
+}
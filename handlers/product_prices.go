@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+type updatePriceRequest struct {
+	ID    ID     `json:"id"`
+	Price string `json:"price"`
+}
+
+// updatePriceResult reports the outcome of every requested price update, in the order of the request
+type updatePriceResult struct {
+	ID     ID     `json:"id"`
+	Status string `json:"status"`
+	Price  string `json:"price,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+const (
+	priceUpdateUpdated    = "updated"
+	priceUpdateNotFound   = "not_found"
+	priceUpdateInvalid    = "invalid"
+	priceUpdateNotApplied = "not_applied"
+)
+
+// errPriceUpdateFailed rolls back the all-or-nothing price update transaction
+var errPriceUpdateFailed = errors.New("some of the price updates failed")
+
+// PricesHandler sets new prices for several products in one transaction. By default the valid updates are
+// applied even if some of the others fail, ?all_or_nothing=true rolls everything back in that case
+func (h *ProductHandler) PricesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		writeAllowedMethods(w, http.MethodPatch)
+		return
+	}
+	if r.Method != http.MethodPatch {
+		writeMethodNotAllowed(w, http.MethodPatch)
+		return
+	}
+
+	// PATCH /products/prices
+	allOrNothing, err := parseBoolParam(r, "all_or_nothing")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+		return
+	}
+
+	var updates []updatePriceRequest
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		writeServerParseError(w, err)
+		return
+	}
+
+	results := make([]updatePriceResult, len(updates))
+	failed := false
+	for i, update := range updates {
+		results[i] = updatePriceResult{ID: update.ID}
+		if msg := h.validatePrice(update.Price); msg != "" {
+			results[i].Status = priceUpdateInvalid
+			results[i].Error = msg
+			failed = true
+		}
+	}
+
+	if !failed || !allOrNothing {
+		err = h.Tx(r.Context(), func(q ProductQueries) error {
+			for i, update := range updates {
+				if results[i].Status != "" {
+					continue
+				}
+				product, err := q.UpdateProductPrice(r.Context(), database.UpdateProductPriceParams{ID: int32(update.ID), Price: update.Price})
+				if err == sql.ErrNoRows {
+					results[i].Status = priceUpdateNotFound
+					results[i].Error = "Product not found"
+					failed = true
+					continue
+				} else if err != nil {
+					return err
+				}
+				if err := q.CreateAuditLogEntry(r.Context(), database.NewAuditEntry(r.Context(), "product", product.ID, database.AuditActionUpdate)); err != nil {
+					return err
+				}
+				results[i].Status = priceUpdateUpdated
+				results[i].Price = product.Price
+			}
+			if failed && allOrNothing {
+				return errPriceUpdateFailed
+			}
+			return nil
+		})
+		if err != nil && err != errPriceUpdateFailed {
+			writeInternalServerError(w, err)
+			return
+		}
+		if err == nil {
+			h.Cache.invalidate()
+		}
+	}
+
+	if failed && allOrNothing {
+		// Nothing has been changed, the prices that would have been updated are reported as such
+		for i := range results {
+			if results[i].Status == "" || results[i].Status == priceUpdateUpdated {
+				results[i].Status = priceUpdateNotApplied
+				results[i].Price = ""
+			}
+		}
+		writeServerResponse(w, http.StatusUnprocessableEntity, results)
+		return
+	}
+
+	writeServerResponse(w, http.StatusOK, results)
+}
+
+// validatePrice checks that the price is a plain non-negative decimal fitting into the price column, so it's
+// not rejected by Postgres with an opaque error. It returns the validation error message or an empty string
+// if the price is valid
+func (h *ProductHandler) validatePrice(price string) string {
+	maxIntegerDigits := h.MaxPriceIntegerDigits
+	if maxIntegerDigits <= 0 {
+		maxIntegerDigits = config.MaxPriceIntegerDigits
+	}
+
+	if strings.TrimSpace(price) == "" {
+		return "Product price is required"
+	}
+	d, err := utils.ParseDecimal(price)
+	switch {
+	case err != nil:
+		return "Invalid price"
+	case d.Value.Sign() < 0:
+		return "price should be a positive number"
+	case d.FractionDigits > config.MaxPriceFractionDigits:
+		return fmt.Sprintf("price must have at most %d decimal places", config.MaxPriceFractionDigits)
+	case d.IntegerDigits > maxIntegerDigits:
+		return fmt.Sprintf("price must have at most %d digits before the decimal point", maxIntegerDigits)
+	}
+	return ""
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+func TestPricesHandler(t *testing.T) {
+	mockQueries := &productMockQueries{}
+	handler := &ProductHandler{Queries: mockQueries, Tx: mockQueries.tx}
+
+	var updated []int32
+	mockQueries.UpdateProductPriceFunc = func(ctx context.Context, params database.UpdateProductPriceParams) (database.Product, error) {
+		if params.ID == 404 {
+			return database.Product{}, sql.ErrNoRows
+		}
+		updated = append(updated, params.ID)
+		return database.Product{ID: params.ID, Price: params.Price}, nil
+	}
+
+	body := `[{"id":1,"price":"19.99"},{"id":2,"price":"1.999"},{"id":404,"price":"5.00"}]`
+
+	t.Run("PATCH products/prices - Valid updates are applied", func(t *testing.T) {
+		updated = nil
+		req := httptest.NewRequest(http.MethodPatch, config.ProductsApiPrefix+"/prices", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		handler.PricesHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+
+		var results []updatePriceResult
+		if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+
+		if len(results) != 3 || results[0].Status != "updated" || results[1].Status != "invalid" || results[2].Status != "not_found" {
+			t.Errorf("unexpected results: %v", results)
+		}
+		if len(updated) != 1 || updated[0] != 1 {
+			t.Errorf("unexpected updated products: %v", updated)
+		}
+	})
+
+	t.Run("PATCH products/prices - All or nothing", func(t *testing.T) {
+		updated = nil
+		req := httptest.NewRequest(http.MethodPatch, config.ProductsApiPrefix+"/prices?all_or_nothing=true", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		handler.PricesHandler(w, req)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+
+		var results []updatePriceResult
+		if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+
+		if len(results) != 3 || results[0].Status != "not_applied" || results[1].Status != "invalid" {
+			t.Errorf("unexpected results: %v", results)
+		}
+		// Invalid prices are caught before anything is written
+		if len(updated) != 0 {
+			t.Errorf("expected no updates, got %v", updated)
+		}
+	})
+
+	t.Run("PATCH products/prices - Unknown product rolls back", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPatch, config.ProductsApiPrefix+"/prices?all_or_nothing=true", strings.NewReader(`[{"id":1,"price":"19.99"},{"id":404,"price":"5.00"}]`))
+		w := httptest.NewRecorder()
+
+		rolledBack := false
+		handler := &ProductHandler{Queries: mockQueries, Tx: func(ctx context.Context, fn func(q ProductQueries) error) error {
+			err := fn(mockQueries)
+			rolledBack = err != nil
+			return err
+		}}
+		handler.PricesHandler(w, req)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+		if !rolledBack {
+			t.Error("expected the transaction to be rolled back")
+		}
+	})
+}
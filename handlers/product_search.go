@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+)
+
+// likeEscaper escapes the LIKE wildcards, so the search term is matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// writeSearchedProducts answers GET /products?search=term with the products containing the term in their name or
+// description, ignoring case
+func (h *ProductHandler) writeSearchedProducts(w http.ResponseWriter, r *http.Request, search string) {
+	if utf8.RuneCountInString(search) < config.MinProductSearchLength {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, fmt.Sprintf("search must be at least %d characters long", config.MinProductSearchLength))
+		return
+	}
+
+	products, err := h.Queries.SearchProducts(r.Context(), likeEscaper.Replace(search))
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	response := []productResponse{}
+	for _, product := range products {
+		response = append(response, h.newProductResponse(product))
+	}
+	writeServerResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+func TestProductSearch(t *testing.T) {
+	products := []database.Product{
+		{ID: 1, Name: "Blue Lamp", Description: sql.NullString{String: "A desk lamp", Valid: true}, Price: "20.00"},
+		{ID: 2, Name: "Chair", Description: sql.NullString{String: "Goes well with the blue lamp", Valid: true}, Price: "45.00"},
+		{ID: 3, Name: "Lampshade", Price: "8.00"},
+		{ID: 4, Name: "Table", Description: sql.NullString{String: "100% oak", Valid: true}, Price: "120.00"},
+	}
+
+	mockQueries := &productMockQueries{}
+	var received string
+	// Matches the same way as the query: case-insensitive substrings, the name matches first
+	mockQueries.SearchProductsFunc = func(ctx context.Context, search string) ([]database.Product, error) {
+		received = search
+		term := strings.ToLower(strings.NewReplacer(`\%`, `%`, `\_`, `_`, `\\`, `\`).Replace(search))
+		var byName, byDescription []database.Product
+		for _, product := range products {
+			if strings.Contains(strings.ToLower(product.Name), term) {
+				byName = append(byName, product)
+			} else if strings.Contains(strings.ToLower(product.Description.String), term) {
+				byDescription = append(byDescription, product)
+			}
+		}
+		return append(byName, byDescription...), nil
+	}
+	handler := &ProductHandler{Queries: mockQueries}
+
+	tests := []struct {
+		name     string
+		search   string
+		expected []ID
+	}{
+		{name: "Name only", search: "shade", expected: []ID{3}},
+		{name: "Description only", search: "DESK", expected: []ID{1}},
+		{name: "Name and description", search: "lamp", expected: []ID{1, 3, 2}},
+		{name: "No match", search: "sofa", expected: []ID{}},
+		{name: "Literal percent", search: "0%", expected: []ID{4}},
+		{name: "Only wildcards", search: "%_", expected: []ID{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"?search="+url.QueryEscape(tt.search), nil)
+			w := httptest.NewRecorder()
+
+			handler.ProductsHandler(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+
+			var response []productResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			ids := []ID{}
+			for _, product := range response {
+				ids = append(ids, product.ID)
+			}
+			if !slices.Equal(ids, tt.expected) {
+				t.Errorf("expected the products %v, got %v", tt.expected, ids)
+			}
+		})
+	}
+
+	t.Run("Wildcards and backslashes are escaped", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"?search="+url.QueryEscape(`0% o_\`), nil)
+		w := httptest.NewRecorder()
+
+		handler.ProductsHandler(w, req)
+
+		if received != `0\% o\_\\` {
+			t.Errorf("unexpected search term %q", received)
+		}
+	})
+
+	t.Run("Too short", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"?search=a", nil)
+		w := httptest.NewRecorder()
+
+		handler.ProductsHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
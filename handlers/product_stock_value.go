@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+type productWithStockValueResponse struct {
+	productResponse
+	// StockValue is price * available_items
+	StockValue string `json:"stock_value"`
+}
+
+// writeProductsWithStockValue answers GET /products?with_stock_value=true. The values are computed by Postgres
+// in numeric, and the grand total of all the products is sent in the X-Total-Stock-Value header
+func (h *ProductHandler) writeProductsWithStockValue(w http.ResponseWriter, r *http.Request) {
+	products, err := h.Queries.ListProductsWithStockValue(r.Context())
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+
+	total := "0"
+	response := []productWithStockValueResponse{}
+	for _, product := range products {
+		total = product.TotalStockValue
+		response = append(response, productWithStockValueResponse{
+			productResponse: h.newProductResponse(database.Product{
+				ID:             product.ID,
+				Name:           product.Name,
+				Description:    product.Description,
+				Price:          product.Price,
+				AvailableItems: product.AvailableItems,
+			}),
+			StockValue: product.StockValue,
+		})
+	}
+	w.Header().Set("X-Total-Stock-Value", total)
+	writeServerResponse(w, http.StatusOK, response)
+}
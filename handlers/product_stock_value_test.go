@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+func TestProductsWithStockValue(t *testing.T) {
+	mockQueries := &productMockQueries{}
+	handler := &ProductHandler{Queries: mockQueries}
+
+	t.Run("GET products with stock value", func(t *testing.T) {
+		mockQueries.ListProductsWithStockValueFunc = func(ctx context.Context) ([]database.ListProductsWithStockValueRow, error) {
+			return []database.ListProductsWithStockValueRow{
+				{ID: 1, Name: "Product 1", Price: "0.10", AvailableItems: 3, StockValue: "0.30", TotalStockValue: "25.30"},
+				{ID: 2, Name: "Product 2", Price: "12.50", AvailableItems: 2, StockValue: "25.00", TotalStockValue: "25.30"},
+			}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"?with_stock_value=true", nil)
+		w := httptest.NewRecorder()
+
+		handler.ProductsHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if total := w.Header().Get("X-Total-Stock-Value"); total != "25.30" {
+			t.Errorf("expected X-Total-Stock-Value 25.30, got %q", total)
+		}
+
+		var products []productWithStockValueResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &products); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if len(products) != 2 || products[0].StockValue != "0.30" || products[1].StockValue != "25.00" || products[1].Price != "12.50" {
+			t.Errorf("unexpected products: %+v", products)
+		}
+	})
+
+	t.Run("GET products with stock value - No products", func(t *testing.T) {
+		mockQueries.ListProductsWithStockValueFunc = func(ctx context.Context) ([]database.ListProductsWithStockValueRow, error) {
+			return nil, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"?with_stock_value=true", nil)
+		w := httptest.NewRecorder()
+
+		handler.ProductsHandler(w, req)
+
+		if total := w.Header().Get("X-Total-Stock-Value"); total != "0" {
+			t.Errorf("expected X-Total-Stock-Value 0, got %q", total)
+		}
+		if body := strings.TrimSpace(w.Body.String()); body != "[]" {
+			t.Errorf("expected an empty list, got %s", body)
+		}
+	})
+
+	t.Run("GET products - No stock value by default", func(t *testing.T) {
+		mockQueries.ListProductsFunc = func(ctx context.Context) ([]database.Product, error) {
+			return []database.Product{{ID: 1, Name: "Product 1", Price: "0.10", AvailableItems: 3}}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix, nil)
+		w := httptest.NewRecorder()
+
+		handler.ProductsHandler(w, req)
+
+		if strings.Contains(w.Body.String(), "stock_value") || w.Header().Get("X-Total-Stock-Value") != "" {
+			t.Errorf("unexpected stock value in the default response: %s", w.Body.String())
+		}
+	})
+
+	t.Run("GET products with stock value - Combined with unused", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"?with_stock_value=true&unused=true", nil)
+		w := httptest.NewRecorder()
+
+		handler.ProductsHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
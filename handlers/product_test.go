@@ -5,28 +5,63 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/egor-markin/wallcraft-go-test-task/config"
 	"github.com/egor-markin/wallcraft-go-test-task/database"
 )
 
 type productMockQueries struct {
-	ListProductsFunc  func(ctx context.Context) ([]database.Product, error)
-	CreateProductFunc func(ctx context.Context, params database.CreateProductParams) (database.Product, error)
-	GetProductFunc    func(ctx context.Context, id int32) (database.Product, error)
-	UpdateProductFunc func(ctx context.Context, params database.UpdateProductParams) (database.Product, error)
-	DeleteProductFunc func(ctx context.Context, id int32) (string, error)
-	WithTxFunc        func(tx *sql.Tx) *database.Queries
+	ListProductsFunc               func(ctx context.Context) ([]database.Product, error)
+	ListUnusedProductsFunc         func(ctx context.Context) ([]database.Product, error)
+	ListProductStockFunc           func(ctx context.Context, ids []int32) ([]database.ListProductStockRow, error)
+	SearchProductsFunc             func(ctx context.Context, search string) ([]database.Product, error)
+	ListProductsModifiedSinceFunc  func(ctx context.Context, modifiedSince time.Time) ([]database.Product, error)
+	ListTopProductsFunc            func(ctx context.Context, params database.ListTopProductsParams) ([]database.ListTopProductsRow, error)
+	ListProductsWithStockValueFunc func(ctx context.Context) ([]database.ListProductsWithStockValueRow, error)
+	CreateProductFunc              func(ctx context.Context, params database.CreateProductParams) (database.Product, error)
+	GetProductFunc                 func(ctx context.Context, id int32) (database.Product, error)
+	GetProductForUpdateFunc        func(ctx context.Context, id int32) (database.Product, error)
+	UpdateProductFunc              func(ctx context.Context, params database.UpdateProductParams) (database.Product, error)
+	UpdateProductPriceFunc         func(ctx context.Context, params database.UpdateProductPriceParams) (database.Product, error)
+	DeleteProductFunc              func(ctx context.Context, id int32) (string, error)
+	WithTxFunc                     func(tx *sql.Tx) *database.Queries
 }
 
 func (m *productMockQueries) ListProducts(ctx context.Context) ([]database.Product, error) {
 	return m.ListProductsFunc(ctx)
 }
 
+func (m *productMockQueries) ListUnusedProducts(ctx context.Context) ([]database.Product, error) {
+	return m.ListUnusedProductsFunc(ctx)
+}
+
+func (m *productMockQueries) ListProductStock(ctx context.Context, ids []int32) ([]database.ListProductStockRow, error) {
+	return m.ListProductStockFunc(ctx, ids)
+}
+
+func (m *productMockQueries) ListTopProducts(ctx context.Context, params database.ListTopProductsParams) ([]database.ListTopProductsRow, error) {
+	return m.ListTopProductsFunc(ctx, params)
+}
+
+func (m *productMockQueries) ListProductsWithStockValue(ctx context.Context) ([]database.ListProductsWithStockValueRow, error) {
+	return m.ListProductsWithStockValueFunc(ctx)
+}
+
+func (m *productMockQueries) SearchProducts(ctx context.Context, search string) ([]database.Product, error) {
+	return m.SearchProductsFunc(ctx, search)
+}
+
+func (m *productMockQueries) ListProductsModifiedSince(ctx context.Context, modifiedSince time.Time) ([]database.Product, error) {
+	return m.ListProductsModifiedSinceFunc(ctx, modifiedSince)
+}
+
 func (m *productMockQueries) CreateProduct(ctx context.Context, params database.CreateProductParams) (database.Product, error) {
 	return m.CreateProductFunc(ctx, params)
 }
@@ -35,10 +70,18 @@ func (m *productMockQueries) GetProduct(ctx context.Context, id int32) (database
 	return m.GetProductFunc(ctx, id)
 }
 
+func (m *productMockQueries) GetProductForUpdate(ctx context.Context, id int32) (database.Product, error) {
+	return m.GetProductForUpdateFunc(ctx, id)
+}
+
 func (m *productMockQueries) UpdateProduct(ctx context.Context, params database.UpdateProductParams) (database.Product, error) {
 	return m.UpdateProductFunc(ctx, params)
 }
 
+func (m *productMockQueries) UpdateProductPrice(ctx context.Context, params database.UpdateProductPriceParams) (database.Product, error) {
+	return m.UpdateProductPriceFunc(ctx, params)
+}
+
 func (m *productMockQueries) DeleteProduct(ctx context.Context, id int32) (string, error) {
 	return m.DeleteProductFunc(ctx, id)
 }
@@ -47,6 +90,14 @@ func (m *productMockQueries) WithTx(tx *sql.Tx) *database.Queries {
 	return m.WithTxFunc(tx)
 }
 
+func (m *productMockQueries) CreateAuditLogEntry(ctx context.Context, params database.CreateAuditLogEntryParams) error {
+	return nil
+}
+
+func (m *productMockQueries) tx(ctx context.Context, fn func(q ProductQueries) error) error {
+	return fn(m)
+}
+
 func TestProductsHandler(t *testing.T) {
 	mockQueries := &productMockQueries{}
 	handler := &ProductHandler{Queries: mockQueries}
@@ -83,6 +134,44 @@ func TestProductsHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("GET products - Unused", func(t *testing.T) {
+		mockQueries.ListProductsFunc = func(ctx context.Context) ([]database.Product, error) {
+			return nil, errors.New("all products must not be listed")
+		}
+		mockQueries.ListUnusedProductsFunc = func(ctx context.Context) ([]database.Product, error) {
+			return []database.Product{{ID: 7, Name: "Dead stock", Price: "1.00"}}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"?unused=true", nil)
+		w := httptest.NewRecorder()
+
+		handler.ProductsHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+
+		var products []productResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &products); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+
+		if len(products) != 1 || products[0].ID != 7 {
+			t.Errorf("unexpected products: %v", products)
+		}
+	})
+
+	t.Run("GET products - Invalid unused value", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"?unused=maybe", nil)
+		w := httptest.NewRecorder()
+
+		handler.ProductsHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
 	// POST /products
 	t.Run("POST products - Success", func(t *testing.T) {
 		newProduct := createProductRequest{Name: "New Product", Price: "150.0"}
@@ -110,11 +199,97 @@ func TestProductsHandler(t *testing.T) {
 			t.Errorf("unexpected created product: %v", createdProduct)
 		}
 	})
+
+	t.Run("POST products - Prefer return=minimal", func(t *testing.T) {
+		mockQueries.CreateProductFunc = func(ctx context.Context, params database.CreateProductParams) (database.Product, error) {
+			return database.Product{ID: 3, Name: params.Name, Price: params.Price}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodPost, config.ProductsApiPrefix, bytes.NewBufferString(`{"name":"New Product","price":"150.0"}`))
+		req.Header.Set("Prefer", "return=minimal")
+		w := httptest.NewRecorder()
+
+		handler.ProductsHandler(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("expected status code %d, got %d", http.StatusCreated, w.Code)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("expected an empty body, got %s", w.Body.String())
+		}
+		if w.Header().Get("Location") != config.ProductsApiPrefix+"/3" || w.Header().Get("Preference-Applied") != "return=minimal" {
+			t.Errorf("unexpected headers: %v", w.Header())
+		}
+	})
+
+	t.Run("POST products - Prefer return=representation", func(t *testing.T) {
+		mockQueries.CreateProductFunc = func(ctx context.Context, params database.CreateProductParams) (database.Product, error) {
+			return database.Product{ID: 3, Name: params.Name, Price: params.Price}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodPost, config.ProductsApiPrefix, bytes.NewBufferString(`{"name":"New Product","price":"150.0"}`))
+		req.Header.Set("Prefer", "return=representation")
+		w := httptest.NewRecorder()
+
+		handler.ProductsHandler(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("expected status code %d, got %d", http.StatusCreated, w.Code)
+		}
+
+		var createdProduct productResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &createdProduct); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if createdProduct.ID != 3 || w.Header().Get("Location") != config.ProductsApiPrefix+"/3" {
+			t.Errorf("unexpected created product: %v", createdProduct)
+		}
+	})
+
+	t.Run("POST products - Validation Error", func(t *testing.T) {
+		productJSON, _ := json.Marshal(createProductRequest{Name: "New Product", Price: "abc"})
+		req := httptest.NewRequest(http.MethodPost, config.ProductsApiPrefix, bytes.NewBuffer(productJSON))
+		w := httptest.NewRecorder()
+
+		handler.ProductsHandler(w, req)
+
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+
+		var response errorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+
+		if response.Field != "price" || response.Code != config.ErrorCodeValidationInvalid {
+			t.Errorf("unexpected error response: %v", response)
+		}
+	})
+
+	t.Run("POST products - Malformed JSON", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, config.ProductsApiPrefix, bytes.NewBufferString(`{"name": 5}`))
+		w := httptest.NewRecorder()
+
+		handler.ProductsHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+
+		var response errorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if response.Code != config.ErrorCodeMalformedJSON {
+			t.Errorf("unexpected error response: %v", response)
+		}
+	})
 }
 
 func TestProductHandler(t *testing.T) {
 	mockQueries := &productMockQueries{}
-	handler := &ProductHandler{Queries: mockQueries}
+	handler := &ProductHandler{Queries: mockQueries, Tx: mockQueries.tx}
 
 	// GET /products/{id}
 	t.Run("GET products/{id} - Success", func(t *testing.T) {
@@ -141,7 +316,7 @@ func TestProductHandler(t *testing.T) {
 			t.Fatalf("failed to unmarshal response: %v", err)
 		}
 
-		if product.ID != p.ID || product.Name != p.Name || product.Price != p.Price {
+		if product.ID != ID(p.ID) || product.Name != p.Name || product.Price != p.Price {
 			t.Errorf("unexpected product: %v", product)
 		}
 	})
@@ -161,8 +336,12 @@ func TestProductHandler(t *testing.T) {
 			t.Errorf("expected status code %d, got %d", http.StatusNotFound, w.Code)
 		}
 
-		if w.Body.String() != "Product not found\n" {
-			t.Errorf("unexpected response body: %s", w.Body.String())
+		var response errorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if response.Code != config.ErrorCodeProductNotFound || response.Error != "Product not found" {
+			t.Errorf("unexpected error response: %v", response)
 		}
 	})
 
@@ -192,11 +371,217 @@ func TestProductHandler(t *testing.T) {
 			t.Fatalf("failed to unmarshal response: %v", err)
 		}
 
-		if updatedProduct.ID != productID || updatedProduct.Name != updateParams.Name || updatedProduct.Price != updateParams.Price {
+		if updatedProduct.ID != ID(productID) || updatedProduct.Name != updateParams.Name || updatedProduct.Price != updateParams.Price {
 			t.Errorf("unexpected updated product: %v", updatedProduct)
 		}
 	})
 
+	t.Run("PATCH products/{id} - Merge patch clears the description", func(t *testing.T) {
+		mockQueries.GetProductForUpdateFunc = func(ctx context.Context, id int32) (database.Product, error) {
+			return database.Product{ID: id, Name: "Product", Description: sql.NullString{String: "Old", Valid: true}, Price: "10.00", AvailableItems: 3}, nil
+		}
+		var updated database.UpdateProductParams
+		mockQueries.UpdateProductFunc = func(ctx context.Context, params database.UpdateProductParams) (database.Product, error) {
+			updated = params
+			return database.Product{ID: params.ID, Name: params.Name, Description: params.Description, Price: params.Price, AvailableItems: params.AvailableItems}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodPatch, config.ProductsApiPrefix+"/7", bytes.NewBufferString(`{"description":null,"price":"12.50"}`))
+		req.Header.Set("Content-Type", config.ContentTypeMergePatch)
+		w := httptest.NewRecorder()
+
+		handler.ProductHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if updated.Description.Valid || updated.Name != "Product" || updated.Price != "12.50" || updated.AvailableItems != 3 {
+			t.Errorf("unexpected update params: %v", updated)
+		}
+	})
+
+	t.Run("PATCH products/{id} - Merge patch reads and updates in one transaction", func(t *testing.T) {
+		inTx := false
+		handler := &ProductHandler{Queries: mockQueries, Tx: func(ctx context.Context, fn func(q ProductQueries) error) error {
+			inTx = true
+			defer func() { inTx = false }()
+			return fn(mockQueries)
+		}}
+		mockQueries.GetProductForUpdateFunc = func(ctx context.Context, id int32) (database.Product, error) {
+			if !inTx {
+				t.Error("expected the product to be locked in the transaction")
+			}
+			return database.Product{ID: id, Name: "Product", Price: "10.00"}, nil
+		}
+		mockQueries.UpdateProductFunc = func(ctx context.Context, params database.UpdateProductParams) (database.Product, error) {
+			if !inTx {
+				t.Error("expected the product to be updated in the same transaction")
+			}
+			return database.Product{ID: params.ID, Name: params.Name, Price: params.Price}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodPatch, config.ProductsApiPrefix+"/7", bytes.NewBufferString(`{"price":"12.50"}`))
+		req.Header.Set("Content-Type", config.ContentTypeMergePatch)
+		w := httptest.NewRecorder()
+
+		handler.ProductHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("PATCH products/{id} - Merge patch of a missing product", func(t *testing.T) {
+		mockQueries.GetProductForUpdateFunc = func(ctx context.Context, id int32) (database.Product, error) {
+			return database.Product{}, sql.ErrNoRows
+		}
+
+		req := httptest.NewRequest(http.MethodPatch, config.ProductsApiPrefix+"/7", bytes.NewBufferString(`{"price":"12.50"}`))
+		req.Header.Set("Content-Type", config.ContentTypeMergePatch)
+		w := httptest.NewRecorder()
+
+		handler.ProductHandler(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+
+	t.Run("PATCH products/{id} - Merge patch keeps an omitted description", func(t *testing.T) {
+		mockQueries.GetProductForUpdateFunc = func(ctx context.Context, id int32) (database.Product, error) {
+			return database.Product{ID: id, Name: "Product", Description: sql.NullString{String: "Old", Valid: true}, Price: "10.00"}, nil
+		}
+		var updated database.UpdateProductParams
+		mockQueries.UpdateProductFunc = func(ctx context.Context, params database.UpdateProductParams) (database.Product, error) {
+			updated = params
+			return database.Product{ID: params.ID, Name: params.Name, Description: params.Description, Price: params.Price}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodPatch, config.ProductsApiPrefix+"/7", bytes.NewBufferString(`{"name":"Renamed"}`))
+		req.Header.Set("Content-Type", config.ContentTypeMergePatch)
+		w := httptest.NewRecorder()
+
+		handler.ProductHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if updated.Description.String != "Old" || !updated.Description.Valid || updated.Name != "Renamed" {
+			t.Errorf("unexpected update params: %v", updated)
+		}
+	})
+
+	t.Run("PATCH products/{id} - Merge patch can't null the name", func(t *testing.T) {
+		mockQueries.GetProductForUpdateFunc = func(ctx context.Context, id int32) (database.Product, error) {
+			return database.Product{ID: id, Name: "Product", Price: "10.00"}, nil
+		}
+
+		req := httptest.NewRequest(http.MethodPatch, config.ProductsApiPrefix+"/7", bytes.NewBufferString(`{"name":null}`))
+		req.Header.Set("Content-Type", config.ContentTypeMergePatch)
+		w := httptest.NewRecorder()
+
+		handler.ProductHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("PATCH products/{id} - JSON Patch replaces the price", func(t *testing.T) {
+		mockQueries.GetProductForUpdateFunc = func(ctx context.Context, id int32) (database.Product, error) {
+			return database.Product{ID: id, Name: "Product", Description: sql.NullString{String: "Old", Valid: true}, Price: "10.00", AvailableItems: 3}, nil
+		}
+		var updated database.UpdateProductParams
+		mockQueries.UpdateProductFunc = func(ctx context.Context, params database.UpdateProductParams) (database.Product, error) {
+			updated = params
+			return database.Product{ID: params.ID, Name: params.Name, Description: params.Description, Price: params.Price, AvailableItems: params.AvailableItems}, nil
+		}
+
+		body := `[{"op":"test","path":"/price","value":"10.00"},{"op":"replace","path":"/price","value":"19.99"}]`
+		req := httptest.NewRequest(http.MethodPatch, config.ProductsApiPrefix+"/7", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", config.ContentTypeJSONPatch)
+		w := httptest.NewRecorder()
+
+		handler.ProductHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if updated.Price != "19.99" || updated.Name != "Product" || updated.Description.String != "Old" || updated.AvailableItems != 3 {
+			t.Errorf("unexpected update params: %v", updated)
+		}
+	})
+
+	t.Run("PATCH products/{id} - JSON Patch test is checked against the locked product", func(t *testing.T) {
+		inTx := false
+		var txErr error
+		handler := &ProductHandler{Queries: mockQueries, Tx: func(ctx context.Context, fn func(q ProductQueries) error) error {
+			inTx = true
+			defer func() { inTx = false }()
+			txErr = fn(mockQueries)
+			return txErr
+		}}
+		// The price was changed by someone else since the client read it
+		mockQueries.GetProductForUpdateFunc = func(ctx context.Context, id int32) (database.Product, error) {
+			if !inTx {
+				t.Error("expected the product to be locked in the transaction")
+			}
+			return database.Product{ID: id, Name: "Product", Price: "11.00"}, nil
+		}
+		mockQueries.UpdateProductFunc = func(ctx context.Context, params database.UpdateProductParams) (database.Product, error) {
+			t.Error("expected the product not to be updated")
+			return database.Product{}, nil
+		}
+
+		body := `[{"op":"test","path":"/price","value":"10.00"},{"op":"replace","path":"/price","value":"9.00"}]`
+		req := httptest.NewRequest(http.MethodPatch, config.ProductsApiPrefix+"/7", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", config.ContentTypeJSONPatch)
+		w := httptest.NewRecorder()
+
+		handler.ProductHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+		if txErr == nil {
+			t.Error("expected the transaction to be rolled back")
+		}
+	})
+
+	t.Run("PATCH products/{id} - JSON Patch rejections", func(t *testing.T) {
+		mockQueries.GetProductForUpdateFunc = func(ctx context.Context, id int32) (database.Product, error) {
+			return database.Product{ID: id, Name: "Product", Price: "10.00"}, nil
+		}
+		mockQueries.UpdateProductFunc = func(ctx context.Context, params database.UpdateProductParams) (database.Product, error) {
+			t.Error("expected the product not to be updated")
+			return database.Product{}, nil
+		}
+
+		tests := []struct {
+			name     string
+			body     string
+			expected int
+		}{
+			{name: "Invalid path", body: `[{"op":"replace","path":"/stock","value":1}]`, expected: http.StatusBadRequest},
+			{name: "Nested path", body: `[{"op":"replace","path":"/price/0","value":"1"}]`, expected: http.StatusBadRequest},
+			{name: "Unsupported op", body: `[{"op":"move","from":"/name","path":"/description"}]`, expected: http.StatusBadRequest},
+			{name: "Failed test", body: `[{"op":"test","path":"/price","value":"11.00"},{"op":"replace","path":"/price","value":"1"}]`, expected: http.StatusBadRequest},
+			{name: "Removed name", body: `[{"op":"remove","path":"/name"}]`, expected: http.StatusBadRequest},
+			{name: "Invalid result", body: `[{"op":"replace","path":"/price","value":"-1"}]`, expected: http.StatusUnprocessableEntity},
+		}
+		for _, tt := range tests {
+			req := httptest.NewRequest(http.MethodPatch, config.ProductsApiPrefix+"/7", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", config.ContentTypeJSONPatch)
+			w := httptest.NewRecorder()
+
+			handler.ProductHandler(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("%s: expected status code %d, got %d", tt.name, tt.expected, w.Code)
+			}
+		}
+	})
+
 	// DELETE products/{id}
 	t.Run("DELETE products/{id} - Success", func(t *testing.T) {
 		var productId int32 = 444
@@ -217,3 +602,224 @@ func TestProductHandler(t *testing.T) {
 		}
 	})
 }
+
+func TestProductStockStatus(t *testing.T) {
+	mockQueries := &productMockQueries{}
+	handler := &ProductHandler{Queries: mockQueries, LowStockThreshold: 5}
+
+	tests := []struct {
+		availableItems int32
+		expected       string
+	}{
+		{availableItems: 0, expected: "out_of_stock"},
+		{availableItems: 1, expected: "low_stock"},
+		{availableItems: 5, expected: "low_stock"},
+		{availableItems: 6, expected: "in_stock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(int(tt.availableItems))+" available items", func(t *testing.T) {
+			mockQueries.GetProductFunc = func(ctx context.Context, id int32) (database.Product, error) {
+				return database.Product{ID: id, Name: "Product", Price: "10.00", AvailableItems: tt.availableItems}, nil
+			}
+
+			req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"/1", nil)
+			w := httptest.NewRecorder()
+
+			handler.ProductHandler(w, req)
+
+			var product productResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &product); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+
+			if product.StockStatus != tt.expected || product.AvailableItems != tt.availableItems {
+				t.Errorf("expected stock status %q, got %q", tt.expected, product.StockStatus)
+			}
+		})
+	}
+}
+
+func TestProductPricePrecision(t *testing.T) {
+	mockQueries := &productMockQueries{}
+	mockQueries.CreateProductFunc = func(ctx context.Context, params database.CreateProductParams) (database.Product, error) {
+		return database.Product{ID: 1, Name: params.Name, Price: params.Price}, nil
+	}
+
+	tests := []struct {
+		name             string
+		maxIntegerDigits int
+		price            string
+		expected         int
+	}{
+		{name: "At the column boundary", price: "99999999.99", expected: http.StatusCreated},
+		{name: "Overflows the column", price: "100000000", expected: http.StatusUnprocessableEntity},
+		{name: "Too many decimal places", price: "1.999", expected: http.StatusUnprocessableEntity},
+		{name: "At the configured boundary", maxIntegerDigits: 4, price: "9999.99", expected: http.StatusCreated},
+		{name: "Over the configured boundary", maxIntegerDigits: 4, price: "10000", expected: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &ProductHandler{Queries: mockQueries, MaxPriceIntegerDigits: tt.maxIntegerDigits}
+			req := httptest.NewRequest(http.MethodPost, config.ProductsApiPrefix, bytes.NewBufferString(`{"name":"Product","price":"`+tt.price+`"}`))
+			w := httptest.NewRecorder()
+
+			handler.ProductsHandler(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status code %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestProductDescriptionLength(t *testing.T) {
+	mockQueries := &productMockQueries{}
+	mockQueries.CreateProductFunc = func(ctx context.Context, params database.CreateProductParams) (database.Product, error) {
+		return database.Product{ID: 1, Name: params.Name, Description: params.Description, Price: params.Price}, nil
+	}
+	mockQueries.UpdateProductFunc = func(ctx context.Context, params database.UpdateProductParams) (database.Product, error) {
+		return database.Product{ID: params.ID, Name: params.Name, Description: params.Description, Price: params.Price}, nil
+	}
+	handler := &ProductHandler{Queries: mockQueries, MaxDescriptionLength: 5}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		description string
+		expected    int
+	}{
+		// The multibyte characters count as one each
+		{name: "POST at the limit", method: http.MethodPost, path: config.ProductsApiPrefix, description: "héllö", expected: http.StatusCreated},
+		{name: "POST over the limit", method: http.MethodPost, path: config.ProductsApiPrefix, description: "héllö!", expected: http.StatusUnprocessableEntity},
+		{name: "PATCH at the limit", method: http.MethodPatch, path: config.ProductsApiPrefix + "/1", description: "héllö", expected: http.StatusOK},
+		{name: "PATCH over the limit", method: http.MethodPatch, path: config.ProductsApiPrefix + "/1", description: "héllö!", expected: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(`{"name":"Product","price":"1.00","description":"`+tt.description+`"}`))
+			w := httptest.NewRecorder()
+
+			if tt.method == http.MethodPost {
+				handler.ProductsHandler(w, req)
+			} else {
+				handler.ProductHandler(w, req)
+			}
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status code %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestProductAvailableItemsLimit(t *testing.T) {
+	mockQueries := &productMockQueries{}
+	mockQueries.CreateProductFunc = func(ctx context.Context, params database.CreateProductParams) (database.Product, error) {
+		return database.Product{ID: 1, Name: params.Name, Price: params.Price, AvailableItems: params.AvailableItems}, nil
+	}
+	mockQueries.UpdateProductFunc = func(ctx context.Context, params database.UpdateProductParams) (database.Product, error) {
+		return database.Product{ID: params.ID, Name: params.Name, Price: params.Price, AvailableItems: params.AvailableItems}, nil
+	}
+	handler := &ProductHandler{Queries: mockQueries, MaxAvailableItems: 10_000_000}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		availableItems string
+		expected       int
+	}{
+		{name: "POST at the cap", method: http.MethodPost, path: config.ProductsApiPrefix, availableItems: "10000000", expected: http.StatusCreated},
+		{name: "POST over the cap", method: http.MethodPost, path: config.ProductsApiPrefix, availableItems: "10000001", expected: http.StatusUnprocessableEntity},
+		{name: "PATCH at the cap", method: http.MethodPatch, path: config.ProductsApiPrefix + "/1", availableItems: "10000000", expected: http.StatusOK},
+		{name: "PATCH over the cap", method: http.MethodPatch, path: config.ProductsApiPrefix + "/1", availableItems: "100000000", expected: http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(`{"name":"Product","price":"1.00","available_items":`+tt.availableItems+`}`))
+			w := httptest.NewRecorder()
+
+			if tt.method == http.MethodPost {
+				handler.ProductsHandler(w, req)
+			} else {
+				handler.ProductHandler(w, req)
+			}
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status code %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+			if tt.expected == http.StatusUnprocessableEntity && !strings.Contains(w.Body.String(), "available_items") {
+				t.Errorf("expected the error to name available_items: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestProductOptions(t *testing.T) {
+	handler := &ProductHandler{Queries: &productMockQueries{}}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		handle   http.HandlerFunc
+		expected int
+		allow    string
+	}{
+		{name: "OPTIONS collection", method: http.MethodOptions, path: config.ProductsApiPrefix, handle: handler.ProductsHandler, expected: http.StatusNoContent, allow: "GET, POST, OPTIONS"},
+		{name: "OPTIONS item", method: http.MethodOptions, path: config.ProductsApiPrefix + "/1", handle: handler.ProductHandler, expected: http.StatusNoContent, allow: "GET, PATCH, DELETE, OPTIONS"},
+		{name: "Unsupported method", method: http.MethodPut, path: config.ProductsApiPrefix + "/1", handle: handler.ProductHandler, expected: http.StatusMethodNotAllowed, allow: "GET, PATCH, DELETE, OPTIONS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			tt.handle(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status code %d, got %d", tt.expected, w.Code)
+			}
+			if allow := w.Header().Get("Allow"); allow != tt.allow {
+				t.Errorf("expected Allow %q, got %q", tt.allow, allow)
+			}
+		})
+	}
+}
+
+func TestProductDescription(t *testing.T) {
+	tests := []struct {
+		name        string
+		description sql.NullString
+		expected    string
+	}{
+		{name: "NULL description", description: sql.NullString{}, expected: `"description":null`},
+		{name: "Empty description", description: sql.NullString{Valid: true}, expected: `"description":""`},
+		{name: "Description", description: sql.NullString{String: "Blue", Valid: true}, expected: `"description":"Blue"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockQueries := &productMockQueries{
+				GetProductFunc: func(ctx context.Context, id int32) (database.Product, error) {
+					return database.Product{ID: id, Name: "Product", Description: tt.description, Price: "1.00"}, nil
+				},
+			}
+			handler := &ProductHandler{Queries: mockQueries}
+			req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"/1", nil)
+			w := httptest.NewRecorder()
+
+			handler.ProductHandler(w, req)
+
+			if !strings.Contains(w.Body.String(), tt.expected) {
+				t.Errorf("expected %s in the response, got %s", tt.expected, w.Body.String())
+			}
+		})
+	}
+}
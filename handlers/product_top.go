@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+type topProductResponse struct {
+	productResponse
+	// Sold is the total count of the product across all the invoices
+	Sold string `json:"sold"`
+}
+
+// TopProductsHandler lists the best-selling products, the ones that were never sold are only included
+// with ?include_unsold=true
+func (h *ProductHandler) TopProductsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		writeAllowedMethods(w, http.MethodGet)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	// GET /products/top
+	params := database.ListTopProductsParams{MaxEntries: config.DefaultTopProductsLimit}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > config.MaxTopProductsLimit {
+			writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, "limit must be between 1 and "+strconv.Itoa(config.MaxTopProductsLimit))
+			return
+		}
+		params.MaxEntries = int32(limit)
+	}
+	includeUnsold, err := parseBoolParam(r, "include_unsold")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+		return
+	}
+	params.IncludeUnsold = includeUnsold
+
+	products, err := h.Queries.ListTopProducts(r.Context(), params)
+	if err != nil {
+		writeInternalServerError(w, err)
+		return
+	}
+	response := []topProductResponse{}
+	for _, product := range products {
+		response = append(response, topProductResponse{
+			productResponse: h.newProductResponse(database.Product{
+				ID:             product.ID,
+				Name:           product.Name,
+				Description:    product.Description,
+				Price:          product.Price,
+				AvailableItems: product.AvailableItems,
+			}),
+			Sold: product.Sold,
+		})
+	}
+	writeServerResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+func TestTopProductsHandler(t *testing.T) {
+	mockQueries := &productMockQueries{}
+	handler := &ProductHandler{Queries: mockQueries}
+
+	var received database.ListTopProductsParams
+	mockQueries.ListTopProductsFunc = func(ctx context.Context, params database.ListTopProductsParams) ([]database.ListTopProductsRow, error) {
+		received = params
+		return []database.ListTopProductsRow{
+			{ID: 2, Name: "Product 2", Price: "5.00", AvailableItems: 10, Sold: "12.5"},
+			{ID: 1, Name: "Product 1", Price: "7.00", AvailableItems: 3, Sold: "4"},
+		}, nil
+	}
+
+	t.Run("GET products/top - Default limit", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"/top", nil)
+		w := httptest.NewRecorder()
+
+		handler.TopProductsHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if received.MaxEntries != config.DefaultTopProductsLimit || received.IncludeUnsold {
+			t.Errorf("unexpected query params: %+v", received)
+		}
+
+		var products []topProductResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &products); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if len(products) != 2 || products[0].ID != 2 || products[0].Sold != "12.5" {
+			t.Errorf("unexpected top products: %+v", products)
+		}
+	})
+
+	t.Run("GET products/top - Including unsold", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"/top?limit=3&include_unsold=true", nil)
+		w := httptest.NewRecorder()
+
+		handler.TopProductsHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if received.MaxEntries != 3 || !received.IncludeUnsold {
+			t.Errorf("unexpected query params: %+v", received)
+		}
+	})
+
+	for _, limit := range []string{"0", "101", "ten"} {
+		t.Run("GET products/top - Invalid limit "+limit, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, config.ProductsApiPrefix+"/top?limit="+limit, nil)
+			w := httptest.NewRecorder()
+
+			handler.TopProductsHandler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+type validatePriceRequest struct {
+	Price string `json:"price"`
+}
+type validatePriceResponse struct {
+	Valid  bool   `json:"valid"`
+	Reason string `json:"reason,omitempty"`
+	// Normalized is the price the way it would be stored, e.g. "19.90" for "19.9", and null for an invalid price
+	Normalized *string `json:"normalized"`
+}
+
+// ValidatePriceHandler checks a price with the rules of the product creation without touching the database, so
+// the client forms can validate the prices as they are typed
+func (h *ProductHandler) ValidatePriceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		writeAllowedMethods(w, http.MethodPost)
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	// POST /products/validate-price
+	var request validatePriceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeServerParseError(w, err)
+		return
+	}
+
+	if msg := h.validatePrice(request.Price); msg != "" {
+		writeServerResponse(w, http.StatusOK, validatePriceResponse{Valid: false, Reason: msg})
+		return
+	}
+	d, _ := utils.ParseDecimal(request.Price)
+	normalized := d.Value.FloatString(config.MaxPriceFractionDigits)
+	writeServerResponse(w, http.StatusOK, validatePriceResponse{Valid: true, Normalized: &normalized})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+)
+
+func TestValidatePriceHandler(t *testing.T) {
+	// No queries are set, the validation must not touch the database
+	handler := &ProductHandler{Queries: &productMockQueries{}}
+
+	tests := []struct {
+		name       string
+		body       string
+		valid      bool
+		reason     string
+		normalized string
+	}{
+		{name: "Valid", body: `{"price":"19.9"}`, valid: true, normalized: "19.90"},
+		{name: "Valid integer", body: `{"price":"+007"}`, valid: true, normalized: "7.00"},
+		{name: "Too precise", body: `{"price":"19.999"}`, reason: "price must have at most 2 decimal places"},
+		{name: "Negative", body: `{"price":"-1.00"}`, reason: "price should be a positive number"},
+		{name: "Non-numeric", body: `{"price":"12abc"}`, reason: "Invalid price"},
+		{name: "Missing", body: `{}`, reason: "Product price is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, config.ProductsApiPrefix+"/validate-price", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.ValidatePriceHandler(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+			var response validatePriceResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if response.Valid != tt.valid || response.Reason != tt.reason {
+				t.Errorf("expected valid %v with reason %q, got %v with %q", tt.valid, tt.reason, response.Valid, response.Reason)
+			}
+			if tt.valid {
+				if response.Normalized == nil || *response.Normalized != tt.normalized {
+					t.Errorf("expected normalized price %s, got %v", tt.normalized, response.Normalized)
+				}
+			} else if response.Normalized != nil {
+				t.Errorf("expected a null normalized price, got %s", *response.Normalized)
+			}
+		})
+	}
+
+	t.Run("Price as a number", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, config.ProductsApiPrefix+"/validate-price", strings.NewReader(`{"price":19.99}`))
+		w := httptest.NewRecorder()
+
+		handler.ValidatePriceHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseBoolParam parses an optional boolean query parameter, which is false when absent
+func parseBoolParam(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("Invalid %s value %q, expected true or false", name, value)
+	}
+	return b, nil
+}
+
+// parseSortParam parses an optional ?sort= parameter, a field name optionally prefixed with "-" for the
+// descending order. It returns an empty string when absent
+func parseSortParam(r *http.Request, allowed []string) (string, error) {
+	value := r.URL.Query().Get("sort")
+	if value == "" {
+		return "", nil
+	}
+	if !slices.Contains(allowed, strings.TrimPrefix(value, "-")) {
+		return "", fmt.Errorf("Invalid sort value %q, the supported fields are: %s", value, strings.Join(allowed, ","))
+	}
+	return value, nil
+}
+
+// parseExpandParam parses an optional comma-separated ?expand= list of the related resources to include inline,
+// e.g. ?expand=items. It returns nil when absent
+func parseExpandParam(r *http.Request, allowed []string) ([]string, error) {
+	value := r.URL.Query().Get("expand")
+	if value == "" {
+		return nil, nil
+	}
+	var expand []string
+	for item := range strings.SplitSeq(value, ",") {
+		item = strings.TrimSpace(item)
+		if !slices.Contains(allowed, item) {
+			return nil, fmt.Errorf("Invalid expand value %q, the supported values are: %s", value, strings.Join(allowed, ","))
+		}
+		expand = append(expand, item)
+	}
+	return expand, nil
+}
+
+// parseIDsParam parses an optional comma-separated list of ids, e.g. ?ids=1,2,3. It returns nil when absent
+func parseIDsParam(r *http.Request, name string) ([]int32, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	var ids []int32
+	for item := range strings.SplitSeq(value, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(item), 10, 32)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("Invalid %s value %q, expected a comma-separated list of ids", name, value)
+		}
+		ids = append(ids, int32(id))
+	}
+	return ids, nil
+}
+
+// parseTimeParam parses an optional RFC 3339 timestamp, e.g. ?modified_since=2025-01-01T00:00:00Z. It returns the
+// zero time when absent
+func parseTimeParam(r *http.Request, name string) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid %s value %q, expected an RFC 3339 timestamp like 2025-01-01T00:00:00Z", name, value)
+	}
+	return t, nil
+}
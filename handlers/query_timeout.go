@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+// errQueryTimeout is returned by withQueryTimeout for a query cancelled by its own deadline
+var errQueryTimeout = errors.New("query timed out")
+
+// withQueryTimeout runs an aggregate query with a deadline of its own, shorter than the statement timeout of every
+// query. The cancelled queries are logged with how long they ran. A zero timeout runs the query as it is
+func withQueryTimeout[T any](ctx context.Context, timeout time.Duration, name string, query func(ctx context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return query(ctx)
+	}
+	queryCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	result, err := query(queryCtx)
+	// The driver may report the cancellation in its own words, the contexts tell whose deadline it was
+	if err != nil && ctx.Err() == nil && errors.Is(queryCtx.Err(), context.DeadlineExceeded) {
+		log.Printf("Slow query %s cancelled after %v", name, time.Since(start).Round(time.Millisecond))
+		return result, errQueryTimeout
+	}
+	return result, err
+}
+
+// writeQueryError reports a failed query, telling the ones that timed out from the internal errors. The 503 of
+// a timeout comes with retryAfter as Retry-After when it's not zero
+func writeQueryError(w http.ResponseWriter, err error, retryAfter time.Duration) {
+	if errors.Is(err, errQueryTimeout) {
+		utils.SetRetryAfter(w, retryAfter)
+		writeError(w, http.StatusServiceUnavailable, config.ErrorCodeQueryTimeout, "The query took too long, try fewer invoices or a shorter period")
+		return
+	}
+	writeInternalServerError(w, err)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+const (
+	dependencyOK       = "ok"
+	dependencyDegraded = "degraded"
+)
+
+// DependencyCheck reports whether a dependency of the service is usable. A failing critical dependency makes
+// the service not ready, a failing non-critical one is only reported
+type DependencyCheck struct {
+	Name     string
+	Critical bool
+	Check    func(ctx context.Context) error
+	// Timeout overrides the ReadinessHandler one for this check, e.g. for a slow remote endpoint
+	Timeout time.Duration
+}
+
+type ReadinessHandler struct {
+	Checks []DependencyCheck
+	// Timeout limits every check, so a hanging dependency can't hang the probe
+	Timeout time.Duration
+	// RetryAfter is sent with the 503 when it's not zero
+	RetryAfter time.Duration
+}
+
+// ReadinessHandler runs all the dependency checks concurrently, so the probe takes as long as the slowest of them,
+// and reports the status of each of them, e.g. {"db":"ok"}. The status code is 200 if all the critical dependencies are healthy and 503 otherwise
+func (h *ReadinessHandler) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	statuses := make(map[string]string, len(h.Checks))
+	ready := true
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.Checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			timeout := h.Timeout
+			if check.Timeout > 0 {
+				timeout = check.Timeout
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			err := check.Check(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				statuses[check.Name] = dependencyDegraded
+				ready = ready && !check.Critical
+			} else {
+				statuses[check.Name] = dependencyOK
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !ready {
+		utils.SetRetryAfter(w, h.RetryAfter)
+		writeServerResponse(w, http.StatusServiceUnavailable, statuses)
+		return
+	}
+	writeServerResponse(w, http.StatusOK, statuses)
+}
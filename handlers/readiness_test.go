@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	healthy := func(ctx context.Context) error { return nil }
+	failing := func(ctx context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name     string
+		checks   []DependencyCheck
+		expected int
+		statuses map[string]string
+	}{
+		{
+			name:     "All healthy",
+			checks:   []DependencyCheck{{Name: "db", Critical: true, Check: healthy}, {Name: "webhook", Check: healthy}},
+			expected: http.StatusOK,
+			statuses: map[string]string{"db": "ok", "webhook": "ok"},
+		},
+		{
+			name:     "Non-critical degraded",
+			checks:   []DependencyCheck{{Name: "db", Critical: true, Check: healthy}, {Name: "webhook", Check: failing}},
+			expected: http.StatusOK,
+			statuses: map[string]string{"db": "ok", "webhook": "degraded"},
+		},
+		{
+			name:     "Critical degraded",
+			checks:   []DependencyCheck{{Name: "db", Critical: true, Check: failing}, {Name: "webhook", Check: healthy}},
+			expected: http.StatusServiceUnavailable,
+			statuses: map[string]string{"db": "degraded", "webhook": "ok"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &ReadinessHandler{Checks: tt.checks, Timeout: time.Second, RetryAfter: 5 * time.Second}
+			req := httptest.NewRequest(http.MethodGet, config.ReadinessPath, nil)
+			w := httptest.NewRecorder()
+
+			handler.ReadinessHandler(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status code %d, got %d", tt.expected, w.Code)
+			}
+			expectedRetryAfter := ""
+			if tt.expected == http.StatusServiceUnavailable {
+				expectedRetryAfter = "5"
+			}
+			if retryAfter := w.Header().Get("Retry-After"); retryAfter != expectedRetryAfter {
+				t.Errorf("expected Retry-After %q, got %q", expectedRetryAfter, retryAfter)
+			}
+
+			var statuses map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &statuses); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			for name, status := range tt.statuses {
+				if statuses[name] != status {
+					t.Errorf("expected %s to be %q, got %q", name, status, statuses[name])
+				}
+			}
+		})
+	}
+}
+
+func TestReadinessHandlerLatency(t *testing.T) {
+	sleeping := func(d time.Duration) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			select {
+			case <-time.After(d):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	handler := &ReadinessHandler{
+		Checks: []DependencyCheck{
+			{Name: "db", Critical: true, Check: sleeping(100 * time.Millisecond)},
+			{Name: "replica", Check: sleeping(100 * time.Millisecond)},
+			{Name: "webhook", Check: sleeping(100 * time.Millisecond)},
+			// Gives up on its own timeout long before the handler one
+			{Name: "search", Check: sleeping(time.Minute), Timeout: 50 * time.Millisecond},
+		},
+		Timeout: time.Second,
+	}
+	req := httptest.NewRequest(http.MethodGet, config.ReadinessPath, nil)
+	w := httptest.NewRecorder()
+
+	start := time.Now()
+	handler.ReadinessHandler(w, req)
+	elapsed := time.Since(start)
+
+	// Run one after another, the checks would take 350ms
+	if elapsed >= 250*time.Millisecond {
+		t.Errorf("expected the checks to run concurrently, the probe took %v", elapsed)
+	}
+
+	var statuses map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if statuses["db"] != "ok" || statuses["replica"] != "ok" || statuses["webhook"] != "ok" || statuses["search"] != "degraded" {
+		t.Errorf("unexpected statuses: %v", statuses)
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+// salesReportGranularities lists the ?granularity= values of GET /reports/sales, they're date_trunc fields
+var salesReportGranularities = []string{"day", "week", "month"}
+
+type ReportQueries interface {
+	ListSalesByPeriod(ctx context.Context, params database.ListSalesByPeriodParams) ([]database.ListSalesByPeriodRow, error)
+}
+
+type ReportHandler struct {
+	Queries ReportQueries
+	// Timeout bounds the report queries, zero disables it
+	Timeout time.Duration
+	// RetryAfter is sent with the 503 of a timed out report when it's not zero
+	RetryAfter time.Duration
+}
+
+type salesPeriodResponse struct {
+	// Period is the start of the day, the week (on Monday) or the month in UTC
+	Period       Timestamp `json:"period"`
+	InvoiceCount int64     `json:"invoice_count"`
+	Total        string    `json:"total"`
+}
+
+// SalesHandler sums up the invoices dated from ?from= up to, but not including, ?to= per day, week or month
+func (h *ReportHandler) SalesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		writeAllowedMethods(w, http.MethodGet)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	// GET /reports/sales
+	params := database.ListSalesByPeriodParams{Granularity: r.URL.Query().Get("granularity")}
+	if params.Granularity == "" {
+		params.Granularity = "day"
+	}
+	if !slices.Contains(salesReportGranularities, params.Granularity) {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, "granularity must be one of: "+strings.Join(salesReportGranularities, ","))
+		return
+	}
+	var err error
+	if params.FromDate, err = parseTimeParam(r, "from"); err != nil {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+		return
+	}
+	if params.ToDate, err = parseTimeParam(r, "to"); err != nil {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, err.Error())
+		return
+	}
+	if params.FromDate.IsZero() || params.ToDate.IsZero() {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, "from and to are required")
+		return
+	}
+	if !params.FromDate.Before(params.ToDate) {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeInvalidParameter, "from must be before to")
+		return
+	}
+
+	periods, err := withQueryTimeout(r.Context(), h.Timeout, "ListSalesByPeriod", func(ctx context.Context) ([]database.ListSalesByPeriodRow, error) {
+		return h.Queries.ListSalesByPeriod(ctx, params)
+	})
+	if err != nil {
+		writeQueryError(w, err, h.RetryAfter)
+		return
+	}
+	response := []salesPeriodResponse{}
+	for _, period := range periods {
+		response = append(response, salesPeriodResponse{
+			Period:       Timestamp(period.Period.UTC()),
+			InvoiceCount: period.InvoiceCount,
+			Total:        period.Total,
+		})
+	}
+	writeServerResponse(w, http.StatusOK, response)
+}
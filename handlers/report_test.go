@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+type reportMockQueries struct {
+	ListSalesByPeriodFunc func(ctx context.Context, params database.ListSalesByPeriodParams) ([]database.ListSalesByPeriodRow, error)
+}
+
+func (m *reportMockQueries) ListSalesByPeriod(ctx context.Context, params database.ListSalesByPeriodParams) ([]database.ListSalesByPeriodRow, error) {
+	return m.ListSalesByPeriodFunc(ctx, params)
+}
+
+func TestSalesReport(t *testing.T) {
+	mockQueries := &reportMockQueries{}
+	handler := &ReportHandler{Queries: mockQueries}
+
+	// The sales of a small dataset of invoices, already summed up per month
+	sales := []database.ListSalesByPeriodRow{
+		{Period: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), InvoiceCount: 2, Total: "150.00"},
+		{Period: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), InvoiceCount: 1, Total: "0.00"},
+	}
+	var gotParams database.ListSalesByPeriodParams
+	mockQueries.ListSalesByPeriodFunc = func(ctx context.Context, params database.ListSalesByPeriodParams) ([]database.ListSalesByPeriodRow, error) {
+		gotParams = params
+		var periods []database.ListSalesByPeriodRow
+		for _, period := range sales {
+			if !period.Period.Before(params.FromDate) && period.Period.Before(params.ToDate) {
+				periods = append(periods, period)
+			}
+		}
+		return periods, nil
+	}
+
+	t.Run("GET reports/sales - Monthly", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.ReportsApiPrefix+"/sales?from=2025-01-01T00:00:00Z&to=2025-04-01T00:00:00Z&granularity=month", nil)
+		w := httptest.NewRecorder()
+
+		handler.SalesHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if gotParams.Granularity != "month" || !gotParams.ToDate.Equal(time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("unexpected query parameters: %+v", gotParams)
+		}
+		var response []map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if len(response) != 2 {
+			t.Fatalf("expected 2 periods, got %d", len(response))
+		}
+		if response[0]["period"] != "2025-01-01T00:00:00Z" || response[0]["invoice_count"] != float64(2) || response[0]["total"] != "150.00" {
+			t.Errorf("unexpected first period: %v", response[0])
+		}
+	})
+
+	t.Run("GET reports/sales - No sales", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, config.ReportsApiPrefix+"/sales?from=2024-01-01T00:00:00Z&to=2024-02-01T00:00:00Z", nil)
+		w := httptest.NewRecorder()
+
+		handler.SalesHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if gotParams.Granularity != "day" {
+			t.Errorf("expected the day granularity by default, got %q", gotParams.Granularity)
+		}
+		if body := w.Body.String(); body != "[]\n" {
+			t.Errorf("expected an empty array, got %s", body)
+		}
+	})
+
+	for name, query := range map[string]string{
+		"Invalid granularity": "?from=2025-01-01T00:00:00Z&to=2025-02-01T00:00:00Z&granularity=year",
+		"Missing to":          "?from=2025-01-01T00:00:00Z",
+		"Invalid from":        "?from=yesterday&to=2025-02-01T00:00:00Z",
+		"Reversed range":      "?from=2025-02-01T00:00:00Z&to=2025-01-01T00:00:00Z",
+	} {
+		t.Run("GET reports/sales - "+name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, config.ReportsApiPrefix+"/sales"+query, nil)
+			w := httptest.NewRecorder()
+
+			handler.SalesHandler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+}
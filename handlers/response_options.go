@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+)
+
+// ResponseOptions are the ways the responses can be adjusted for the clients. They're carried by the response
+// writer (see WithResponseOptions), so every handler and test can use its own. The zero value is the default
+// format
+type ResponseOptions struct {
+	// IDsAsStrings serializes the ids as JSON strings, e.g. "id":"33", for the clients that can't represent large
+	// integers exactly
+	IDsAsStrings bool
+	// InvoiceDateFormat is how the invoice dates are serialized, one of the config.DateFormat* values. Empty
+	// means RFC 3339
+	InvoiceDateFormat string
+	// DeleteConfirmations makes the successful deletes respond with 200 and a small JSON body instead of 204, for
+	// the clients that can't handle a 204
+	DeleteConfirmations bool
+	// OmitNulls drops the null fields from the response objects instead of sending them as null, e.g.
+	// the description of a product without one, for the clients that tell a missing field from a null one
+	OmitNulls bool
+}
+
+// WithResponseOptions serves next with the given response options. It has to wrap the handlers directly, the
+// options are looked up on the response writer they're given
+func WithResponseOptions(next http.Handler, options ResponseOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&responseOptionsWriter{ResponseWriter: w, options: options}, r)
+	})
+}
+
+type responseOptionsWriter struct {
+	http.ResponseWriter
+	options ResponseOptions
+}
+
+// Unwrap lets http.ResponseController reach the flushing of the underlying writer
+func (w *responseOptionsWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// responseOptionsOf returns the options the response is written with, the default ones without WithResponseOptions
+func responseOptionsOf(w http.ResponseWriter) ResponseOptions {
+	if ow, ok := w.(*responseOptionsWriter); ok {
+		return ow.options
+	}
+	return ResponseOptions{}
+}
+
+// marshal encodes a response with the options
+func (o ResponseOptions) marshal(v any) ([]byte, error) {
+	var data []byte
+	var err error
+	if o.defaultValues() {
+		data, err = json.Marshal(v)
+	} else {
+		data, err = json.Marshal(o.convert(reflect.ValueOf(v)))
+	}
+	if err == nil && o.OmitNulls {
+		data, err = omitNullFields(data)
+	}
+	return data, err
+}
+
+// defaultValues tells that the ids and the timestamps are encoded the way their own MarshalJSON does it
+func (o ResponseOptions) defaultValues() bool {
+	return !o.IDsAsStrings && (o.InvoiceDateFormat == "" || o.InvoiceDateFormat == config.DateFormatRFC3339)
+}
+
+var (
+	idType        = reflect.TypeFor[ID]()
+	timestampType = reflect.TypeFor[Timestamp]()
+	marshalerType = reflect.TypeFor[json.Marshaler]()
+)
+
+// convert copies a response into plain values encoding like the response itself, except for the ids and
+// the timestamps, which are encoded with the options. The structs become jsonObjects, keeping the order of
+// the fields and following the json tags
+func (o ResponseOptions) convert(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	// Before the types, a nil *Timestamp is encoded as null
+	if v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		return o.convert(v.Elem())
+	}
+	switch v.Type() {
+	case idType:
+		if o.IDsAsStrings {
+			return strconv.FormatInt(v.Int(), 10)
+		}
+		return v.Int()
+	case timestampType:
+		return o.convertTimestamp(time.Time(v.Interface().(Timestamp)))
+	}
+	if v.Type().Implements(marshalerType) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		fallthrough
+	case reflect.Array:
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = o.convert(v.Index(i))
+		}
+		return items
+	case reflect.Map:
+		if v.IsNil() || v.Type().Key().Kind() != reflect.String {
+			return v.Interface()
+		}
+		entries := make(map[string]any, v.Len())
+		for key, value := range v.Seq2() {
+			entries[key.String()] = o.convert(value)
+		}
+		return entries
+	case reflect.Struct:
+		var object jsonObject
+		o.appendFields(&object, v, 0)
+		return object
+	default:
+		return v.Interface()
+	}
+}
+
+func (o ResponseOptions) convertTimestamp(t time.Time) any {
+	switch o.InvoiceDateFormat {
+	case config.DateFormatDate:
+		// The date in UTC, a date read with another time zone would otherwise shift by a day
+		return t.UTC().Format(time.DateOnly)
+	case config.DateFormatUnix:
+		return t.Unix()
+	default:
+		return t
+	}
+}
+
+// appendFields appends the encoded fields of a struct, inlining the embedded structs the way encoding/json does
+func (o ResponseOptions) appendFields(object *jsonObject, v reflect.Value, depth int) {
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && options == "" {
+			continue
+		}
+		value := v.Field(i)
+		if field.Anonymous && name == "" {
+			if value.Kind() == reflect.Pointer {
+				if value.IsNil() {
+					continue
+				}
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct {
+				o.appendFields(object, value, depth+1)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.Contains(options, "omitempty") && isEmptyValue(value) {
+			continue
+		}
+		object.set(name, o.convert(value), depth)
+	}
+}
+
+// isEmptyValue is what omitempty leaves out
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// jsonObject is a JSON object with its members in order
+type jsonObject []jsonMember
+type jsonMember struct {
+	name  string
+	value any
+	depth int
+}
+
+// set adds a member. Like with encoding/json, a field of the struct itself wins over the ones of the structs
+// it embeds, and takes its place in the order
+func (object *jsonObject) set(name string, value any, depth int) {
+	for i, member := range *object {
+		if member.name != name {
+			continue
+		}
+		if depth >= member.depth {
+			return
+		}
+		*object = slices.Delete(*object, i, i+1)
+		break
+	}
+	*object = append(*object, jsonMember{name: name, value: value, depth: depth})
+}
+
+// get returns the value of a member, nil when there's none
+func (object jsonObject) get(name string) any {
+	for _, member := range object {
+		if member.name == name {
+			return member.value
+		}
+	}
+	return nil
+}
+
+func (object jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, member := range object {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(member.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(member.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
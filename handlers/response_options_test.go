@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+)
+
+func TestResponseOptions(t *testing.T) {
+	t.Parallel()
+	updatedAt := time.Date(2025, time.March, 6, 15, 4, 5, 0, time.UTC)
+	lastInvoiceDate := Timestamp(updatedAt)
+	two := "2"
+
+	t.Run("Converted values encode like the responses", func(t *testing.T) {
+		for _, response := range []any{
+			modifiedProductResponse{productResponse: productResponse{ID: 1, Name: "Lamp", Price: "9.99", StockStatus: stockStatusInStock}, UpdatedAt: updatedAt},
+			customerActivityResponse{customerResponse: customerResponse{ID: 3, FirstName: "Jack", LastName: "Poe"}, LastInvoiceDate: &lastInvoiceDate, TotalSpend: "1.00"},
+			[]invoiceDiffLineResponse{{ProductID: 1, Name: "Lamp", Price: "9.99", Status: invoiceDiffAdded, ToCount: &two, SumDelta: "19.98"}},
+			[]invoiceValidationError{{Field: "customer_id", Reason: "missing"}, {Line: 2, Reason: "stock"}},
+			map[string][]*string{"counts": {nil, &two}},
+			[]productResponse(nil),
+		} {
+			expected, _ := json.Marshal(response)
+			got, err := json.Marshal(ResponseOptions{}.convert(reflect.ValueOf(response)))
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+			if string(got) != string(expected) {
+				t.Errorf("expected %s, got %s", expected, got)
+			}
+		}
+	})
+
+	t.Run("Ids and dates of the embedded responses", func(t *testing.T) {
+		options := ResponseOptions{IDsAsStrings: true, InvoiceDateFormat: config.DateFormatUnix}
+		data, err := options.marshal(modifiedInvoiceResponse{invoiceResponse: invoiceResponse{ID: 4, InvoiceNumber: "INV-4", InvoiceDate: Timestamp(updatedAt), CustomerID: 3}, UpdatedAt: updatedAt})
+		if err != nil {
+			t.Fatalf("failed to marshal: %v", err)
+		}
+		expected := `{"id":"4","invoice_number":"INV-4","invoice_date":1741273445,"customer_id":"3","updated_at":"2025-03-06T15:04:05Z"}`
+		if string(data) != expected {
+			t.Errorf("expected %s, got %s", expected, data)
+		}
+	})
+
+	t.Run("Selected fields", func(t *testing.T) {
+		selected := selectFields(ResponseOptions{IDsAsStrings: true}, []productResponse{{ID: 5, Name: "Lamp"}}, []string{"id", "description"})
+		data, _ := json.Marshal(selected)
+		if expected := `[{"description":null,"id":"5"}]`; string(data) != expected {
+			t.Errorf("expected %s, got %s", expected, data)
+		}
+	})
+
+	t.Run("Carried by the response writer", func(t *testing.T) {
+		handler := WithResponseOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeServerResponse(w, http.StatusOK, deletedResponse{Deleted: true, ID: 9})
+		}), ResponseOptions{IDsAsStrings: true})
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if body := strings.TrimSpace(w.Body.String()); body != `{"deleted":true,"id":"9"}` {
+			t.Errorf("unexpected response: %s", body)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+)
+
+// Route is an endpoint of the API. The routes with path parameters share the Pattern they're registered
+// under with http.ServeMux, e.g. all the /invoices/{id}/... routes are served by the /invoices/ pattern
+type Route struct {
+	Path    string
+	Methods []string
+	// Pattern defaults to Path
+	Pattern string
+	Handler http.Handler
+}
+
+type routeResponse struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// RegisterRoutes registers the handler of every route with mux, once per pattern
+func RegisterRoutes(mux *http.ServeMux, routes []Route) {
+	var registered []string
+	for _, route := range routes {
+		pattern := route.Pattern
+		if pattern == "" {
+			pattern = route.Path
+		}
+		if slices.Contains(registered, pattern) {
+			continue
+		}
+		mux.Handle(pattern, route.Handler)
+		registered = append(registered, pattern)
+	}
+}
+
+// NotFoundHandler answers the requests to the paths under the API prefix that no route matches, so they get
+// a JSON error like everything else in the API rather than the plain-text net/http one
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, config.ErrorCodeNotFound, "Not found")
+}
+
+// UnsupportedVersionHandler answers the requests under the API root for the versions the service doesn't have,
+// e.g. /api/v2/products, pointing the client to the supported one
+func UnsupportedVersionHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, config.ErrorCodeUnsupportedVersion, fmt.Sprintf("Unsupported API version, use %s", config.ApiPrefix))
+}
+
+type rootResponse struct {
+	Name        string   `json:"name"`
+	Version     string   `json:"version"`
+	APIVersions []string `json:"api_versions"`
+}
+
+// RootHandler describes the service, it's registered for / exactly
+type RootHandler struct {
+	Name    string
+	Version string
+}
+
+func (h *RootHandler) RootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		writeAllowedMethods(w, http.MethodGet)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	// GET /
+	writeServerResponse(w, http.StatusOK, rootResponse{Name: h.Name, Version: h.Version, APIVersions: []string{config.ApiVersion}})
+}
+
+type RoutesHandler struct {
+	Routes []Route
+}
+
+// RoutesHandler lists every method and path the server handles, in the order of the registration
+func (h *RoutesHandler) RoutesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		writeAllowedMethods(w, http.MethodGet)
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	// GET /routes
+	response := []routeResponse{}
+	for _, route := range h.Routes {
+		for _, method := range route.Methods {
+			response = append(response, routeResponse{Method: method, Path: route.Path})
+		}
+	}
+	writeServerResponse(w, http.StatusOK, response)
+}
@@ -1,28 +1,183 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
 )
 
+// writeServerResponse marshals the whole response before writing anything, so that a value failing to marshal
+// results in a clean 500 rather than in a status already sent with a truncated body
 func writeServerResponse[T any](w http.ResponseWriter, statusCode int, data T) {
+	body, err := responseOptionsOf(w).marshal(data)
+	if err != nil {
+		writeInternalServerError(w, fmt.Errorf("encoding server response: %w", err))
+		return
+	}
 	w.Header().Set("Content-Type", config.ContentTypeJSON)
 	w.WriteHeader(statusCode)
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		log.Println("Error encoding server reponse: ", err)
-		http.Error(w, config.InternalServerErrorMsg, http.StatusInternalServerError)
+	if _, err := w.Write(append(body, '\n')); err != nil {
+		log.Println("Error writing server response: ", err)
+	}
+}
+
+// omitNullFields removes the null members of all the objects in a JSON document, keeping the order of the others.
+// The nulls in arrays are kept as they are, removing them would shift the positions
+func omitNullFields(data json.RawMessage) (json.RawMessage, error) {
+	if len(data) == 0 || data[0] != '{' && data[0] != '[' {
+		return data, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	opening, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(data[0])
+	for dec.More() {
+		var key json.Token
+		if opening == json.Delim('{') {
+			if key, err = dec.Token(); err != nil {
+				return nil, err
+			}
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if key != nil && string(value) == "null" {
+			continue
+		}
+		if value, err = omitNullFields(value); err != nil {
+			return nil, err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		if key != nil {
+			encodedKey, _ := json.Marshal(key)
+			buf.Write(encodedKey)
+			buf.WriteByte(':')
+		}
+		buf.Write(value)
+	}
+	if data[0] == '{' {
+		buf.WriteByte('}')
+	} else {
+		buf.WriteByte(']')
+	}
+	return buf.Bytes(), nil
+}
+
+// writeCreatedResponse responds with 201 and the Location of the created resource. The representation of the
+// resource is omitted if the client asked for that with "Prefer: return=minimal"
+func writeCreatedResponse[T any](w http.ResponseWriter, r *http.Request, location string, data T) {
+	w.Header().Set("Location", location)
+	if prefersMinimalReturn(r) {
+		w.Header().Set("Preference-Applied", "return=minimal")
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	writeServerResponse(w, http.StatusCreated, data)
+}
+
+// prefersMinimalReturn parses the Prefer header (RFC 7240), return=representation is the default
+func prefersMinimalReturn(r *http.Request) bool {
+	for _, header := range r.Header.Values("Prefer") {
+		for preference := range strings.SplitSeq(header, ",") {
+			preference, _, _ = strings.Cut(preference, ";")
+			if strings.EqualFold(strings.Join(strings.Fields(preference), ""), "return=minimal") {
+				return true
+			}
+		}
 	}
+	return false
+}
+
+type deletedResponse struct {
+	Deleted bool `json:"deleted"`
+	ID      ID   `json:"id"`
+}
+type deletedInvoiceItemResponse struct {
+	Deleted   bool `json:"deleted"`
+	InvoiceID ID   `json:"invoice_id"`
+	ProductID ID   `json:"product_id"`
+}
+
+// writeDeletedResponse responds to a successful delete, with 204 unless ResponseOptions.DeleteConfirmations is set
+func writeDeletedResponse[T any](w http.ResponseWriter, confirmation T) {
+	if !responseOptionsOf(w).DeleteConfirmations {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeServerResponse(w, http.StatusOK, confirmation)
 }
 
 func writeInternalServerError(w http.ResponseWriter, err error) {
 	log.Println(err)
-	http.Error(w, config.InternalServerErrorMsg, http.StatusInternalServerError)
+	writeError(w, http.StatusInternalServerError, config.ErrorCodeInternal, config.InternalServerErrorMsg)
 }
 
 func writeServerParseError(w http.ResponseWriter, err error) {
 	log.Println(err)
-	http.Error(w, "An error occurred while parsing the input JSON", http.StatusBadRequest)
+
+	// The integers too big for their int32 fields are the one decoding mistake worth explaining
+	var typeErr *json.UnmarshalTypeError
+	var idErr *idRangeError
+	if errors.As(err, &typeErr) && typeErr.Type.Kind() == reflect.Int32 && isInteger(strings.TrimPrefix(typeErr.Value, "number ")) {
+		utils.WriteError(w, http.StatusBadRequest, errorResponse{
+			Error: fmt.Sprintf("%s is out of range, it must be between %d and %d", typeErr.Field, math.MinInt32, math.MaxInt32),
+			Code:  config.ErrorCodeMalformedJSON,
+			Field: typeErr.Field,
+		})
+		return
+	} else if errors.As(err, &idErr) {
+		writeError(w, http.StatusBadRequest, config.ErrorCodeMalformedJSON, idErr.Error())
+		return
+	}
+	writeError(w, http.StatusBadRequest, config.ErrorCodeMalformedJSON, "An error occurred while parsing the input JSON")
+}
+
+// isInteger tells an integer, however big, from a fraction or an exponent
+func isInteger(number string) bool {
+	_, err := strconv.ParseInt(number, 10, 64)
+	return err == nil || errors.Is(err, strconv.ErrRange)
+}
+
+// writeAllowedMethods answers a plain OPTIONS request, the CORS preflights are answered by the middleware
+func writeAllowedMethods(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", allowHeader(methods))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", allowHeader(methods))
+	writeError(w, http.StatusMethodNotAllowed, config.ErrorCodeMethodNotAllowed, config.MethodNotAllowedMsg)
+}
+
+func allowHeader(methods []string) string {
+	return strings.Join(append(slices.Clip(methods), http.MethodOptions), ", ")
+}
+
+type errorResponse = utils.ErrorResponse
+
+func writeError(w http.ResponseWriter, statusCode int, code, message string) {
+	utils.WriteError(w, statusCode, errorResponse{Error: message, Code: code})
+}
+
+// writeValidationError reports well-formed input that breaks a business rule, e.g. an empty name
+func writeValidationError(w http.ResponseWriter, code, field, message string) {
+	utils.WriteError(w, http.StatusUnprocessableEntity, errorResponse{Error: message, Code: code, Field: field})
 }
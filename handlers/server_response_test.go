@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+func TestInt32Overflow(t *testing.T) {
+	productQueries := &productMockQueries{
+		CreateProductFunc: func(ctx context.Context, params database.CreateProductParams) (database.Product, error) {
+			return database.Product{ID: 1, Name: params.Name, Price: params.Price, AvailableItems: params.AvailableItems}, nil
+		},
+		GetProductFunc: func(ctx context.Context, id int32) (database.Product, error) {
+			return database.Product{ID: id, Name: "Product", Price: "1.00"}, nil
+		},
+	}
+	productHandler := &ProductHandler{Queries: productQueries}
+	invoiceHandler := &InvoiceHandler{Queries: &invoiceMockQueries{}}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		handle   http.HandlerFunc
+		expected int
+		message  string
+	}{
+		{name: "available_items at the maximum", method: http.MethodPost, path: config.ProductsApiPrefix, body: `{"name":"Product","price":"1.00","available_items":2147483647}`, handle: productHandler.ProductsHandler, expected: http.StatusCreated},
+		{name: "available_items just over the maximum", method: http.MethodPost, path: config.ProductsApiPrefix, body: `{"name":"Product","price":"1.00","available_items":2147483648}`, handle: productHandler.ProductsHandler, expected: http.StatusBadRequest, message: "available_items is out of range"},
+		{name: "customer_id just over the maximum", method: http.MethodPost, path: config.InvoicesApiPrefix, body: `{"invoice_number":"INV-001","customer_id":2147483648}`, handle: invoiceHandler.InvoicesHandler, expected: http.StatusBadRequest, message: "id 2147483648 is out of range"},
+		{name: "Path id just over the maximum", method: http.MethodGet, path: config.ProductsApiPrefix + "/2147483648", handle: productHandler.ProductHandler, expected: http.StatusBadRequest, message: "Invalid product ID"},
+		{name: "Path id at the maximum", method: http.MethodGet, path: config.ProductsApiPrefix + "/2147483647", handle: productHandler.ProductHandler, expected: http.StatusOK},
+		{name: "Invoice path id just over the maximum", method: http.MethodGet, path: config.InvoicesApiPrefix + "/2147483648/products", handle: invoiceHandler.InvoiceHandler, expected: http.StatusBadRequest, message: "Invalid invoice ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			tt.handle(w, req)
+
+			if w.Code != tt.expected {
+				t.Fatalf("expected status code %d, got %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+			if tt.message == "" {
+				return
+			}
+			var response errorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if !strings.HasPrefix(response.Error, tt.message) {
+				t.Errorf("expected the error to start with %q, got %q", tt.message, response.Error)
+			}
+		})
+	}
+}
+
+func TestDeleteConfirmations(t *testing.T) {
+	t.Parallel()
+	productQueries := &productMockQueries{
+		DeleteProductFunc: func(ctx context.Context, id int32) (string, error) { return "success", nil },
+	}
+	customerQueries := &customerMockQueries{
+		DeleteCustomerFunc: func(ctx context.Context, id int32) (string, error) { return "success", nil },
+	}
+	invoiceQueries := &invoiceMockQueries{
+		DeleteInvoiceFunc: func(ctx context.Context, id int32) (string, error) { return "success", nil },
+		DeleteProductFromInvoiceFunc: func(ctx context.Context, params database.DeleteProductFromInvoiceParams) (string, error) {
+			return "success", nil
+		},
+	}
+	productHandler := &ProductHandler{Queries: productQueries, Tx: productQueries.tx}
+	customerHandler := &CustomerHandler{Queries: customerQueries, Tx: customerQueries.tx}
+	invoiceHandler := &InvoiceHandler{Queries: invoiceQueries, Tx: invoiceQueries.tx}
+
+	tests := []struct {
+		name         string
+		path         string
+		handle       http.HandlerFunc
+		confirmation string
+	}{
+		{name: "Product", path: config.ProductsApiPrefix + "/5", handle: productHandler.ProductHandler, confirmation: `{"deleted":true,"id":5}`},
+		{name: "Customer", path: config.CustomersApiPrefix + "/6", handle: customerHandler.CustomerHandler, confirmation: `{"deleted":true,"id":6}`},
+		{name: "Invoice", path: config.InvoicesApiPrefix + "/7", handle: invoiceHandler.InvoiceHandler, confirmation: `{"deleted":true,"id":7}`},
+		{name: "Invoice item", path: config.InvoicesApiPrefix + "/7/products/5", handle: invoiceHandler.InvoiceHandler, confirmation: `{"deleted":true,"invoice_id":7,"product_id":5}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" - No content by default", func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handle(w, httptest.NewRequest(http.MethodDelete, tt.path, nil))
+
+			if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+				t.Errorf("expected an empty %d, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+			}
+		})
+
+		t.Run(tt.name+" - Confirmation", func(t *testing.T) {
+			handler := WithResponseOptions(tt.handle, ResponseOptions{DeleteConfirmations: true})
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, tt.path, nil))
+
+			if w.Code != http.StatusOK {
+				t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+			if body := strings.TrimSpace(w.Body.String()); body != tt.confirmation {
+				t.Errorf("expected %s, got %s", tt.confirmation, body)
+			}
+		})
+	}
+}
+
+func TestOmitNulls(t *testing.T) {
+	t.Parallel()
+	two := "2"
+	tests := []struct {
+		name     string
+		data     any
+		withNull string
+		omitted  string
+	}{
+		{
+			name:     "Product description",
+			data:     productResponse{ID: 1, Name: "Lamp", Price: "9.99", AvailableItems: 3, StockStatus: stockStatusLowStock},
+			withNull: `{"id":1,"name":"Lamp","description":null,"price":"9.99","available_items":3,"stock_status":"low_stock"}`,
+			omitted:  `{"id":1,"name":"Lamp","price":"9.99","available_items":3,"stock_status":"low_stock"}`,
+		},
+		{
+			name:     "Invoice diff counts",
+			data:     []invoiceDiffLineResponse{{ProductID: 1, Name: "Lamp", Price: "9.99", Status: invoiceDiffAdded, ToCount: &two, SumDelta: "19.98"}},
+			withNull: `[{"product_id":1,"name":"Lamp","price":"9.99","status":"added","from_count":null,"to_count":"2","sum_delta":"19.98"}]`,
+			omitted:  `[{"product_id":1,"name":"Lamp","price":"9.99","status":"added","to_count":"2","sum_delta":"19.98"}]`,
+		},
+		{
+			name:     "Customer last invoice date",
+			data:     customerActivityResponse{customerResponse: customerResponse{ID: 3, FirstName: "Jack", LastName: "Poe"}, TotalSpend: "0.00"},
+			withNull: `{"id":3,"first_name":"Jack","last_name":"Poe","last_invoice_date":null,"total_spend":"0.00"}`,
+			omitted:  `{"id":3,"first_name":"Jack","last_name":"Poe","total_spend":"0.00"}`,
+		},
+		{
+			name:     "Normalized price",
+			data:     validatePriceResponse{Reason: "Invalid price"},
+			withNull: `{"valid":false,"reason":"Invalid price","normalized":null}`,
+			omitted:  `{"valid":false,"reason":"Invalid price"}`,
+		},
+		{
+			name:     "Nulls in arrays are kept",
+			data:     map[string][]*string{"counts": {nil, &two}},
+			withNull: `{"counts":[null,"2"]}`,
+			omitted:  `{"counts":[null,"2"]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, omit := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s - omitted %v", tt.name, omit), func(t *testing.T) {
+				w := httptest.NewRecorder()
+				writeServerResponse(&responseOptionsWriter{ResponseWriter: w, options: ResponseOptions{OmitNulls: omit}}, http.StatusOK, tt.data)
+
+				expected := tt.withNull
+				if omit {
+					expected = tt.omitted
+				}
+				if body := strings.TrimSpace(w.Body.String()); body != expected {
+					t.Errorf("expected %s, got %s", expected, body)
+				}
+			})
+		}
+	}
+}
+
+// unmarshalableResponse can't be encoded, json.Marshal rejects channels
+type unmarshalableResponse struct {
+	ID      int           `json:"id"`
+	Updates chan struct{} `json:"updates"`
+}
+
+func TestUnmarshalableResponse(t *testing.T) {
+	t.Parallel()
+	for _, omit := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Omitted nulls %v", omit), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeServerResponse(&responseOptionsWriter{ResponseWriter: w, options: ResponseOptions{OmitNulls: omit}}, http.StatusOK, unmarshalableResponse{ID: 1, Updates: make(chan struct{})})
+
+			if w.Code != http.StatusInternalServerError {
+				t.Fatalf("expected status code %d, got %d", http.StatusInternalServerError, w.Code)
+			}
+			var response errorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("expected a clean error body, got %q: %v", w.Body.String(), err)
+			}
+			if response.Code != config.ErrorCodeInternal {
+				t.Errorf("expected error code %q, got %q", config.ErrorCodeInternal, response.Code)
+			}
+		})
+	}
+}
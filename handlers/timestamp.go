@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Timestamp is a point in time in the requests and responses. It's always accepted in any of the supported
+// formats: an RFC 3339 string, a date-only string (midnight UTC) or a Unix timestamp in seconds. It's encoded
+// as RFC 3339 unless ResponseOptions.InvoiceDateFormat says otherwise
+type Timestamp time.Time
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return time.Time(t).MarshalJSON()
+}
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if seconds, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+		*t = Timestamp(time.Unix(seconds, 0).UTC())
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid timestamp %s", data)
+	}
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			*t = Timestamp(parsed)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid timestamp %q, expected RFC 3339, YYYY-MM-DD or Unix seconds", s)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+)
+
+func TestTimestampSerialization(t *testing.T) {
+	t.Parallel()
+	invoice := invoiceResponse{ID: 1, InvoiceDate: Timestamp(time.Date(2025, time.March, 6, 15, 4, 5, 0, time.UTC))}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{format: config.DateFormatRFC3339, expected: `"invoice_date":"2025-03-06T15:04:05Z"`},
+		{format: config.DateFormatDate, expected: `"invoice_date":"2025-03-06"`},
+		{format: config.DateFormatUnix, expected: `"invoice_date":1741273445`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			data, err := ResponseOptions{InvoiceDateFormat: tt.format}.marshal(invoice)
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+			if !strings.Contains(string(data), tt.expected) {
+				t.Errorf("expected %s in %s", tt.expected, data)
+			}
+		})
+	}
+
+	t.Run("Date of a non-UTC time", func(t *testing.T) {
+		// Still the 5th in UTC-3, but already the 6th in UTC
+		late := invoiceResponse{ID: 1, InvoiceDate: Timestamp(time.Date(2025, time.March, 5, 23, 30, 0, 0, time.FixedZone("UTC-3", -3*60*60)))}
+		data, err := ResponseOptions{InvoiceDateFormat: config.DateFormatDate}.marshal(late)
+		if err != nil {
+			t.Fatalf("failed to marshal: %v", err)
+		}
+		if expected := `"invoice_date":"2025-03-06"`; !strings.Contains(string(data), expected) {
+			t.Errorf("expected %s in %s", expected, data)
+		}
+	})
+}
+
+func TestTimestampParsing(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected time.Time
+	}{
+		{name: "RFC 3339", body: `{"invoice_date":"2025-03-06T15:04:05Z"}`, expected: time.Date(2025, time.March, 6, 15, 4, 5, 0, time.UTC)},
+		{name: "Date only", body: `{"invoice_date":"2025-03-06"}`, expected: time.Date(2025, time.March, 6, 0, 0, 0, 0, time.UTC)},
+		{name: "Unix seconds", body: `{"invoice_date":1741273445}`, expected: time.Date(2025, time.March, 6, 15, 4, 5, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var invoice updateInvoiceRequest
+			if err := json.Unmarshal([]byte(tt.body), &invoice); err != nil {
+				t.Fatalf("failed to unmarshal %s: %v", tt.body, err)
+			}
+			if !time.Time(invoice.InvoiceDate).Equal(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, time.Time(invoice.InvoiceDate))
+			}
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		var invoice updateInvoiceRequest
+		if err := json.Unmarshal([]byte(`{"invoice_date":"06/03/2025"}`), &invoice); err == nil {
+			t.Error("expected an error for an unsupported format")
+		}
+	})
+}
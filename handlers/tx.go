@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+)
+
+// TxFunc runs fn within a single database transaction, passing it queries bound to that transaction.
+// The transaction is committed if fn returns nil and rolled back otherwise
+type TxFunc[Q any] func(ctx context.Context, fn func(q Q) error) error
+
+// NewTxFunc adapts the store transactions to the queries interface of a handler
+func NewTxFunc[Q any](store *database.Store) TxFunc[Q] {
+	return func(ctx context.Context, fn func(q Q) error) error {
+		return store.ExecTx(ctx, func(q *database.Queries) error {
+			return fn(any(q).(Q))
+		})
+	}
+}
+
+// NewSnapshotTxFunc is like NewTxFunc, but the transactions are read-only and all their queries see the same
+// snapshot of the database
+func NewSnapshotTxFunc[Q any](store *database.Store) TxFunc[Q] {
+	return func(ctx context.Context, fn func(q Q) error) error {
+		return store.ExecSnapshotTx(ctx, func(q *database.Queries) error {
+			return fn(any(q).(Q))
+		})
+	}
+}
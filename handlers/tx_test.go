@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestTransactionsAudited checks that every transaction of the handlers writes an audit log entry. Unlike the
+// single writes of the store, the changes made within h.Tx have to record their entries themselves. The reads
+// run with SnapshotTx instead
+func TestTransactionsAudited(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	transactions := 0
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok || !isMethodCall(call, "Tx") {
+				return true
+			}
+			transactions++
+			for _, arg := range call.Args {
+				if fn, ok := arg.(*ast.FuncLit); ok && !callsMethod(fn.Body, "CreateAuditLogEntry") {
+					t.Errorf("%s: the transaction doesn't write an audit log entry", fset.Position(call.Pos()))
+				}
+			}
+			return true
+		})
+	}
+	if transactions == 0 {
+		t.Error("expected to find the transactions of the handlers")
+	}
+}
+
+func isMethodCall(call *ast.CallExpr, name string) bool {
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && selector.Sel.Name == name
+}
+
+func callsMethod(body ast.Node, name string) bool {
+	found := false
+	ast.Inspect(body, func(node ast.Node) bool {
+		if call, ok := node.(*ast.CallExpr); ok && isMethodCall(call, name) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
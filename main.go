@@ -1,22 +1,44 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
 
 	"github.com/egor-markin/wallcraft-go-test-task/config"
 	"github.com/egor-markin/wallcraft-go-test-task/database"
 	"github.com/egor-markin/wallcraft-go-test-task/handlers"
+	"github.com/egor-markin/wallcraft-go-test-task/middleware"
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
 	_ "github.com/lib/pq"
 )
 
 func main() {
-	// Check if the DATABASE_URL environment variable is set
-	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
-		log.Fatal("DATABASE_URL environment variable is not set")
+	// Read the configuration from the environment variables
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Let Postgres itself abort runaway queries, even if the client side has already given up on them.
+	// Unless DB_STATEMENT_TIMEOUT is set, a statement_timeout already given in DATABASE_URL is kept
+	setStatementTimeout := utils.SetDSNDefaultParameter
+	if cfg.StatementTimeoutSet {
+		setStatementTimeout = utils.SetDSNParameter
+	}
+	dbURL, err := setStatementTimeout(cfg.DatabaseURL, "statement_timeout", strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10))
+	if err != nil {
+		log.Fatalf("Invalid DATABASE_URL: %v", err)
 	}
 
 	// Initialize the database connection
@@ -26,31 +48,82 @@ func main() {
 	}
 	defer db.Close()
 
-	// Test the database connection
-	if _, err := db.Exec("SELECT 1"); err != nil {
+	// Test the database connection. The database may still be starting up (e.g. in docker compose), so keep retrying for a while
+	err = utils.RetryWithBackoff(context.Background(), cfg.StartupDBTimeout, config.StartupDBRetryInitialDelay, config.StartupDBRetryMaxDelay, func(ctx context.Context) error {
+		_, err := db.ExecContext(ctx, "SELECT 1")
+		return err
+	})
+	if err != nil {
 		log.Fatalf("Database connection test failed: %v", err)
 	}
 
-	// Initialize the query object
-	queries := database.New(db)
+	// Initialize the query object. Timing the queries is only needed for the Server-Timing header
+	queries := database.NewStore(db)
+	if cfg.ServerTiming {
+		queries = database.NewTimedStore(db)
+	}
 
 	// Initialize handlers
-	productHandler := &handlers.ProductHandler{Queries: queries}
-	customerHandler := &handlers.CustomerHandler{Queries: queries}
-	invoiceHandler := &handlers.InvoiceHandler{Queries: queries}
-
-	// Routes
-	http.HandleFunc(config.ProductsApiPrefix, productHandler.ProductsHandler)
-	http.HandleFunc(config.ProductsApiPrefix+"/", productHandler.ProductHandler)
-	http.HandleFunc(config.CustomersApiPrefix, customerHandler.CustomersHandler)
-	http.HandleFunc(config.CustomersApiPrefix+"/", customerHandler.CustomerHandler)
-	http.HandleFunc(config.InvoicesApiPrefix, invoiceHandler.InvoicesHandler)
-	http.HandleFunc(config.InvoicesApiPrefix+"/", invoiceHandler.InvoiceHandler)
+	productHandler := &handlers.ProductHandler{
+		Queries:               queries,
+		Tx:                    handlers.NewTxFunc[handlers.ProductQueries](queries),
+		LowStockThreshold:     int32(cfg.LowStockThreshold),
+		MaxPriceIntegerDigits: cfg.MaxPriceIntegerDigits,
+		MaxDescriptionLength:  cfg.MaxDescriptionLength,
+		MaxAvailableItems:     int32(cfg.MaxAvailableItems),
+	}
+	if cfg.ProductsCacheTTL > 0 {
+		productHandler.Cache = &handlers.ProductsCache{TTL: cfg.ProductsCacheTTL}
+	}
+	customerHandler := &handlers.CustomerHandler{Queries: queries, Tx: handlers.NewTxFunc[handlers.CustomerQueries](queries)}
+	invoiceHandler := &handlers.InvoiceHandler{
+		Queries:             queries,
+		Tx:                  handlers.NewTxFunc[handlers.InvoiceQueries](queries),
+		SnapshotTx:          handlers.NewSnapshotTxFunc[handlers.InvoiceQueries](queries),
+		NumberPattern:       cfg.InvoiceNumberPattern,
+		UppercaseNumbers:    cfg.InvoiceNumberUppercase,
+		DiffAcrossCustomers: cfg.InvoiceDiffAcrossCustomers,
+		AggregateTimeout:    cfg.AggregateQueryTimeout,
+		RetryAfter:          cfg.UnavailableRetryAfter,
+	}
+	auditHandler := &handlers.AuditHandler{Queries: queries}
+	cleanupHandler := &handlers.CleanupHandler{Queries: queries, Tx: handlers.NewTxFunc[handlers.CleanupQueries](queries)}
+	exportHandler := &handlers.ExportHandler{Queries: queries, LowStockThreshold: int32(cfg.LowStockThreshold)}
+	reportHandler := &handlers.ReportHandler{Queries: queries, Timeout: cfg.AggregateQueryTimeout, RetryAfter: cfg.UnavailableRetryAfter}
+
+	// Routes, they're all listed by GET /routes
+	routes := apiRoutes(productHandler, customerHandler, invoiceHandler)
+
+	// Admin endpoints
+	if cfg.AdminToken != "" {
+		routes = append(routes, handlers.Route{
+			Path:    config.AdminApiPrefix + "/audit",
+			Methods: []string{http.MethodGet},
+			Handler: middleware.RequireAdminToken(http.HandlerFunc(auditHandler.AuditHandler), cfg.AdminToken),
+		}, handlers.Route{
+			Path:    config.AdminApiPrefix + "/cleanup/orphaned-items",
+			Methods: []string{http.MethodPost},
+			Handler: middleware.RequireAdminToken(http.HandlerFunc(cleanupHandler.OrphanedItemsHandler), cfg.AdminToken),
+		}, handlers.Route{
+			Path:    config.AdminApiPrefix + "/export/{entity}",
+			Pattern: config.AdminApiPrefix + "/export/",
+			Methods: []string{http.MethodGet},
+			Handler: middleware.RequireAdminToken(http.HandlerFunc(exportHandler.ExportHandler), cfg.AdminToken),
+		})
+	}
+
+	// The reports are only available to the admins when the admin endpoints are enabled
+	var salesReportHandler http.Handler = http.HandlerFunc(reportHandler.SalesHandler)
+	if cfg.AdminToken != "" {
+		salesReportHandler = middleware.RequireAdminToken(salesReportHandler, cfg.AdminToken)
+	}
+	routes = append(routes, handlers.Route{Path: config.ReportsApiPrefix + "/sales", Methods: []string{http.MethodGet}, Handler: salesReportHandler})
 
 	// Health check endpoint
-	http.HandleFunc(config.ApiPrefix+"/health", func(w http.ResponseWriter, r *http.Request) {
+	healthHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Check database connectivity
 		if err := db.Ping(); err != nil {
+			utils.SetRetryAfter(w, cfg.UnavailableRetryAfter)
 			w.WriteHeader(http.StatusServiceUnavailable)
 			w.Write([]byte("Database connection failed"))
 			return
@@ -60,10 +133,161 @@ func main() {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
+	routes = append(routes, handlers.Route{Path: config.HealthApiPath, Methods: []string{http.MethodGet}, Handler: healthHandler})
+
+	// Readiness check reporting every dependency separately
+	readinessHandler := &handlers.ReadinessHandler{
+		Checks: []handlers.DependencyCheck{
+			{Name: "db", Critical: !slices.Contains(cfg.NonCriticalDependencies, "db"), Check: db.PingContext},
+		},
+		Timeout:    config.ReadinessCheckTimeout,
+		RetryAfter: cfg.UnavailableRetryAfter,
+	}
+	routes = append(routes, handlers.Route{Path: config.ReadinessPath, Methods: []string{http.MethodGet}, Handler: http.HandlerFunc(readinessHandler.ReadinessHandler)})
+
+	rootHandler := &handlers.RootHandler{Name: cfg.ServiceName, Version: cfg.ServiceVersion}
+	routes = append(routes, handlers.Route{Path: "/", Pattern: "/{$}", Methods: []string{http.MethodGet}, Handler: http.HandlerFunc(rootHandler.RootHandler)})
+
+	routesHandler := &handlers.RoutesHandler{}
+	routes = append(routes, handlers.Route{Path: config.RoutesApiPath, Methods: []string{http.MethodGet}, Handler: http.HandlerFunc(routesHandler.RoutesHandler)})
+	routesHandler.Routes = routes
+	handlers.RegisterRoutes(http.DefaultServeMux, routes)
+	// The paths outside of the API keep the plain net/http 404
+	http.HandleFunc(config.ApiPrefix+"/", handlers.NotFoundHandler)
+	http.HandleFunc(config.ApiRoot, handlers.UnsupportedVersionHandler)
+
+	// Middlewares, the response options have to wrap the handlers directly
+	handler := handlers.WithResponseOptions(http.DefaultServeMux, handlers.ResponseOptions{
+		IDsAsStrings:        cfg.IDsAsStrings,
+		InvoiceDateFormat:   cfg.InvoiceDateFormat,
+		DeleteConfirmations: cfg.DeleteConfirmations,
+		OmitNulls:           cfg.OmitNulls,
+	})
+	if cfg.LogLevel == config.LogLevelDebug {
+		handler = middleware.LogBodies(handler, config.DebugBodyLogLimit)
+	}
+	if len(cfg.DisabledEndpoints) > 0 {
+		handler = middleware.DisableEndpoints(handler, cfg.DisabledEndpoints, config.ApiPrefix, cfg.UnavailableRetryAfter)
+	}
+	if cfg.ServerTiming {
+		handler = middleware.ServerTiming(handler)
+	}
+	if cfg.StrictAccept {
+		// The health check answers in plain text, the exports in NDJSON
+		handler = middleware.RequireJSONAccept(handler, []string{
+			config.HealthApiPath,
+			config.AdminApiPrefix + "/export/products",
+			config.AdminApiPrefix + "/export/customers",
+			config.AdminApiPrefix + "/export/invoices",
+		})
+	}
+	if cfg.BackpressureMaxInUse > 0 {
+		handler = middleware.Backpressure(handler, db, middleware.BackpressureOptions{
+			MaxInUse:    cfg.BackpressureMaxInUse,
+			ShedPercent: cfg.BackpressureShedPercent,
+			Delay:       cfg.BackpressureDelay,
+			RetryAfter:  cfg.BackpressureRetryAfter,
+			ExemptPaths: []string{config.HealthApiPath, config.ReadinessPath},
+		})
+	}
+	handler = middleware.LimitURL(handler, cfg.MaxURLLength, cfg.MaxQueryItems)
+	handler = middleware.LimitHeaders(handler, cfg.MaxHeaderCount)
+	handler = middleware.RequestID(handler)
+	// Wraps the middlewares above, so they see the path the way it's routed
+	handler = middleware.StripPathPrefix(handler, cfg.StripPathPrefix)
+	if len(cfg.CORSAllowedOrigins) > 0 {
+		handler = middleware.CORS(handler, middleware.CORSOptions{
+			AllowedOrigins:   cfg.CORSAllowedOrigins,
+			AllowCredentials: cfg.CORSAllowCredentials,
+			MaxAge:           cfg.CORSMaxAge,
+		})
+	}
 
 	// Start the server
-	log.Printf("The service is available at %s...", config.DefaultServiceBindingAddress)
-	if err := http.ListenAndServe(config.DefaultServiceBindingAddress, nil); err != nil {
+	server := newServer(cfg, handler)
+	listener, err := listen(cfg.ListenAddress)
+	if err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("The service is available at %s...", cfg.ListenAddress)
+		serverErr <- server.Serve(listener)
+	}()
+
+	// Wait for a stop signal and let the in-flight requests finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case err := <-serverErr:
 		log.Fatalf("Failed to start server: %v", err)
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Graceful shutdown failed: %v", err)
+	}
+}
+
+// listen binds to a TCP host:port address or, with the unix: prefix, to a Unix domain socket.
+// The socket file is removed when the listener is closed, e.g. by http.Server.Shutdown
+func listen(address string) (net.Listener, error) {
+	path, isUnix := strings.CutPrefix(address, config.UnixSocketPrefix)
+	if !isUnix {
+		return net.Listen("tcp", address)
+	}
+
+	// A socket file left behind by a crashed instance would make the bind fail
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return net.Listen("unix", path)
+}
+
+// newServer configures the HTTP server protocols and connection handling
+func newServer(cfg config.Config, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Handler:           handler,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		MaxHeaderBytes:    cfg.HTTPMaxHeaderBytes,
+		HTTP2:             &http.HTTP2Config{MaxConcurrentStreams: cfg.HTTP2MaxConcurrentStreams},
+	}
+	server.SetKeepAlivesEnabled(cfg.HTTPKeepAlives)
+
+	if cfg.H2C {
+		// Cleartext HTTP/2 is supported natively since Go 1.24, HTTP/1 stays enabled for the clients without it
+		server.Protocols = new(http.Protocols)
+		server.Protocols.SetHTTP1(true)
+		server.Protocols.SetUnencryptedHTTP2(true)
+	}
+	return server
+}
+
+// apiRoutes lists the resource endpoints of the API. The methods are the ones the handlers accept
+func apiRoutes(productHandler *handlers.ProductHandler, customerHandler *handlers.CustomerHandler, invoiceHandler *handlers.InvoiceHandler) []handlers.Route {
+	invoiceByIDHandler := http.HandlerFunc(invoiceHandler.InvoiceHandler)
+	return []handlers.Route{
+		{Path: config.ProductsApiPrefix, Methods: []string{http.MethodGet, http.MethodPost}, Handler: http.HandlerFunc(productHandler.ProductsHandler)},
+		{Path: config.ProductsApiPrefix + "/{id}", Pattern: config.ProductsApiPrefix + "/", Methods: []string{http.MethodGet, http.MethodPatch, http.MethodDelete}, Handler: http.HandlerFunc(productHandler.ProductHandler)},
+		{Path: config.ProductsApiPrefix + "/prices", Methods: []string{http.MethodPatch}, Handler: http.HandlerFunc(productHandler.PricesHandler)},
+		{Path: config.ProductsApiPrefix + "/availability", Methods: []string{http.MethodPost}, Handler: http.HandlerFunc(productHandler.AvailabilityHandler)},
+		{Path: config.ProductsApiPrefix + "/validate-price", Methods: []string{http.MethodPost}, Handler: http.HandlerFunc(productHandler.ValidatePriceHandler)},
+		{Path: config.ProductsApiPrefix + "/top", Methods: []string{http.MethodGet}, Handler: http.HandlerFunc(productHandler.TopProductsHandler)},
+		{Path: config.CustomersApiPrefix, Methods: []string{http.MethodGet, http.MethodPost}, Handler: http.HandlerFunc(customerHandler.CustomersHandler)},
+		{Path: config.CustomersApiPrefix + "/{id}", Pattern: config.CustomersApiPrefix + "/", Methods: []string{http.MethodGet, http.MethodPatch, http.MethodDelete}, Handler: http.HandlerFunc(customerHandler.CustomerHandler)},
+		{Path: config.CustomersApiPrefix + "/import", Methods: []string{http.MethodPost}, Handler: http.HandlerFunc(customerHandler.ImportHandler)},
+		{Path: config.CustomersApiPrefix + "/{id}/invoices/reassign", Methods: []string{http.MethodPost}, Handler: http.HandlerFunc(customerHandler.ReassignInvoicesHandler)},
+		{Path: config.InvoicesApiPrefix, Methods: []string{http.MethodGet, http.MethodPost}, Handler: http.HandlerFunc(invoiceHandler.InvoicesHandler)},
+		{Path: config.InvoicesApiPrefix + "/{id}", Pattern: config.InvoicesApiPrefix + "/", Methods: []string{http.MethodGet, http.MethodPatch, http.MethodDelete}, Handler: invoiceByIDHandler},
+		{Path: config.InvoicesApiPrefix + "/{id}/products", Pattern: config.InvoicesApiPrefix + "/", Methods: []string{http.MethodGet, http.MethodPatch}, Handler: invoiceByIDHandler},
+		{Path: config.InvoicesApiPrefix + "/{id}/products/{product_id}", Pattern: config.InvoicesApiPrefix + "/", Methods: []string{http.MethodPost, http.MethodDelete}, Handler: invoiceByIDHandler},
+		{Path: config.InvoicesApiPrefix + "/{id}/full", Pattern: config.InvoicesApiPrefix + "/", Methods: []string{http.MethodGet}, Handler: invoiceByIDHandler},
+		{Path: config.InvoicesApiPrefix + "/{id}/diff/{other_id}", Pattern: config.InvoicesApiPrefix + "/", Methods: []string{http.MethodGet}, Handler: invoiceByIDHandler},
+		{Path: config.InvoicesApiPrefix + "/validate", Methods: []string{http.MethodPost}, Handler: http.HandlerFunc(invoiceHandler.ValidateHandler)},
+		{Path: config.InvoicesApiPrefix + "/totals", Methods: []string{http.MethodGet}, Handler: http.HandlerFunc(invoiceHandler.TotalsHandler)},
 	}
 }
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/handlers"
+)
+
+func TestNewServerH2C(t *testing.T) {
+	server := newServer(config.Config{H2C: true, HTTPKeepAlives: true}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go server.Serve(listener)
+
+	// A client speaking only cleartext HTTP/2, with prior knowledge
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get("http://" + listener.Addr().String() + config.HealthApiPath)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("expected HTTP/2, got %s", resp.Proto)
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Errorf("graceful shutdown failed: %v", err)
+	}
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "api.sock")
+	listener, err := listen("unix:" + socketPath)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	server := newServer(config.Config{HTTPKeepAlives: true}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	go server.Serve(listener)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+		},
+	}}
+	resp, err := client.Get("http://unix" + config.HealthApiPath)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "OK" {
+		t.Errorf("unexpected response body: %s", body)
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Errorf("graceful shutdown failed: %v", err)
+	}
+	if _, err := os.Stat(socketPath); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected the socket file to be removed, got %v", err)
+	}
+}
+
+func TestAPIRoutes(t *testing.T) {
+	routes := apiRoutes(&handlers.ProductHandler{}, &handlers.CustomerHandler{}, &handlers.InvoiceHandler{})
+
+	// Registering the patterns shared by several routes twice would panic
+	handlers.RegisterRoutes(http.NewServeMux(), routes)
+
+	handler := &handlers.RoutesHandler{Routes: routes}
+	w := httptest.NewRecorder()
+	handler.RoutesHandler(w, httptest.NewRequest(http.MethodGet, config.RoutesApiPath, nil))
+
+	for _, expected := range []string{
+		`{"method":"GET","path":"/api/v1/products"}`,
+		`{"method":"DELETE","path":"/api/v1/products/{id}"}`,
+		`{"method":"POST","path":"/api/v1/customers/import"}`,
+		`{"method":"POST","path":"/api/v1/invoices/{id}/products/{product_id}"}`,
+	} {
+		if !strings.Contains(w.Body.String(), expected) {
+			t.Errorf("expected %s in the routes, got %s", expected, w.Body.String())
+		}
+	}
+}
+
+func TestUnknownRoutes(t *testing.T) {
+	mux := http.NewServeMux()
+	handlers.RegisterRoutes(mux, apiRoutes(&handlers.ProductHandler{}, &handlers.CustomerHandler{}, &handlers.InvoiceHandler{}))
+	mux.HandleFunc(config.ApiPrefix+"/", handlers.NotFoundHandler)
+	mux.HandleFunc(config.ApiRoot, handlers.UnsupportedVersionHandler)
+	rootHandler := &handlers.RootHandler{Name: "wallcraft", Version: "1.2.3"}
+	mux.HandleFunc("/{$}", rootHandler.RootHandler)
+
+	t.Run("Root", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if body := strings.TrimSpace(w.Body.String()); body != `{"name":"wallcraft","version":"1.2.3","api_versions":["v1"]}` {
+			t.Errorf("unexpected response body: %s", body)
+		}
+	})
+
+	t.Run("Unsupported API version", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/products", nil))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, w.Code)
+		}
+		if body := strings.TrimSpace(w.Body.String()); body != `{"error":"Unsupported API version, use /api/v1","code":"request.unsupported_api_version"}` {
+			t.Errorf("unexpected response body: %s", body)
+		}
+	})
+
+	t.Run("Under the API prefix", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, config.ApiPrefix+"/nonsense", nil))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, w.Code)
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != config.ContentTypeJSON {
+			t.Errorf("expected Content-Type %s, got %s", config.ContentTypeJSON, contentType)
+		}
+		if body := strings.TrimSpace(w.Body.String()); body != `{"error":"Not found","code":"request.not_found"}` {
+			t.Errorf("unexpected response body: %s", body)
+		}
+	})
+
+	t.Run("Outside of the API", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/nonsense", nil))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, w.Code)
+		}
+		if contentType := w.Header().Get("Content-Type"); strings.HasPrefix(contentType, config.ContentTypeJSON) {
+			t.Errorf("expected the generic 404, got Content-Type %s", contentType)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+// RequireJSONAccept rejects with 406 Not Acceptable the requests whose Accept header rules out JSON responses.
+// The requests without an Accept header are served as before. exemptPaths lists the endpoints producing other
+// content types
+func RequireJSONAccept(next http.Handler, exemptPaths []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept := r.Header.Get("Accept")
+		if accept == "" || slices.Contains(exemptPaths, r.URL.Path) || acceptsMediaType(accept, config.ContentTypeJSON) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		utils.WriteError(w, http.StatusNotAcceptable, utils.ErrorResponse{Error: "This endpoint can only produce " + config.ContentTypeJSON, Code: config.ErrorCodeNotAcceptable})
+	})
+}
+
+// acceptsMediaType reports whether an Accept header allows the media type. The most specific matching
+// media range wins, so "*/*, application/json;q=0" doesn't accept JSON
+func acceptsMediaType(accept, mediaType string) bool {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	bestSpecificity := -1
+	acceptable := false
+
+	for mediaRange := range strings.SplitSeq(accept, ",") {
+		rangeType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+
+		var specificity int
+		switch rangeType {
+		case mediaType:
+			specificity = 2
+		case mainType + "/*":
+			specificity = 1
+		case "*/*":
+			specificity = 0
+		default:
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if specificity > bestSpecificity {
+			bestSpecificity = specificity
+			acceptable = q > 0
+		}
+	}
+
+	return acceptable
+}
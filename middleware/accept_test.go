@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireJSONAccept(t *testing.T) {
+	handler := RequireJSONAccept(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), []string{"/api/v1/health"})
+
+	tests := []struct {
+		name     string
+		path     string
+		accept   string
+		expected int
+	}{
+		{name: "No Accept header", path: "/api/v1/products", expected: http.StatusOK},
+		{name: "Any media type", path: "/api/v1/products", accept: "*/*", expected: http.StatusOK},
+		{name: "JSON", path: "/api/v1/products", accept: "application/json", expected: http.StatusOK},
+		{name: "Browser defaults", path: "/api/v1/products", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", expected: http.StatusOK},
+		{name: "HTML only", path: "/api/v1/products", accept: "text/html", expected: http.StatusNotAcceptable},
+		{name: "JSON explicitly excluded", path: "/api/v1/products", accept: "*/*, application/json;q=0", expected: http.StatusNotAcceptable},
+		{name: "Exempt endpoint", path: "/api/v1/health", accept: "text/plain", expected: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status code %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
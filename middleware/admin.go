@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+// RequireAdminToken only lets through the requests authenticated with "Authorization: Bearer <token>". The admin
+// is set as the actor of the request, so it's recorded in the audit log
+func RequireAdminToken(next http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			utils.WriteError(w, http.StatusUnauthorized, utils.ErrorResponse{Error: "Unauthorized", Code: config.ErrorCodeUnauthorized})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(utils.WithActor(r.Context(), config.AdminActor)))
+	})
+}
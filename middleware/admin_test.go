@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/database"
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+func TestRequireAdminToken(t *testing.T) {
+	handler := RequireAdminToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), "secret")
+
+	tests := []struct {
+		name          string
+		authorization string
+		expected      int
+	}{
+		{name: "Valid token", authorization: "Bearer secret", expected: http.StatusOK},
+		{name: "Wrong token", authorization: "Bearer guess", expected: http.StatusUnauthorized},
+		{name: "No token", authorization: "", expected: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/audit", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status code %d, got %d", tt.expected, w.Code)
+			}
+			if tt.expected == http.StatusUnauthorized {
+				var response utils.ErrorResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Code != config.ErrorCodeUnauthorized {
+					t.Errorf("unexpected error response: %s", w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRequireAdminTokenActor(t *testing.T) {
+	var entry database.CreateAuditLogEntryParams
+	handler := RequireAdminToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entry = database.NewAuditEntry(r.Context(), "invoice_item", 0, database.AuditActionDelete)
+		w.WriteHeader(http.StatusOK)
+	}), "secret")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/cleanup/orphaned-items?apply=true", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !entry.Actor.Valid || entry.Actor.String != config.AdminActor {
+		t.Errorf("expected the audit entry to record the actor %q, got %+v", config.AdminActor, entry.Actor)
+	}
+}
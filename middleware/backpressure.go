@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+// PoolStatsProvider reports the usage of a connection pool, *sql.DB implements it
+type PoolStatsProvider interface {
+	Stats() sql.DBStats
+}
+
+type BackpressureOptions struct {
+	// MaxInUse is the number of connections in use from which the pool counts as saturated. The pool is saturated
+	// as well when a request had to wait for a connection since the previous check
+	MaxInUse int
+	// ShedPercent of the requests arriving at a saturated pool are rejected, the rest of them are delayed
+	ShedPercent int
+	Delay       time.Duration
+	RetryAfter  time.Duration
+	// ExemptPaths lists the endpoints never slowed down, e.g. the health checks
+	ExemptPaths []string
+}
+
+// Backpressure sheds load while the database connection pool is saturated: a share of the requests is rejected
+// with 503 Service Unavailable and a Retry-After header, the others wait for a moment before being served
+func Backpressure(next http.Handler, pool PoolStatsProvider, options BackpressureOptions) http.Handler {
+	var lastWaitCount atomic.Int64
+	lastWaitCount.Store(pool.Stats().WaitCount)
+	retryAfter := max(options.RetryAfter, time.Second)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(options.ExemptPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		stats := pool.Stats()
+		waited := lastWaitCount.Swap(stats.WaitCount) < stats.WaitCount
+		if !waited && stats.InUse < options.MaxInUse {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if rand.IntN(100) < options.ShedPercent {
+			utils.SetRetryAfter(w, retryAfter)
+			utils.WriteError(w, http.StatusServiceUnavailable, utils.ErrorResponse{Error: "The service is overloaded, retry later", Code: config.ErrorCodeOverloaded})
+			return
+		}
+		if err := sleep(r.Context(), options.Delay); err != nil {
+			// The client is gone
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
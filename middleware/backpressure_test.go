@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// mockPoolStats reports whatever pool usage the test sets
+type mockPoolStats struct {
+	stats sql.DBStats
+}
+
+func (m *mockPoolStats) Stats() sql.DBStats {
+	return m.stats
+}
+
+func TestBackpressure(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("Idle pool", func(t *testing.T) {
+		pool := &mockPoolStats{stats: sql.DBStats{InUse: 2, WaitCount: 7}}
+		handler := Backpressure(next, pool, BackpressureOptions{MaxInUse: 10, ShedPercent: 100, RetryAfter: time.Second})
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/products", nil))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("Saturated pool sheds requests", func(t *testing.T) {
+		pool := &mockPoolStats{stats: sql.DBStats{InUse: 10}}
+		handler := Backpressure(next, pool, BackpressureOptions{MaxInUse: 10, ShedPercent: 100, RetryAfter: 2 * time.Second})
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/products", nil))
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("expected status code %d, got %d", http.StatusServiceUnavailable, w.Code)
+		}
+		if retryAfter := w.Header().Get("Retry-After"); retryAfter != "2" {
+			t.Errorf("expected Retry-After 2, got %q", retryAfter)
+		}
+	})
+
+	t.Run("Waiting for connections delays requests", func(t *testing.T) {
+		pool := &mockPoolStats{stats: sql.DBStats{InUse: 2, WaitCount: 7}}
+		handler := Backpressure(next, pool, BackpressureOptions{MaxInUse: 10, Delay: 20 * time.Millisecond})
+		pool.stats.WaitCount = 9
+
+		start := time.Now()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/products", nil))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+			t.Errorf("expected the request to be delayed, it took %v", elapsed)
+		}
+
+		// The waits are already accounted for
+		start = time.Now()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/products", nil))
+		if elapsed := time.Since(start); elapsed >= 20*time.Millisecond {
+			t.Errorf("expected the request not to be delayed, it took %v", elapsed)
+		}
+	})
+
+	t.Run("Exempt path", func(t *testing.T) {
+		pool := &mockPoolStats{stats: sql.DBStats{InUse: 10}}
+		handler := Backpressure(next, pool, BackpressureOptions{MaxInUse: 10, ShedPercent: 100, ExemptPaths: []string{"/healthz"}})
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+}
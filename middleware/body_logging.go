@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+)
+
+// LogBodies logs the request and response bodies of every request, truncating each of them to maxBytes.
+// It's meant for debugging client issues only and must not be enabled in production
+func LogBodies(next http.Handler, maxBytes int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Peek at the beginning of the request body and put it back in front of the unread rest,
+		// so the handler still reads the whole body and large uploads are not buffered in memory
+		var requestBody []byte
+		if r.Body != nil {
+			requestBody, _ = io.ReadAll(io.LimitReader(r.Body, int64(maxBytes)+1))
+			r.Body = readCloser{io.MultiReader(bytes.NewReader(requestBody), r.Body), r.Body}
+		}
+
+		cw := &capturingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK, maxBytes: maxBytes}
+		next.ServeHTTP(cw, r)
+
+		log.Printf("%s %s request body: %s", r.Method, r.URL.RequestURI(), truncate(requestBody, maxBytes))
+		log.Printf("%s %s response %d body: %s", r.Method, r.URL.RequestURI(), cw.statusCode, truncate(cw.body.Bytes(), maxBytes))
+	})
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// capturingResponseWriter passes everything through to the wrapped ResponseWriter while keeping a copy of
+// the first maxBytes+1 bytes of the body
+type capturingResponseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+	maxBytes    int
+}
+
+func (w *capturingResponseWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.statusCode = statusCode
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *capturingResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	if remaining := w.maxBytes + 1 - w.body.Len(); remaining > 0 {
+		w.body.Write(b[:min(remaining, len(b))])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming responses working through the wrapper
+func (w *capturingResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the underlying ResponseWriter
+func (w *capturingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func truncate(body []byte, maxBytes int) string {
+	if len(body) > maxBytes {
+		return string(body[:maxBytes]) + "...(truncated)"
+	}
+	return string(body)
+}
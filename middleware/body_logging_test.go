@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLogBodies(t *testing.T) {
+	var logOutput bytes.Buffer
+	log.SetOutput(&logOutput)
+	defer log.SetOutput(os.Stderr)
+
+	handler := LogBodies(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("failed to read request body: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}), 8)
+
+	t.Run("Handler still reads the full request body", func(t *testing.T) {
+		logOutput.Reset()
+		requestBody := `{"first_name":"John","last_name":"Doe"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/customers", strings.NewReader(requestBody))
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("expected status code %d, got %d", http.StatusCreated, w.Code)
+		}
+		if w.Body.String() != requestBody {
+			t.Errorf("unexpected response body: %s", w.Body.String())
+		}
+		if !strings.Contains(logOutput.String(), `request body: {"first_...(truncated)`) {
+			t.Errorf("request body is not logged: %s", logOutput.String())
+		}
+		if !strings.Contains(logOutput.String(), `response 201 body: {"first_...(truncated)`) {
+			t.Errorf("response body is not logged: %s", logOutput.String())
+		}
+	})
+
+	t.Run("Short bodies are logged as is", func(t *testing.T) {
+		logOutput.Reset()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/customers", strings.NewReader("{}"))
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if !strings.Contains(logOutput.String(), "request body: {}\n") {
+			t.Errorf("request body is not logged: %s", logOutput.String())
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const corsAllowedMethods = "GET, POST, PATCH, DELETE, OPTIONS"
+
+type CORSOptions struct {
+	// AllowedOrigins lists the origins allowed to call the API, "*" allows any origin
+	AllowedOrigins []string
+	// AllowCredentials lets browsers send cookies and authorization headers. It can't be combined with "*"
+	AllowCredentials bool
+	// MaxAge tells browsers how long they may cache a preflight response. Zero omits the header
+	MaxAge time.Duration
+}
+
+// CORS adds the Cross-Origin Resource Sharing headers to the responses and answers preflight requests
+func CORS(next http.Handler, opts CORSOptions) http.Handler {
+	anyOrigin := slices.Contains(opts.AllowedOrigins, "*")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		if !anyOrigin && !slices.Contains(opts.AllowedOrigins, origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// A credentialed response must name the origin explicitly, browsers reject "*" in that case
+		if anyOrigin && !opts.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if opts.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		isPreflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if !isPreflight {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+		if requestedHeaders := r.Header.Get("Access-Control-Request-Headers"); requestedHeaders != "" {
+			w.Header().Set("Access-Control-Allow-Headers", requestedHeaders)
+		}
+		if opts.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
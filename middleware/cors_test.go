@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	preflight := func(origin string) *http.Request {
+		req := httptest.NewRequest(http.MethodOptions, "/api/v1/products", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+		return req
+	}
+
+	t.Run("Non-credentialed preflight", func(t *testing.T) {
+		handler := CORS(next, CORSOptions{AllowedOrigins: []string{"*"}, MaxAge: 10 * time.Minute})
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, preflight("https://shop.example.com"))
+
+		if w.Code != http.StatusNoContent {
+			t.Errorf("expected status code %d, got %d", http.StatusNoContent, w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Errorf("unexpected Access-Control-Allow-Origin: %q", got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+			t.Errorf("unexpected Access-Control-Allow-Credentials: %q", got)
+		}
+		if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+			t.Errorf("unexpected Access-Control-Max-Age: %q", got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type" {
+			t.Errorf("unexpected Access-Control-Allow-Headers: %q", got)
+		}
+	})
+
+	t.Run("Credentialed preflight", func(t *testing.T) {
+		handler := CORS(next, CORSOptions{AllowedOrigins: []string{"https://shop.example.com"}, AllowCredentials: true})
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, preflight("https://shop.example.com"))
+
+		if w.Code != http.StatusNoContent {
+			t.Errorf("expected status code %d, got %d", http.StatusNoContent, w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://shop.example.com" {
+			t.Errorf("unexpected Access-Control-Allow-Origin: %q", got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+			t.Errorf("unexpected Access-Control-Allow-Credentials: %q", got)
+		}
+		if got := w.Header().Get("Access-Control-Max-Age"); got != "" {
+			t.Errorf("unexpected Access-Control-Max-Age: %q", got)
+		}
+	})
+
+	t.Run("Disallowed origin", func(t *testing.T) {
+		handler := CORS(next, CORSOptions{AllowedOrigins: []string{"https://shop.example.com"}, AllowCredentials: true})
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/products", nil)
+		req.Header.Set("Origin", "https://evil.example.com")
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("unexpected Access-Control-Allow-Origin: %q", got)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+// DisableEndpoints responds with 503 Service Unavailable to the requests matching one of the endpoints, given as
+// "METHOD /path" with the path relative to prefix, e.g. "POST /invoices". Path segments in braces match any value,
+// so "DELETE /products/{id}" disables the deletion of every product. A non-zero retryAfter is sent as Retry-After
+func DisableEndpoints(next http.Handler, endpoints []string, prefix string, retryAfter time.Duration) http.Handler {
+	type endpoint struct {
+		method   string
+		segments []string
+	}
+	var disabled []endpoint
+	for _, e := range endpoints {
+		method, path, _ := strings.Cut(e, " ")
+		disabled = append(disabled, endpoint{method: strings.ToUpper(method), segments: splitPath(path)})
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		segments := splitPath(strings.TrimPrefix(r.URL.Path, prefix))
+		for _, e := range disabled {
+			if e.method == r.Method && matchSegments(e.segments, segments) {
+				utils.SetRetryAfter(w, retryAfter)
+				utils.WriteError(w, http.StatusServiceUnavailable, utils.ErrorResponse{Error: "This endpoint is temporarily disabled", Code: config.ErrorCodeEndpointDisabled})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) != len(segments) {
+		return false
+	}
+	for i, p := range pattern {
+		isWildcard := strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}")
+		if isWildcard && segments[i] == "" || !isWildcard && p != segments[i] {
+			return false
+		}
+	}
+	return true
+}
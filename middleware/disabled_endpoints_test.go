@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDisableEndpoints(t *testing.T) {
+	handler := DisableEndpoints(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), []string{"POST /invoices", "DELETE /products/{id}"}, "/api/v1", 30*time.Second)
+
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		expected int
+	}{
+		{name: "Disabled write", method: http.MethodPost, url: "/api/v1/invoices", expected: http.StatusServiceUnavailable},
+		{name: "Disabled templated path", method: http.MethodDelete, url: "/api/v1/products/42", expected: http.StatusServiceUnavailable},
+		{name: "Unaffected read", method: http.MethodGet, url: "/api/v1/invoices", expected: http.StatusOK},
+		{name: "Unaffected sub-resource", method: http.MethodDelete, url: "/api/v1/products/42/images", expected: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status code %d, got %d", tt.expected, w.Code)
+			}
+			if retryAfter := w.Header().Get("Retry-After"); tt.expected == http.StatusServiceUnavailable && retryAfter != "30" {
+				t.Errorf("expected Retry-After 30, got %q", retryAfter)
+			}
+		})
+	}
+}
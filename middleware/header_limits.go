@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+// LimitHeaders rejects the requests with more than maxCount header fields with 431 Request Header Fields Too
+// Large. Their total size is limited by the MaxHeaderBytes of the server already, before any handler runs.
+// A zero limit disables the check
+func LimitHeaders(next http.Handler, maxCount int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if maxCount > 0 {
+			count := 0
+			for _, values := range r.Header {
+				count += len(values)
+			}
+			if count > maxCount {
+				utils.WriteError(w, http.StatusRequestHeaderFieldsTooLarge, utils.ErrorResponse{Error: fmt.Sprintf("No more than %d header fields are allowed", maxCount), Code: config.ErrorCodeHeadersTooLarge})
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestLimitHeaders(t *testing.T) {
+	handler := LimitHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), 10)
+
+	tests := []struct {
+		name     string
+		headers  int
+		expected int
+	}{
+		{name: "Within the limit", headers: 10, expected: http.StatusOK},
+		{name: "Too many headers", headers: 11, expected: http.StatusRequestHeaderFieldsTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/products", nil)
+			for i := range tt.headers {
+				// The repeated fields count one by one
+				req.Header.Add("X-Custom", strconv.Itoa(i))
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status code %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
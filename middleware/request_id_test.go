@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = utils.RequestID(r.Context())
+	}))
+
+	t.Run("Client request id is kept", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/products", nil)
+		req.Header.Set("X-Request-ID", "abc-123")
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if seen != "abc-123" || w.Header().Get("X-Request-ID") != "abc-123" {
+			t.Errorf("unexpected request id %q, response header %q", seen, w.Header().Get("X-Request-ID"))
+		}
+	})
+
+	t.Run("Missing request id is generated", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/products", nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if len(seen) != 32 || w.Header().Get("X-Request-ID") != seen {
+			t.Errorf("unexpected request id %q, response header %q", seen, w.Header().Get("X-Request-ID"))
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+// ServerTiming adds a Server-Timing header with the time the request spent in the database and the total time
+// it took the handler to produce the response headers. The database time is only recorded by a store created
+// with database.NewTimedStore
+func ServerTiming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timing := &utils.RequestTiming{}
+		tw := &timingResponseWriter{ResponseWriter: w, timing: timing, start: time.Now()}
+		next.ServeHTTP(tw, r.WithContext(utils.WithRequestTiming(r.Context(), timing)))
+		// The handler didn't write anything, net/http is going to send an empty 200 response
+		tw.setHeader()
+	})
+}
+
+// timingResponseWriter sets the Server-Timing header right before the headers are sent
+type timingResponseWriter struct {
+	http.ResponseWriter
+	timing      *utils.RequestTiming
+	start       time.Time
+	wroteHeader bool
+}
+
+func (w *timingResponseWriter) WriteHeader(statusCode int) {
+	w.setHeader()
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *timingResponseWriter) Write(b []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *timingResponseWriter) setHeader() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.Header().Set("Server-Timing", fmt.Sprintf("db;dur=%s, total;dur=%s", formatMilliseconds(w.timing.DBTime()), formatMilliseconds(time.Since(w.start))))
+}
+
+// Flush keeps streaming responses working through the wrapper
+func (w *timingResponseWriter) Flush() {
+	w.setHeader()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the underlying ResponseWriter
+func (w *timingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func formatMilliseconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", float64(d)/float64(time.Millisecond))
+}
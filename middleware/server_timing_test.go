@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+func TestServerTiming(t *testing.T) {
+	handler := ServerTiming(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Pretend the products were loaded from the database
+		utils.AddDBTime(r.Context(), 2*time.Millisecond)
+		utils.AddDBTime(r.Context(), 500*time.Microsecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("[]"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/products", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	header := w.Header().Get("Server-Timing")
+	if !regexp.MustCompile(`^db;dur=2\.500, total;dur=\d+\.\d{3}$`).MatchString(header) {
+		t.Errorf("unexpected Server-Timing header: %q", header)
+	}
+	if w.Body.String() != "[]" {
+		t.Errorf("unexpected response body: %s", w.Body.String())
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// StripPathPrefix removes prefix from the path of the requests forwarded by a gateway with the full external path,
+// e.g. /service-a/api/v1/products becomes /api/v1/products, before they are routed. The requests without the
+// prefix, like the health checks sent to the service directly, are passed unchanged. An empty prefix disables it
+func StripPathPrefix(next http.Handler, prefix string) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, ok := strings.CutPrefix(r.URL.Path, prefix)
+		if !ok || (path != "" && path[0] != '/') {
+			// Either not prefixed or only sharing the beginning of a segment, e.g. /service-ab
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = path
+		if r2.URL.Path == "" {
+			r2.URL.Path = "/"
+		}
+		r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, prefix)
+		next.ServeHTTP(w, r2)
+	})
+}
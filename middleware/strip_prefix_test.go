@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStripPathPrefix(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/products", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("products"))
+	})
+	mux.HandleFunc("/api/v1/products/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("product " + r.URL.Path))
+	})
+	handler := StripPathPrefix(mux, "/service-a/")
+
+	tests := []struct {
+		name     string
+		url      string
+		expected int
+		body     string
+	}{
+		{name: "Prefixed request", url: "/service-a/api/v1/products?unused=true", expected: http.StatusOK, body: "products"},
+		{name: "Prefixed request with an id", url: "/service-a/api/v1/products/5", expected: http.StatusOK, body: "product /api/v1/products/5"},
+		{name: "Request without the prefix", url: "/api/v1/products", expected: http.StatusOK, body: "products"},
+		{name: "Partial segment", url: "/service-ab/api/v1/products", expected: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status code %d, got %d", tt.expected, w.Code)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+	"github.com/egor-markin/wallcraft-go-test-task/utils"
+)
+
+// LimitURL rejects the requests whose URL is longer than maxLength bytes with 414 URI Too Long, and the ones with
+// more than maxQueryItems query parameters or comma-separated items in a single parameter (like ids=1,2,3)
+// with 400 Bad Request. A zero limit disables the corresponding check
+func LimitURL(next http.Handler, maxLength, maxQueryItems int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if maxLength > 0 && len(r.URL.RequestURI()) > maxLength {
+			utils.WriteError(w, http.StatusRequestURITooLong, utils.ErrorResponse{Error: fmt.Sprintf("The URL must not be longer than %d characters", maxLength), Code: config.ErrorCodeURLTooLong})
+			return
+		}
+
+		if maxQueryItems > 0 && r.URL.RawQuery != "" {
+			// Count the separators up front, so parsing the query can't allocate more than the limit allows
+			if strings.Count(r.URL.RawQuery, "&")+1 > maxQueryItems {
+				utils.WriteError(w, http.StatusBadRequest, utils.ErrorResponse{Error: fmt.Sprintf("No more than %d query parameters are allowed", maxQueryItems), Code: config.ErrorCodeTooManyQueryItems})
+				return
+			}
+			for name, values := range r.URL.Query() {
+				for _, value := range values {
+					if strings.Count(value, ",")+1 > maxQueryItems {
+						utils.WriteError(w, http.StatusBadRequest, utils.ErrorResponse{Error: fmt.Sprintf("No more than %d items are allowed in %s", maxQueryItems, name), Code: config.ErrorCodeTooManyQueryItems})
+						return
+					}
+				}
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLimitURL(t *testing.T) {
+	handler := LimitURL(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), 64, 3)
+
+	tests := []struct {
+		name     string
+		url      string
+		expected int
+	}{
+		{name: "Within the limits", url: "/api/v1/products?ids=1,2,3", expected: http.StatusOK},
+		{name: "URL too long", url: "/api/v1/products?name=" + strings.Repeat("a", 64), expected: http.StatusRequestURITooLong},
+		{name: "Too many ids", url: "/api/v1/products?ids=1,2,3,4", expected: http.StatusBadRequest},
+		{name: "Too many query parameters", url: "/api/v1/products?a=1&b=2&c=3&d=4", expected: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status code %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
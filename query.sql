@@ -5,9 +5,53 @@
 -- name: ListProducts :many
 SELECT * FROM product ORDER BY id LIMIT 100;
 
+-- name: ListProductsWithStockValue :many
+-- The total is taken over all the products, not only the listed ones
+SELECT p.*, (p.price * p.available_items)::numeric AS stock_value,
+       (SUM(p.price * p.available_items) OVER ())::numeric AS total_stock_value
+FROM product p
+ORDER BY p.id
+LIMIT 100;
+
+-- name: ListProductStock :many
+SELECT id, available_items FROM product WHERE id = ANY(@ids::int[]);
+
+-- name: ListUnusedProducts :many
+SELECT p.id, p.name, p.description, p.price, p.available_items, p.created_at, p.updated_at
+FROM product p
+WHERE NOT EXISTS (SELECT 1 FROM invoice_item ii WHERE ii.product_id = p.id)
+ORDER BY p.id
+LIMIT 100;
+
+-- name: SearchProducts :many
+-- The search term is matched as a substring, the products having it in the name come first. The wildcards in it
+-- are escaped with a backslash
+SELECT p.id, p.name, p.description, p.price, p.available_items, p.created_at, p.updated_at
+FROM product p
+WHERE p.name ILIKE '%' || @search::text || '%' ESCAPE '\' OR p.description ILIKE '%' || @search::text || '%' ESCAPE '\'
+ORDER BY p.name ILIKE '%' || @search::text || '%' ESCAPE '\' DESC, p.id
+LIMIT 100;
+
+-- name: ListProductsModifiedSince :many
+SELECT * FROM product WHERE updated_at > @modified_since::timestamptz ORDER BY updated_at, id LIMIT 100;
+
+-- name: ListTopProducts :many
+SELECT p.id, p.name, p.description, p.price, p.available_items, p.created_at, p.updated_at,
+    COALESCE(SUM(ii.count), 0)::numeric AS sold
+FROM product p
+LEFT JOIN invoice_item ii ON ii.product_id = p.id
+GROUP BY p.id
+HAVING @include_unsold::boolean OR COUNT(ii.id) > 0
+ORDER BY sold DESC, p.id
+LIMIT @max_entries::int;
+
 -- name: GetProduct :one
 SELECT * FROM product WHERE id = $1;
 
+-- name: GetProductForUpdate :one
+-- Locks the product until the end of the transaction, for the read-modify-write updates
+SELECT * FROM product WHERE id = $1 FOR UPDATE;
+
 -- name: CreateProduct :one
 INSERT INTO product (name, description, price, available_items)
 VALUES ($1, $2, $3, $4)
@@ -19,7 +63,14 @@ SET
     name = $2,
     description = $3,
     price = $4,
-    available_items = $5
+    available_items = $5,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateProductPrice :one
+UPDATE product
+SET price = $2, updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
@@ -48,9 +99,45 @@ RIGHT JOIN (SELECT NULL) AS dummy ON true;
 -- name: ListInvoices :many
 SELECT * FROM invoice ORDER BY id LIMIT 100;
 
+-- name: ListInvoicesModifiedSince :many
+SELECT * FROM invoice WHERE updated_at > @modified_since::timestamptz ORDER BY updated_at, id LIMIT 100;
+
+-- name: ListInvoiceTotals :many
+-- The invoices without items total 0.00, the missing ones are left out. The lines are rounded to the cent before
+-- being added up, like the sums of the listed items, so that the total is the sum of those
+SELECT
+    i.id AS invoice_id,
+    CAST(COALESCE(SUM(CAST(ii.unit_price * ii.count AS numeric(18,2))), 0) AS numeric(20,2)) AS total
+FROM
+    invoice i
+    LEFT JOIN invoice_item ii ON ii.invoice_id = i.id
+WHERE
+    i.id = ANY(@ids::int[])
+GROUP BY
+    i.id
+ORDER BY
+    i.id;
+
 -- name: GetInvoice :one
 SELECT * FROM invoice WHERE id = $1;
 
+-- name: GetInvoiceDocument :one
+-- The invoice together with its customer and the total of all its items, which is the sum of their rounded sums. The lines
+-- fit numeric(18,2) with the largest allowed count and price
+SELECT
+    i.id,
+    i.invoice_number,
+    i.invoice_date,
+    i.customer_id,
+    c.first_name,
+    c.last_name,
+    CAST(COALESCE((SELECT SUM(CAST(ii.unit_price * ii.count AS numeric(18,2))) FROM invoice_item ii WHERE ii.invoice_id = i.id), 0) AS numeric(20,2)) AS total
+FROM
+    invoice i
+    JOIN customer c ON c.id = i.customer_id
+WHERE
+    i.id = @invoice_id::int;
+
 -- name: CreateInvoice :one
 INSERT INTO invoice (invoice_number, invoice_date, customer_id)
 VALUES (@invoice_number::text, @invoice_date::timestamp, @customer_id::int)
@@ -66,7 +153,8 @@ WITH
         SET
             invoice_number = @invoice_number::text,
             invoice_date = @invoice_date::timestamp,
-            customer_id = @customer_id::int
+            customer_id = @customer_id::int,
+            updated_at = NOW()
         WHERE id = $1
         RETURNING *
     )
@@ -80,6 +168,9 @@ SELECT
 FROM update_invoice
 RIGHT JOIN (SELECT NULL) AS dummy ON true;
 
+-- name: ReassignCustomerInvoices :execrows
+UPDATE invoice SET customer_id = @target_id::int, updated_at = NOW() WHERE customer_id = @source_id::int;
+
 -- name: DeleteInvoice :one
 WITH check_invoice AS (
     SELECT EXISTS(SELECT 1 FROM invoice WHERE id = @invoice_id::int) AS invoice_exists
@@ -98,6 +189,23 @@ SELECT
 FROM delete_invoice
 RIGHT JOIN (SELECT NULL) AS dummy ON true;
 
+-- name: ListSalesByPeriod :many
+-- The periods are truncated in UTC, the ones without invoices are left out
+SELECT
+    date_trunc(@granularity::text, i.invoice_date, 'UTC')::timestamptz AS period,
+    COUNT(DISTINCT i.id) AS invoice_count,
+    CAST(COALESCE(SUM(CAST(ii.unit_price * ii.count AS numeric(18,2))), 0) AS numeric(20,2)) AS total
+FROM
+    invoice i
+    LEFT JOIN invoice_item ii ON ii.invoice_id = i.id
+WHERE
+    i.invoice_date >= @from_date::timestamptz
+    AND i.invoice_date < @to_date::timestamptz
+GROUP BY
+    period
+ORDER BY
+    period;
+
 ------------------------------------------------------------------------------------------------------------------------
 -- customer
 ------------------------------------------------------------------------------------------------------------------------
@@ -105,19 +213,61 @@ RIGHT JOIN (SELECT NULL) AS dummy ON true;
 -- name: ListCustomers :many
 SELECT * FROM customer ORDER BY id LIMIT 100;
 
+-- name: ListCustomersByIDs :many
+SELECT * FROM customer WHERE id = ANY(@ids::int[]) ORDER BY id;
+
+-- name: ListCustomersByActivity :many
+-- The customers without invoices come last in either direction
+SELECT
+    c.id,
+    c.first_name,
+    c.last_name,
+    MAX(i.invoice_date) AS last_invoice_date,
+    CAST(COALESCE(SUM(CAST(ii.unit_price * ii.count AS numeric(18,2))), 0) AS numeric(20,2)) AS total_spend
+FROM
+    customer c
+    LEFT JOIN invoice i ON i.customer_id = c.id
+    LEFT JOIN invoice_item ii ON ii.invoice_id = i.id
+GROUP BY
+    c.id
+ORDER BY
+    COUNT(i.id) = 0,
+    CASE WHEN @sort::text = 'last_invoice' THEN MAX(i.invoice_date) END,
+    CASE WHEN @sort::text = '-last_invoice' THEN MAX(i.invoice_date) END DESC,
+    CASE WHEN @sort::text = 'total_spend' THEN COALESCE(SUM(CAST(ii.unit_price * ii.count AS numeric(18,2))), 0) END,
+    CASE WHEN @sort::text = '-total_spend' THEN COALESCE(SUM(CAST(ii.unit_price * ii.count AS numeric(18,2))), 0) END DESC,
+    c.id
+LIMIT
+    100;
+
+-- name: ListCustomersModifiedSince :many
+SELECT * FROM customer WHERE updated_at > @modified_since::timestamptz ORDER BY updated_at, id LIMIT 100;
+
 -- name: GetCustomer :one
 SELECT * FROM customer WHERE id = $1;
 
+-- name: GetCustomerWithInvoiceCount :one
+SELECT c.id, c.first_name, c.last_name, c.created_at, c.updated_at, COUNT(i.id) AS invoice_count
+FROM customer c
+LEFT JOIN invoice i ON i.customer_id = c.id
+WHERE c.id = $1
+GROUP BY c.id;
+
 -- name: CreateCustomer :one
 INSERT INTO customer (first_name, last_name)
 VALUES ($1, $2)
 RETURNING *;
 
+-- name: CreateCustomers :execrows
+INSERT INTO customer (first_name, last_name)
+SELECT unnest(@first_names::text[]), unnest(@last_names::text[]);
+
 -- name: UpdateCustomer :one
 UPDATE customer
 SET
     first_name = $2,
-    last_name = $3
+    last_name = $3,
+    updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
@@ -148,25 +298,106 @@ SELECT
     p.id,
     p.name,
     p.description,
-    p.price,
+    ii.unit_price AS price,
     ii.count,
-    CAST((p.price * ii.count) AS numeric(10,2)) AS sum
+    CAST((ii.unit_price * ii.count) AS numeric(18,2)) AS sum
 FROM
     invoice_item ii
     JOIN Product p ON ii.product_id = p.id
 WHERE
-    ii.invoice_id = $1
+    ii.invoice_id = @invoice_id::int
 ORDER BY
+    CASE WHEN @sort::text = 'name' THEN p.name END,
+    CASE WHEN @sort::text = '-name' THEN p.name END DESC,
+    CASE WHEN @sort::text = 'count' THEN ii.count END,
+    CASE WHEN @sort::text = '-count' THEN ii.count END DESC,
+    CASE WHEN @sort::text = 'price' THEN ii.unit_price END,
+    CASE WHEN @sort::text = '-price' THEN ii.unit_price END DESC,
+    CASE WHEN @sort::text = 'sum' THEN ii.unit_price * ii.count END,
+    CASE WHEN @sort::text = '-sum' THEN ii.unit_price * ii.count END DESC,
+    p.id
+ LIMIT
+    100;
+
+-- name: ListProductsFromInvoiceWithoutSum :many
+SELECT
+    p.id,
+    p.name,
+    p.description,
+    ii.unit_price AS price,
+    ii.count
+FROM
+    invoice_item ii
+    JOIN Product p ON ii.product_id = p.id
+WHERE
+    ii.invoice_id = @invoice_id::int
+ORDER BY
+    CASE WHEN @sort::text = 'name' THEN p.name END,
+    CASE WHEN @sort::text = '-name' THEN p.name END DESC,
+    CASE WHEN @sort::text = 'count' THEN ii.count END,
+    CASE WHEN @sort::text = '-count' THEN ii.count END DESC,
+    CASE WHEN @sort::text = 'price' THEN ii.unit_price END,
+    CASE WHEN @sort::text = '-price' THEN ii.unit_price END DESC,
+    CASE WHEN @sort::text = 'sum' THEN ii.unit_price * ii.count END,
+    CASE WHEN @sort::text = '-sum' THEN ii.unit_price * ii.count END DESC,
+    p.id
+ LIMIT
+    100;
+
+-- name: ListProductsFromInvoiceWithRunningTotal :many
+SELECT
+    p.id,
+    p.name,
+    p.description,
+    ii.unit_price AS price,
+    ii.count,
+    CAST((ii.unit_price * ii.count) AS numeric(18,2)) AS sum,
+    (SUM(CAST((ii.unit_price * ii.count) AS numeric(18,2))) OVER line_order)::numeric AS running_total
+FROM
+    invoice_item ii
+    JOIN Product p ON ii.product_id = p.id
+WHERE
+    ii.invoice_id = @invoice_id::int
+WINDOW
+    line_order AS (
+        ORDER BY
+            CASE WHEN @sort::text = 'name' THEN p.name END,
+            CASE WHEN @sort::text = '-name' THEN p.name END DESC,
+            CASE WHEN @sort::text = 'count' THEN ii.count END,
+            CASE WHEN @sort::text = '-count' THEN ii.count END DESC,
+            CASE WHEN @sort::text = 'price' THEN ii.unit_price END,
+            CASE WHEN @sort::text = '-price' THEN ii.unit_price END DESC,
+            CASE WHEN @sort::text = 'sum' THEN ii.unit_price * ii.count END,
+            CASE WHEN @sort::text = '-sum' THEN ii.unit_price * ii.count END DESC,
+            p.id
+    )
+ORDER BY
+    CASE WHEN @sort::text = 'name' THEN p.name END,
+    CASE WHEN @sort::text = '-name' THEN p.name END DESC,
+    CASE WHEN @sort::text = 'count' THEN ii.count END,
+    CASE WHEN @sort::text = '-count' THEN ii.count END DESC,
+    CASE WHEN @sort::text = 'price' THEN ii.unit_price END,
+    CASE WHEN @sort::text = '-price' THEN ii.unit_price END DESC,
+    CASE WHEN @sort::text = 'sum' THEN ii.unit_price * ii.count END,
+    CASE WHEN @sort::text = '-sum' THEN ii.unit_price * ii.count END DESC,
     p.id
  LIMIT
     100;
 
 -- name: AddProductToInvoice :one
-INSERT INTO invoice_item (invoice_id, product_id, count)
-VALUES (@invoice_id::int, @product_id::int, @count::int)
+-- The price of the product is recorded when it's first added, changing the count later keeps it
+INSERT INTO invoice_item (invoice_id, product_id, count, unit_price)
+SELECT @invoice_id::int, p.id, @count::numeric, p.price FROM product p WHERE p.id = @product_id::int
 ON CONFLICT (invoice_id, product_id)
 DO UPDATE SET
-    count = EXCLUDED.count
+    count = EXCLUDED.count,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: UpdateInvoiceItemCount :one
+UPDATE invoice_item
+SET count = @count::numeric, updated_at = NOW()
+WHERE invoice_id = @invoice_id::int AND product_id = @product_id::int
 RETURNING *;
 
 -- name: DeleteProductFromInvoice :one
@@ -190,3 +421,44 @@ SELECT
     END AS result
 FROM delete_invoice_item
 RIGHT JOIN (SELECT NULL) AS dummy ON true;
+
+-- name: DiffInvoiceItems :many
+-- Lists the lines whose count or unit price differs between the two invoices. The difference of the invoice
+-- totals is repeated in every row, it's computed from the whole invoices rather than from the listed lines.
+-- The prices are the ones on the to invoice, or on the from invoice for the removed lines
+SELECT p.id AS product_id, p.name, COALESCE(b.unit_price, a.unit_price)::numeric AS price, a.count AS from_count, b.count AS to_count,
+       (COALESCE(b.sum, 0) - COALESCE(a.sum, 0))::numeric AS sum_delta,
+       CAST(
+           (SELECT COALESCE(SUM(CAST(unit_price * count AS numeric(18,2))), 0) FROM invoice_item WHERE invoice_id = @to_invoice_id::int)
+           - (SELECT COALESCE(SUM(CAST(unit_price * count AS numeric(18,2))), 0) FROM invoice_item WHERE invoice_id = @from_invoice_id::int)
+       AS numeric(20,2)) AS total_delta
+FROM (SELECT product_id, count, unit_price, CAST(unit_price * count AS numeric(18,2)) AS sum FROM invoice_item WHERE invoice_id = @from_invoice_id::int) a
+FULL JOIN (SELECT product_id, count, unit_price, CAST(unit_price * count AS numeric(18,2)) AS sum FROM invoice_item WHERE invoice_id = @to_invoice_id::int) b ON b.product_id = a.product_id
+JOIN product p ON p.id = COALESCE(a.product_id, b.product_id)
+WHERE a.count IS DISTINCT FROM b.count OR a.unit_price IS DISTINCT FROM b.unit_price
+ORDER BY p.id;
+
+-- name: CountOrphanedInvoiceItems :one
+-- The foreign keys prevent orphans, unless they were bypassed, e.g. by a direct database operation
+SELECT COUNT(*) FROM invoice_item ii
+WHERE NOT EXISTS (SELECT 1 FROM invoice i WHERE i.id = ii.invoice_id)
+    OR NOT EXISTS (SELECT 1 FROM product p WHERE p.id = ii.product_id);
+
+-- name: DeleteOrphanedInvoiceItems :execrows
+DELETE FROM invoice_item ii
+WHERE NOT EXISTS (SELECT 1 FROM invoice i WHERE i.id = ii.invoice_id)
+    OR NOT EXISTS (SELECT 1 FROM product p WHERE p.id = ii.product_id);
+
+------------------------------------------------------------------------------------------------------------------------
+-- audit_log
+------------------------------------------------------------------------------------------------------------------------
+
+-- name: CreateAuditLogEntry :exec
+INSERT INTO audit_log (entity, entity_id, action, actor, request_id)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ListAuditLog :many
+SELECT * FROM audit_log
+WHERE id > @after_id::bigint
+ORDER BY id
+LIMIT @max_entries::int;
@@ -30,7 +30,9 @@ CREATE TABLE IF NOT EXISTS invoice_item (
     id SERIAL PRIMARY KEY,
     invoice_id INT NOT NULL,
     product_id INT NOT NULL,
-    count INT NOT NULL CHECK (count > 0),
+    count NUMERIC NOT NULL CHECK (count > 0),
+    -- The price of the product when it was added to the invoice
+    unit_price NUMERIC(10, 2) NOT NULL CHECK (unit_price >= 0),
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     FOREIGN KEY (invoice_id) REFERENCES invoice(id),
@@ -38,7 +40,49 @@ CREATE TABLE IF NOT EXISTS invoice_item (
     UNIQUE (invoice_id, product_id)
 );
 
+-- Migrates the databases created before the invoice item counts became fractional. It only runs on the integer
+-- column, altering the type locks the table exclusively
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = 'invoice_item' AND column_name = 'count' AND data_type = 'integer'
+    ) THEN
+        ALTER TABLE invoice_item ALTER COLUMN count TYPE NUMERIC;
+    END IF;
+END
+$$;
+
+-- Migrates the databases created before the invoice items recorded their unit price, the existing items get
+-- the current prices of their products. It only runs once, setting NOT NULL scans the table under an exclusive lock
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = current_schema() AND table_name = 'invoice_item' AND column_name = 'unit_price'
+    ) THEN
+        ALTER TABLE invoice_item ADD COLUMN unit_price NUMERIC(10, 2) CHECK (unit_price >= 0);
+        UPDATE invoice_item ii SET unit_price = p.price FROM product p WHERE p.id = ii.product_id;
+        ALTER TABLE invoice_item ALTER COLUMN unit_price SET NOT NULL;
+    END IF;
+END
+$$;
+
+-- Append-only trail of the changes made through the API
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    entity VARCHAR(50) NOT NULL,
+    entity_id INT,
+    action VARCHAR(50) NOT NULL,
+    actor TEXT,
+    request_id TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
 CREATE INDEX IF NOT EXISTS idx_invoice_date ON invoice(invoice_date);
 CREATE INDEX IF NOT EXISTS idx_invoice_customer_id ON invoice(customer_id);
 CREATE INDEX IF NOT EXISTS idx_invoice_item_invoice_id ON invoice_item(invoice_id);
 CREATE INDEX IF NOT EXISTS idx_invoice_item_product_id ON invoice_item(product_id);
+CREATE INDEX IF NOT EXISTS idx_product_updated_at ON product(updated_at);
+CREATE INDEX IF NOT EXISTS idx_customer_updated_at ON customer(updated_at);
+CREATE INDEX IF NOT EXISTS idx_invoice_updated_at ON invoice(updated_at);
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"context"
+	"sync"
+)
+
+// Coalescer shares the result of a call among all the concurrent calls with the same key, like singleflight.
+// Nothing is kept once a call returns, so a call starting after that always runs fn again
+type Coalescer[K comparable, V any] struct {
+	mu      sync.Mutex
+	flights map[K]*flight[V]
+}
+
+type flight[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// Do runs fn unless a call with the same key is already in flight, in which case it waits for that one instead.
+// fn runs detached from the cancellation of ctx, as other callers may be waiting for it, but every caller stops
+// waiting when its own ctx is done
+func (c *Coalescer[K, V]) Do(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (V, error) {
+	c.mu.Lock()
+	f, ok := c.flights[key]
+	if !ok {
+		if c.flights == nil {
+			c.flights = make(map[K]*flight[V])
+		}
+		f = &flight[V]{done: make(chan struct{})}
+		c.flights[key] = f
+		go func() {
+			f.value, f.err = fn(context.WithoutCancel(ctx))
+			c.mu.Lock()
+			if c.flights[key] == f {
+				delete(c.flights, key)
+			}
+			c.mu.Unlock()
+			close(f.done)
+		}()
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// ForgetAll makes the calls starting from now on run fn again instead of joining the ones in flight, e.g. after
+// a write that could have made their results outdated
+func (c *Coalescer[K, V]) ForgetAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.flights)
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitingContext tells when a caller starts waiting for its result, Do only asks for Done once it has joined
+// a flight
+type waitingContext struct {
+	context.Context
+	waiting chan<- struct{}
+}
+
+func (ctx waitingContext) Done() <-chan struct{} {
+	ctx.waiting <- struct{}{}
+	return ctx.Context.Done()
+}
+
+func TestCoalescer(t *testing.T) {
+	t.Run("Concurrent calls share one run", func(t *testing.T) {
+		var c Coalescer[int32, string]
+		var calls atomic.Int32
+		release := make(chan struct{})
+		fn := func(ctx context.Context) (string, error) {
+			calls.Add(1)
+			<-release
+			return "product", nil
+		}
+
+		const callers = 10
+		var wg sync.WaitGroup
+		waiting := make(chan struct{}, callers)
+		results := make([]string, callers)
+		for i := range callers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], _ = c.Do(waitingContext{context.Background(), waiting}, 1, fn)
+			}()
+		}
+		// Nothing returns before the release, so all the callers are waiting for the same run
+		for range callers {
+			<-waiting
+		}
+		close(release)
+		wg.Wait()
+
+		if calls.Load() != 1 {
+			t.Errorf("expected a single call, got %d", calls.Load())
+		}
+		for i, result := range results {
+			if result != "product" {
+				t.Errorf("unexpected result of caller %d: %q", i, result)
+			}
+		}
+	})
+
+	t.Run("Sequential calls run again", func(t *testing.T) {
+		var c Coalescer[int32, string]
+		calls := 0
+		fn := func(ctx context.Context) (string, error) {
+			calls++
+			return "", errors.New("failed")
+		}
+
+		c.Do(context.Background(), 1, fn)
+		if _, err := c.Do(context.Background(), 1, fn); err == nil || calls != 2 {
+			t.Errorf("expected 2 calls and an error, got %d calls and %v", calls, err)
+		}
+	})
+
+	t.Run("Forgotten flights aren't joined", func(t *testing.T) {
+		var c Coalescer[int32, string]
+		entered := make(chan struct{}, 2)
+		release := make(chan struct{})
+		fn := func(ctx context.Context) (string, error) {
+			entered <- struct{}{}
+			<-release
+			return "", nil
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Do(context.Background(), 1, fn)
+		}()
+		<-entered
+		c.ForgetAll()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Do(context.Background(), 1, fn)
+		}()
+		// The first run is still blocked, so only running fn again gets the second caller there
+		select {
+		case <-entered:
+		case <-time.After(time.Second):
+			t.Error("the second caller joined the forgotten flight")
+		}
+		close(release)
+		wg.Wait()
+	})
+
+	t.Run("Cancelled caller", func(t *testing.T) {
+		var c Coalescer[int32, string]
+		release := make(chan struct{})
+		defer close(release)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := c.Do(ctx, 1, func(ctx context.Context) (string, error) {
+			<-release
+			return "", nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the cancellation error, got %v", err)
+		}
+	})
+}
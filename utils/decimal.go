@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"errors"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+var decimalPattern = regexp.MustCompile(`^[+-]?\d+(\.\d+)?$`)
+
+// Decimal is a parsed plain decimal number
+type Decimal struct {
+	Value *big.Rat
+	// IntegerDigits and FractionDigits count the significant digits before and after the decimal point,
+	// e.g. 2 and 1 for "012.50"
+	IntegerDigits  int
+	FractionDigits int
+}
+
+// ParseDecimal parses a plain decimal number like "12", "-0.5" or "19.99". Unlike strconv.ParseFloat, it doesn't
+// accept exponents or special values like "NaN" and doesn't lose precision
+func ParseDecimal(s string) (Decimal, error) {
+	if !decimalPattern.MatchString(s) {
+		return Decimal{}, errors.New("not a decimal number")
+	}
+
+	value, _ := new(big.Rat).SetString(s)
+	integerPart, fractionPart, _ := strings.Cut(strings.TrimLeft(s, "+-"), ".")
+	return Decimal{
+		Value:          value,
+		IntegerDigits:  len(strings.TrimLeft(integerPart, "0")),
+		FractionDigits: len(strings.TrimRight(fractionPart, "0")),
+	}, nil
+}
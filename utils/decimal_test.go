@@ -0,0 +1,41 @@
+package utils
+
+import "testing"
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		input          string
+		valid          bool
+		sign           int
+		integerDigits  int
+		fractionDigits int
+	}{
+		{input: "12", valid: true, sign: 1, integerDigits: 2},
+		{input: "012.50", valid: true, sign: 1, integerDigits: 2, fractionDigits: 1},
+		{input: "-0.125", valid: true, sign: -1, fractionDigits: 3},
+		{input: "0", valid: true},
+		{input: "1e3"},
+		{input: "NaN"},
+		{input: ".5"},
+		{input: "1/3"},
+		{input: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d, err := ParseDecimal(tt.input)
+			if !tt.valid {
+				if err == nil {
+					t.Errorf("expected %q to be rejected", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if d.Value.Sign() != tt.sign || d.IntegerDigits != tt.integerDigits || d.FractionDigits != tt.fractionDigits {
+				t.Errorf("unexpected result for %q: sign %d, %d integer digits, %d fraction digits", tt.input, d.Value.Sign(), d.IntegerDigits, d.FractionDigits)
+			}
+		})
+	}
+}
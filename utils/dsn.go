@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// SetDSNParameter sets a connection parameter on a Postgres connection string. Both the URL form
+// ("postgres://user@host/db?sslmode=disable") and the key/value form ("host=db user=user") are supported.
+// lib/pq passes parameters it doesn't know itself (e.g. statement_timeout) to the server as run-time settings
+func SetDSNParameter(dsn, key, value string) (string, error) {
+	if isDSNURL(dsn) {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", err
+		}
+		query := u.Query()
+		query.Set(key, value)
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
+
+	return strings.TrimSpace(dsn) + " " + key + "=" + value, nil
+}
+
+// SetDSNDefaultParameter is like SetDSNParameter, but leaves the connection string as it is when it already has
+// the parameter
+func SetDSNDefaultParameter(dsn, key, value string) (string, error) {
+	if isDSNURL(dsn) {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", err
+		}
+		if u.Query().Has(key) {
+			return dsn, nil
+		}
+	} else {
+		for field := range strings.FieldsSeq(dsnEquals.ReplaceAllString(dsn, "=")) {
+			if strings.HasPrefix(field, key+"=") {
+				return dsn, nil
+			}
+		}
+	}
+	return SetDSNParameter(dsn, key, value)
+}
+
+// dsnEquals matches the equals sign of the key/value form, which allows spaces around it
+var dsnEquals = regexp.MustCompile(`\s*=\s*`)
+
+func isDSNURL(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
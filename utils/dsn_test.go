@@ -0,0 +1,80 @@
+package utils
+
+import "testing"
+
+func TestSetDSNParameter(t *testing.T) {
+	tests := []struct {
+		name     string
+		dsn      string
+		expected string
+	}{
+		{
+			name:     "URL form",
+			dsn:      "postgres://user:password@db:5432/mydb?sslmode=disable",
+			expected: "postgres://user:password@db:5432/mydb?sslmode=disable&statement_timeout=5000",
+		},
+		{
+			name:     "URL form overrides an existing value",
+			dsn:      "postgres://user:password@db:5432/mydb?statement_timeout=100",
+			expected: "postgres://user:password@db:5432/mydb?statement_timeout=5000",
+		},
+		{
+			name:     "key/value form",
+			dsn:      "host=db user=user dbname=mydb",
+			expected: "host=db user=user dbname=mydb statement_timeout=5000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SetDSNParameter(tt.dsn, "statement_timeout", "5000")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSetDSNDefaultParameter(t *testing.T) {
+	tests := []struct {
+		name     string
+		dsn      string
+		expected string
+	}{
+		{
+			name:     "URL form",
+			dsn:      "postgres://user:password@db:5432/mydb?sslmode=disable",
+			expected: "postgres://user:password@db:5432/mydb?sslmode=disable&statement_timeout=5000",
+		},
+		{
+			name:     "URL form keeps an existing value",
+			dsn:      "postgres://user:password@db:5432/mydb?statement_timeout=100",
+			expected: "postgres://user:password@db:5432/mydb?statement_timeout=100",
+		},
+		{
+			name:     "key/value form",
+			dsn:      "host=db user=user dbname=mydb",
+			expected: "host=db user=user dbname=mydb statement_timeout=5000",
+		},
+		{
+			name:     "key/value form keeps an existing value",
+			dsn:      "host=db statement_timeout = 100 dbname=mydb",
+			expected: "host=db statement_timeout = 100 dbname=mydb",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SetDSNDefaultParameter(tt.dsn, "statement_timeout", "5000")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/egor-markin/wallcraft-go-test-task/config"
+)
+
+// ErrorResponse is the body of every error response, Code is one of the config.ErrorCode* constants
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+	Field string `json:"field,omitempty"`
+}
+
+// WriteError is shared by the handlers and the middleware, so that all the errors look the same to the clients
+func WriteError(w http.ResponseWriter, statusCode int, response ErrorResponse) {
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", config.ContentTypeJSON)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Println("Error encoding error response: ", err)
+	}
+}
+
+// SetRetryAfter tells the client how long to wait before retrying a 503, in whole seconds and at least one. A zero
+// duration sends no header
+func SetRetryAfter(w http.ResponseWriter, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(max(d.Round(time.Second), time.Second)/time.Second)))
+}
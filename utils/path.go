@@ -10,10 +10,11 @@ func ExtractTrailingID(path string) (int, error) {
 	parts := strings.Split(path, "/")
 	lastPart := parts[len(parts)-1]
 
-	number, err := strconv.Atoi(lastPart)
+	// The ids are int32 in the database, a bigger number must not wrap around to another id
+	number, err := strconv.ParseInt(lastPart, 10, 32)
 	if err != nil {
 		return 0, err
 	}
 
-	return number, nil
+	return int(number), nil
 }
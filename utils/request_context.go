@@ -0,0 +1,28 @@
+package utils
+
+import "context"
+
+type requestIDKey struct{}
+type actorKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the id of the request being handled
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the id of the request being handled or an empty string
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// WithActor returns a copy of ctx carrying the authenticated caller making the request
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the authenticated caller making the request or an empty string
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
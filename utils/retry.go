@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RetryWithBackoff calls fn until it succeeds or the timeout elapses, doubling the delay between the attempts from
+// initialDelay up to maxDelay. It returns the error of the last attempt if fn never succeeded.
+// A zero timeout means a single attempt
+func RetryWithBackoff(ctx context.Context, timeout, initialDelay, maxDelay time.Duration, fn func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	delay := initialDelay
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		log.Printf("Attempt %d failed: %v", attempt, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxDelay)
+	}
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryWithBackoff(t *testing.T) {
+	t.Run("Succeeds after failures", func(t *testing.T) {
+		attempts := 0
+		err := RetryWithBackoff(context.Background(), time.Second, time.Millisecond, 4*time.Millisecond, func(ctx context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return errors.New("connection refused")
+			}
+			return nil
+		})
+
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if attempts != 3 {
+			t.Errorf("expected 3 attempts, got %d", attempts)
+		}
+	})
+
+	t.Run("Gives up after the timeout", func(t *testing.T) {
+		lastErr := errors.New("connection refused")
+		started := time.Now()
+		err := RetryWithBackoff(context.Background(), 20*time.Millisecond, time.Millisecond, 4*time.Millisecond, func(ctx context.Context) error {
+			return lastErr
+		})
+
+		if !errors.Is(err, lastErr) {
+			t.Errorf("expected the last error, got %v", err)
+		}
+		if elapsed := time.Since(started); elapsed > time.Second {
+			t.Errorf("retrying took too long: %s", elapsed)
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// RequestTiming accumulates the time a request spends waiting for the database
+type RequestTiming struct {
+	db atomic.Int64
+}
+
+type requestTimingKey struct{}
+
+// WithRequestTiming returns a copy of ctx carrying timing for the database calls made with it
+func WithRequestTiming(ctx context.Context, timing *RequestTiming) context.Context {
+	return context.WithValue(ctx, requestTimingKey{}, timing)
+}
+
+// AddDBTime adds d to the request timing in ctx, if there is one
+func AddDBTime(ctx context.Context, d time.Duration) {
+	if timing, ok := ctx.Value(requestTimingKey{}).(*RequestTiming); ok {
+		timing.db.Add(int64(d))
+	}
+}
+
+// DBTime returns the total time spent in the database so far
+func (t *RequestTiming) DBTime() time.Duration {
+	return time.Duration(t.db.Load())
+}